@@ -3,9 +3,11 @@ package adapters
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/jekabolt/protokol"
 	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/transform"
 )
 
 // Config is the common adapter configuration.
@@ -13,6 +15,92 @@ type Config struct {
 	Schema     *schema.Schema
 	Backends   *protokol.BackendRegistry
 	Middleware []Middleware
+	// SkipValidation disables checking request input against the method's
+	// input type before the backend is called.
+	SkipValidation bool
+	// OutputDefaults fills fields missing from backend responses with their
+	// schema defaults, so every protocol returns the same shape.
+	OutputDefaults bool
+	// ErrorMapper translates errors before they are sent to clients. See
+	// PublicError.
+	ErrorMapper ErrorMapper
+	// DebugErrors sends the full error chain to clients. Without it,
+	// internal errors are hidden behind a correlation id and only the
+	// messages of protokol errors are sent.
+	DebugErrors bool
+	// ErrorLogger logs internal errors with their correlation id. Defaults
+	// to slog.Default().
+	ErrorLogger *slog.Logger
+}
+
+// Handler returns a handler that applies input defaults, validates the input
+// for method and calls the backend serving it, wrapped in the configured
+// middleware. Validation runs inside the middleware chain, so
+// requests rejected by authentication or rate limiting are never validated.
+func (c Config) Handler(svc schema.Service, method schema.Method) Handler {
+	var h Handler = HandlerFunc(func(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+		transform.ApplyDefaults(c.Schema, method.Input, req.Input)
+		if err := c.validate(method, req); err != nil {
+			return nil, err
+		}
+		backend, ok := c.Backends.Get(svc.BackendFor(method))
+		if !ok {
+			return nil, protokol.ErrBackendNotFound
+		}
+		resp, err := backend.Call(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if c.OutputDefaults && resp != nil {
+			transform.ApplyDefaults(c.Schema, method.Output, resp.Output)
+		}
+		return resp, nil
+	})
+	return Chain(h, c.Middleware...)
+}
+
+// OpenStream opens a stream on the backend serving method. The middleware
+// chain runs around stream setup, so authentication, rate limiting and logging
+// apply to streams the same way they apply to unary calls. The request input
+// of server-streaming methods is validated before the stream is opened, and
+// every message sent on a client or bidirectional stream when it is sent.
+// Defaults are applied to every input and, with OutputDefaults, every output
+// message.
+func (c Config) OpenStream(ctx context.Context, svc schema.Service, method schema.Method, req *protokol.Request) (protokol.Stream, error) {
+	var stream protokol.Stream
+	h := HandlerFunc(func(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+		if !method.IsClientStreaming() {
+			transform.ApplyDefaults(c.Schema, method.Input, req.Input)
+			if err := c.validate(method, req); err != nil {
+				return nil, err
+			}
+		}
+		backend, ok := c.Backends.Get(svc.BackendFor(method))
+		if !ok {
+			return nil, protokol.ErrBackendNotFound
+		}
+		s, err := backend.Stream(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		stream = c.wrapStream(method, s)
+		return &protokol.Response{}, nil
+	})
+
+	if _, err := Chain(h, c.Middleware...).Handle(ctx, req); err != nil {
+		if stream != nil {
+			stream.Close()
+		}
+		return nil, err
+	}
+	return stream, nil
+}
+
+func (c Config) validate(method schema.Method, req *protokol.Request) error {
+	if c.SkipValidation {
+		return nil
+	}
+	return transform.Validate(c.Schema, method.Input, req.Input)
 }
 
 // Middleware wraps handler logic.
@@ -0,0 +1,96 @@
+package adapters
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/transform"
+)
+
+// ErrorMapper translates an error returned by the handler chain into the
+// error reported to the client, e.g. a domain error into a code and public
+// message. It returns nil to leave err to the default mapping. The returned
+// error is sent as it is, in production mode too.
+type ErrorMapper func(ctx context.Context, err error) *protokol.Error
+
+// PublicError returns the error to report to the client for err, which
+// adapters translate into their protocol's status. The ErrorMapper is tried
+// first. Otherwise, with DebugErrors, the result has the message of the full
+// error chain. In production mode:
+//
+//   - Errors with code CodeInternal, CodeUnknown or CodeDataLoss are replaced
+//     by a generic message with a correlation id, also sent as a
+//     protokol.RequestInfo detail, and logged with that id.
+//   - Other errors carry the Message and details of the first
+//     *protokol.Error in the chain, so messages of wrapped causes, including
+//     the error arguments of protokol.Errorf, are not sent. Validation
+//     errors keep their message.
+//   - Errors without a *protokol.Error, such as context errors, get a
+//     message naming their code.
+func (c Config) PublicError(ctx context.Context, err error) *protokol.Error {
+	if err == nil {
+		return nil
+	}
+	if c.ErrorMapper != nil {
+		if e := c.ErrorMapper(ctx, err); e != nil {
+			return e
+		}
+	}
+	e := protokol.AsError(err)
+	if c.DebugErrors {
+		return e
+	}
+
+	switch e.Code {
+	case protokol.CodeInternal, protokol.CodeUnknown, protokol.CodeDataLoss:
+		id := correlationID()
+		c.errorLogger().LogAttrs(ctx, slog.LevelError, "internal error",
+			slog.String("correlation_id", id),
+			slog.String("error", err.Error()),
+		)
+		return &protokol.Error{
+			Code:    e.Code,
+			Message: "internal error (correlation id " + id + ")",
+			Details: []any{&protokol.RequestInfo{RequestID: id}},
+			Err:     err,
+		}
+	}
+
+	var verr *transform.ValidationError
+	if errors.As(err, &verr) {
+		return &protokol.Error{Code: e.Code, Message: verr.Error(), Err: err}
+	}
+	var perr *protokol.Error
+	if errors.As(err, &perr) {
+		msg := perr.Message
+		if msg == "" {
+			msg = codeMessage(perr.Code)
+		}
+		return &protokol.Error{Code: perr.Code, Message: msg, Details: perr.Details, Err: err}
+	}
+	return &protokol.Error{Code: e.Code, Message: codeMessage(e.Code), Err: err}
+}
+
+func (c Config) errorLogger() *slog.Logger {
+	if c.ErrorLogger != nil {
+		return c.ErrorLogger
+	}
+	return slog.Default()
+}
+
+// codeMessage returns a message naming code, e.g. "deadline exceeded".
+func codeMessage(code protokol.Code) string {
+	return strings.ToLower(strings.ReplaceAll(code.String(), "_", " "))
+}
+
+// correlationID returns a random id for finding a logged error.
+func correlationID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,118 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/transform"
+)
+
+var errDatabase = errors.New("pq: connection refused to 10.0.0.5")
+
+func TestPublicError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		debug   bool
+		mapper  ErrorMapper
+		code    protokol.Code
+		message string
+	}{
+		{
+			name:    "message without wrapped causes",
+			err:     fmt.Errorf("load user: %w", protokol.Errorf(protokol.CodeNotFound, "user %d not found", 7, errDatabase)),
+			code:    protokol.CodeNotFound,
+			message: "user 7 not found",
+		},
+		{
+			name:    "empty message names the code",
+			err:     protokol.NewError(protokol.CodeFailedPrecondition, ""),
+			code:    protokol.CodeFailedPrecondition,
+			message: "failed precondition",
+		},
+		{
+			name:    "validation errors keep their message",
+			err:     &transform.ValidationError{Violations: []transform.Violation{{Field: "id", Message: "required"}}},
+			code:    protokol.CodeInvalidArgument,
+			message: "invalid argument: id: required",
+		},
+		{
+			name:    "context errors",
+			err:     fmt.Errorf("call upstream: %w", context.DeadlineExceeded),
+			code:    protokol.CodeDeadlineExceeded,
+			message: "deadline exceeded",
+		},
+		{
+			name:    "debug errors keep the chain",
+			err:     fmt.Errorf("load user: %w", errDatabase),
+			debug:   true,
+			code:    protokol.CodeInternal,
+			message: "load user: pq: connection refused to 10.0.0.5",
+		},
+		{
+			name: "mapped errors",
+			err:  errDatabase,
+			mapper: func(ctx context.Context, err error) *protokol.Error {
+				if errors.Is(err, errDatabase) {
+					return protokol.NewError(protokol.CodeUnavailable, "try again later")
+				}
+				return nil
+			},
+			code:    protokol.CodeUnavailable,
+			message: "try again later",
+		},
+		{
+			name:    "mapper returning nil",
+			err:     protokol.NewError(protokol.CodeAborted, "conflict"),
+			mapper:  func(ctx context.Context, err error) *protokol.Error { return nil },
+			code:    protokol.CodeAborted,
+			message: "conflict",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Config{DebugErrors: tt.debug, ErrorMapper: tt.mapper}
+			e := c.PublicError(context.Background(), tt.err)
+			if e.Code != tt.code || e.Error() != tt.message {
+				t.Errorf("PublicError = %v %q, want %v %q", e.Code, e.Error(), tt.code, tt.message)
+			}
+		})
+	}
+
+	if e := (Config{}).PublicError(context.Background(), nil); e != nil {
+		t.Errorf("PublicError(nil) = %v", e)
+	}
+}
+
+func TestPublicErrorHidesInternalErrors(t *testing.T) {
+	for _, code := range []protokol.Code{protokol.CodeInternal, protokol.CodeUnknown, protokol.CodeDataLoss} {
+		t.Run(code.String(), func(t *testing.T) {
+			var logs bytes.Buffer
+			c := Config{ErrorLogger: slog.New(slog.NewTextHandler(&logs, nil))}
+			err := protokol.Errorf(code, "write user", errDatabase)
+			e := c.PublicError(context.Background(), err)
+
+			m := regexp.MustCompile(`^internal error \(correlation id ([0-9a-f]{16})\)$`).FindStringSubmatch(e.Error())
+			if e.Code != code || m == nil {
+				t.Fatalf("PublicError = %v %q", e.Code, e.Error())
+			}
+			info, ok := protokol.ErrorDetail[*protokol.RequestInfo](e)
+			if !ok || info.RequestID != m[1] {
+				t.Errorf("details = %v, want RequestInfo %s", e.Details, m[1])
+			}
+			if !errors.Is(e, errDatabase) {
+				t.Error("cause not kept for logging")
+			}
+			if log := logs.String(); !strings.Contains(log, "correlation_id="+m[1]) || !strings.Contains(log, "10.0.0.5") {
+				t.Errorf("log = %s", log)
+			}
+		})
+	}
+}
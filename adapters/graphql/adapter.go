@@ -0,0 +1,460 @@
+// Package graphql exposes the schema as a GraphQL API. Query, mutation and
+// subscription roots are generated from the services' methods.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/transform"
+)
+
+// Config for GraphQL adapter.
+type Config struct {
+	adapters.Config
+	Listen string
+	// Path is the HTTP path serving the GraphQL endpoint. Defaults to "/graphql".
+	Path string
+}
+
+// Adapter implements the GraphQL protocol over HTTP. Subscriptions are
+// delivered as Server-Sent Events when the client accepts text/event-stream.
+type Adapter struct {
+	config Config
+	server *http.Server
+	schema graphql.Schema
+	err    error
+}
+
+func New(cfg Config) *Adapter {
+	if cfg.Schema == nil {
+		panic("graphql: schema is required")
+	}
+	if cfg.Backends == nil {
+		panic("graphql: backends registry is required")
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/graphql"
+	}
+
+	a := &Adapter{config: cfg}
+	a.schema, a.err = a.buildSchema()
+	return a
+}
+
+func (a *Adapter) Name() string {
+	return "graphql"
+}
+
+func (a *Adapter) Start(ctx context.Context) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(a.config.Path, a)
+	a.server = &http.Server{
+		Addr:    a.config.Listen,
+		Handler: mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return a.Stop(context.Background())
+	}
+}
+
+func (a *Adapter) Stop(ctx context.Context) error {
+	if a.server != nil {
+		return a.server.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Schema returns the generated GraphQL schema, or an error if the protokol
+// schema could not be converted.
+func (a *Adapter) Schema() (graphql.Schema, error) {
+	return a.schema, a.err
+}
+
+func (a *Adapter) buildSchema() (graphql.Schema, error) {
+	b := newTypeBuilder(a.config.Schema)
+	query := graphql.Fields{}
+	mutation := graphql.Fields{}
+	subscription := graphql.Fields{}
+	roots := map[operation]graphql.Fields{opQuery: query, opMutation: mutation, opSubscription: subscription}
+
+	// Methods of different services sharing a name are prefixed with their
+	// service name, for their root fields and generated type names.
+	counts := make(map[operation]map[string]int)
+	for _, svc := range a.config.Schema.Services {
+		for _, method := range svc.Methods {
+			op := operationOf(method)
+			if counts[op] == nil {
+				counts[op] = make(map[string]int)
+			}
+			counts[op][lowerFirst(method.Name)]++
+		}
+	}
+
+	for _, svc := range a.config.Schema.Services {
+		for _, method := range svc.Methods {
+			op := operationOf(method)
+			if op == opNone {
+				continue
+			}
+
+			prefix := method.Name
+			if counts[op][lowerFirst(method.Name)] > 1 {
+				prefix = svc.Name + method.Name
+			}
+			field, err := a.buildField(b, svc, method, op, prefix)
+			if err != nil {
+				return graphql.Schema{}, fmt.Errorf("graphql: %s.%s: %w", svc.Name, method.Name, err)
+			}
+
+			name := lowerFirst(prefix)
+			if _, exists := roots[op][name]; exists {
+				return graphql.Schema{}, fmt.Errorf("graphql: %s.%s: duplicate field %q", svc.Name, method.Name, name)
+			}
+			roots[op][name] = field
+		}
+	}
+
+	// GraphQL requires a query root with at least one field.
+	if len(query) == 0 {
+		query["_empty"] = &graphql.Field{Type: graphql.Boolean}
+	}
+
+	cfg := graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{Name: "Query", Fields: query}),
+	}
+	if len(mutation) > 0 {
+		cfg.Mutation = graphql.NewObject(graphql.ObjectConfig{Name: "Mutation", Fields: mutation})
+	}
+	if len(subscription) > 0 {
+		cfg.Subscription = graphql.NewObject(graphql.ObjectConfig{Name: "Subscription", Fields: subscription})
+	}
+	return graphql.NewSchema(cfg)
+}
+
+// buildField builds the root field of a method. prefix names the types
+// generated for anonymous inputs and outputs, as in "{prefix}Request".
+func (a *Adapter) buildField(b *typeBuilder, svc schema.Service, method schema.Method, op operation, prefix string) (*graphql.Field, error) {
+	args, err := b.args(method.Input, prefix+"Request")
+	if err != nil {
+		return nil, err
+	}
+	out, err := b.output(method.Output, prefix+"Response")
+	if err != nil {
+		return nil, err
+	}
+
+	field := &graphql.Field{
+		Type:        out,
+		Args:        args,
+		Description: method.Description,
+	}
+	if op == opSubscription {
+		field.Subscribe = a.subscriber(svc, method)
+		field.Resolve = func(p graphql.ResolveParams) (any, error) {
+			if err, ok := p.Source.(*resolverError); ok {
+				return nil, err
+			}
+			return p.Source, nil
+		}
+	} else {
+		field.Resolve = a.resolver(svc, method)
+	}
+	return field, nil
+}
+
+func (a *Adapter) resolver(svc schema.Service, method schema.Method) graphql.FieldResolveFn {
+	handler := a.config.Handler(svc, method)
+
+	return func(p graphql.ResolveParams) (any, error) {
+		req := newRequest(p.Context, svc, method, p.Args)
+		resp, err := handler.Handle(p.Context, req)
+		if err != nil {
+			return nil, a.newError(p.Context, err)
+		}
+		return resp.Output, nil
+	}
+}
+
+// subscriber opens a backend stream and forwards each message on a channel
+// until the stream ends or the subscription context is cancelled. A stream
+// error is forwarded as a *resolverError, which the field resolver returns
+// so the last result carries it in "errors".
+func (a *Adapter) subscriber(svc schema.Service, method schema.Method) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		req := newRequest(p.Context, svc, method, p.Args)
+		stream, err := a.config.OpenStream(p.Context, svc, method, req)
+		if err != nil {
+			return nil, a.newError(p.Context, err)
+		}
+
+		ch := make(chan any)
+		go func() {
+			defer close(ch)
+			defer stream.Close()
+			for {
+				var msg any
+				m, err := stream.Recv()
+				switch {
+				case err == io.EOF:
+					return
+				case err != nil:
+					msg = a.newError(p.Context, err)
+				default:
+					msg = m
+				}
+				select {
+				case ch <- msg:
+				case <-p.Context.Done():
+					return
+				}
+				if err != nil {
+					return
+				}
+			}
+		}()
+		return ch, nil
+	}
+}
+
+// ServeHTTP executes GraphQL requests. It can be mounted on any router when
+// the adapter's own server is not used.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if a.err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": a.err.Error()})
+		return
+	}
+
+	params, err := parseParams(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	// GET requests can be triggered cross-site, so they may only read.
+	if r.Method == http.MethodGet && operationType(params) != ast.OperationTypeQuery {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "only queries may be sent with GET"})
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), requestKey{}, requestInfo{
+		metadata:   r.Header,
+		remoteAddr: r.RemoteAddr,
+	})
+
+	gp := graphql.Params{
+		Schema:         a.schema,
+		RequestString:  params.Query,
+		VariableValues: params.Variables,
+		OperationName:  params.OperationName,
+		Context:        ctx,
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		a.serveSubscription(w, r, gp)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, graphql.Do(gp))
+}
+
+func (a *Adapter) serveSubscription(w http.ResponseWriter, r *http.Request, gp graphql.Params) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming unsupported"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	results := graphql.Subscribe(gp)
+	// Drain remaining results so the executor goroutine can exit after the
+	// client goes away.
+	defer func() {
+		go func() {
+			for range results {
+			}
+		}()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case res, ok := <-results:
+			if !ok {
+				io.WriteString(w, "event: complete\ndata:\n\n")
+				flusher.Flush()
+				return
+			}
+			data, err := json.Marshal(res)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
+
+type requestParams struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables"`
+	OperationName string         `json:"operationName"`
+}
+
+func parseParams(r *http.Request) (requestParams, error) {
+	var p requestParams
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		p.Query = q.Get("query")
+		p.OperationName = q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &p.Variables); err != nil {
+				return p, errors.New("invalid variables")
+			}
+		}
+	case http.MethodPost:
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return p, err
+			}
+			p.Query = string(body)
+		} else if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			return p, errors.New("invalid JSON body")
+		}
+	default:
+		return p, errors.New("method not allowed")
+	}
+	if p.Query == "" {
+		return p, errors.New("query is required")
+	}
+	return p, nil
+}
+
+// operationType returns the type of the operation the request executes:
+// the one named by OperationName, or the only one in the document. It is
+// empty if the document does not parse or has no such operation.
+func operationType(p requestParams) string {
+	doc, err := parser.Parse(parser.ParseParams{Source: p.Query})
+	if err != nil {
+		return ""
+	}
+	var op *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		d, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if p.OperationName == "" {
+			if op != nil {
+				return ""
+			}
+			op = d
+		} else if d.Name != nil && d.Name.Value == p.OperationName {
+			op = d
+		}
+	}
+	if op == nil {
+		return ""
+	}
+	return op.Operation
+}
+
+type requestKey struct{}
+
+// requestInfo carries transport details from ServeHTTP to the resolvers.
+type requestInfo struct {
+	metadata   map[string][]string
+	remoteAddr string
+}
+
+func newRequest(ctx context.Context, svc schema.Service, method schema.Method, args map[string]any) *protokol.Request {
+	req := &protokol.Request{
+		Service:  svc.Name,
+		Method:   method.Name,
+		Input:    args,
+		Metadata: make(map[string][]string),
+	}
+	if req.Input == nil {
+		req.Input = make(map[string]any)
+	}
+	if info, ok := ctx.Value(requestKey{}).(requestInfo); ok {
+		for k, v := range info.metadata {
+			req.Metadata[k] = v
+		}
+		req.RemoteAddr = info.remoteAddr
+	}
+	return req
+}
+
+// resolverError reports an error as converted by Config.PublicError, with
+// the canonical code, e.g. "NOT_FOUND", and error details in the
+// "extensions" field.
+type resolverError struct {
+	err error
+	e   *protokol.Error
+}
+
+func (a *Adapter) newError(ctx context.Context, err error) error {
+	return &resolverError{err: err, e: a.config.PublicError(ctx, err)}
+}
+
+func (e *resolverError) Error() string {
+	return e.e.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e *resolverError) Extensions() map[string]any {
+	ext := map[string]any{"code": e.e.Code.String()}
+	if len(e.e.Details) > 0 {
+		ext["details"] = e.e.Details
+	}
+	var verr *transform.ValidationError
+	if errors.As(e.e, &verr) {
+		ext["violations"] = verr.Violations
+	}
+	return ext
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
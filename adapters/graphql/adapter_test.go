@@ -0,0 +1,148 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/backend"
+	"github.com/jekabolt/protokol/schema"
+)
+
+func testAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	user := schema.Message("User").
+		RequiredField("id", schema.String).
+		Field("name", schema.String).
+		Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Backend("test").
+		Method(schema.Unary("GetUser").Input(user).Output(user).Build()).
+		Method(schema.Unary("CreateUser").Input(user).Output(user).Build()).
+		MustBuild())
+
+	h := backend.NewHandler()
+	h.Register("Users", "GetUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		if input["id"] == "missing" {
+			return nil, protokol.NewError(protokol.CodeNotFound, "user not found")
+		}
+		return map[string]any{"id": input["id"], "name": "ada"}, nil
+	})
+	h.Register("Users", "CreateUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		return input, nil
+	})
+	reg := protokol.NewBackendRegistry()
+	reg.Register("test", h)
+	return New(Config{Config: adapters.Config{
+		Schema:      s,
+		Backends:    reg,
+		ErrorLogger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}})
+}
+
+func TestServeHTTP(t *testing.T) {
+	a := testAdapter(t)
+
+	tests := []struct {
+		name        string
+		method      string
+		contentType string
+		query       string
+		operation   string
+		status      int
+		want        string
+	}{
+		{
+			name:   "query with GET",
+			method: http.MethodGet,
+			query:  `{ getUser(id: "1") { id name } }`,
+			status: http.StatusOK,
+			want:   `{"data":{"getUser":{"id":"1","name":"ada"}}}`,
+		},
+		{
+			name:      "named query with GET",
+			method:    http.MethodGet,
+			query:     `query A { getUser(id: "1") { id } } mutation B { createUser(id: "2") { id } }`,
+			operation: "A",
+			status:    http.StatusOK,
+			want:      `{"data":{"getUser":{"id":"1"}}}`,
+		},
+		{
+			name:   "mutation with GET",
+			method: http.MethodGet,
+			query:  `mutation { createUser(id: "2") { id } }`,
+			status: http.StatusMethodNotAllowed,
+		},
+		{
+			name:      "named mutation with GET",
+			method:    http.MethodGet,
+			query:     `query A { getUser(id: "1") { id } } mutation B { createUser(id: "2") { id } }`,
+			operation: "B",
+			status:    http.StatusMethodNotAllowed,
+		},
+		{
+			name:   "mutation with POST",
+			method: http.MethodPost,
+			query:  `mutation { createUser(id: "2") { id } }`,
+			status: http.StatusOK,
+			want:   `{"data":{"createUser":{"id":"2"}}}`,
+		},
+		{
+			name:        "application/graphql body",
+			method:      http.MethodPost,
+			contentType: "application/graphql",
+			query:       `{ getUser(id: "1") { name } }`,
+			status:      http.StatusOK,
+			want:        `{"data":{"getUser":{"name":"ada"}}}`,
+		},
+		{
+			name:   "error code in extensions",
+			method: http.MethodPost,
+			query:  `{ getUser(id: "missing") { id } }`,
+			status: http.StatusOK,
+			want:   `{"data":{"getUser":null},"errors":[{"message":"user not found","locations":[{"line":1,"column":3}],"path":["getUser"],"extensions":{"code":"NOT_FOUND"}}]}`,
+		},
+		{
+			name:   "missing query",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r *http.Request
+			switch {
+			case tt.method == http.MethodGet:
+				q := url.Values{"query": {tt.query}, "operationName": {tt.operation}}
+				r = httptest.NewRequest(http.MethodGet, "/graphql?"+q.Encode(), nil)
+			case tt.contentType != "":
+				r = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.query))
+				r.Header.Set("Content-Type", tt.contentType)
+			default:
+				body, _ := json.Marshal(map[string]any{"query": tt.query, "operationName": tt.operation})
+				r = httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+				r.Header.Set("Content-Type", "application/json")
+			}
+			w := httptest.NewRecorder()
+			a.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.status == http.StatusMethodNotAllowed && w.Header().Get("Allow") != http.MethodPost {
+				t.Errorf("Allow = %q, want POST", w.Header().Get("Allow"))
+			}
+			if tt.want != "" && strings.TrimSpace(w.Body.String()) != tt.want {
+				t.Errorf("body = %s, want %s", w.Body, tt.want)
+			}
+		})
+	}
+}
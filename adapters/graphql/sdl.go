@@ -0,0 +1,285 @@
+package graphql
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/schema"
+)
+
+// SDL renders the GraphQL schema the adapter serves for s as Schema
+// Definition Language. No backends are needed. Types and fields are sorted
+// by name so the output is stable and can be checked in.
+func SDL(s *schema.Schema) (string, error) {
+	a := &Adapter{config: Config{Config: adapters.Config{Schema: s}}}
+	gs, err := a.buildSchema()
+	if err != nil {
+		return "", err
+	}
+	return PrintSchema(gs), nil
+}
+
+// WriteSDL writes the SDL for s to the file at path.
+func WriteSDL(s *schema.Schema, path string) error {
+	sdl, err := SDL(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(sdl), 0o644)
+}
+
+// PrintSchema renders gs as Schema Definition Language. Root types come
+// first, followed by the remaining types in name order. Built-in scalars and
+// introspection types are omitted.
+func PrintSchema(gs graphql.Schema) string {
+	var roots []graphql.Type
+	rootNames := make(map[string]bool)
+	for _, obj := range []*graphql.Object{gs.QueryType(), gs.MutationType(), gs.SubscriptionType()} {
+		if obj != nil {
+			roots = append(roots, obj)
+			rootNames[obj.Name()] = true
+		}
+	}
+
+	var names []string
+	for name := range gs.TypeMap() {
+		if rootNames[name] || strings.HasPrefix(name, "__") || builtinScalars[name] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	p := &sdlPrinter{}
+	for _, t := range roots {
+		p.typ(t)
+	}
+	for _, name := range names {
+		p.typ(gs.TypeMap()[name])
+	}
+	return strings.TrimSuffix(p.sb.String(), "\n")
+}
+
+var builtinScalars = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+type sdlPrinter struct {
+	sb strings.Builder
+}
+
+func (p *sdlPrinter) typ(t graphql.Type) {
+	switch t := t.(type) {
+	case *graphql.Scalar:
+		p.description("", t.Description())
+		fmt.Fprintf(&p.sb, "scalar %s\n", t.Name())
+
+	case *graphql.Enum:
+		p.description("", t.Description())
+		fmt.Fprintf(&p.sb, "enum %s {\n", t.Name())
+		values := t.Values()
+		sort.Slice(values, func(i, j int) bool { return values[i].Name < values[j].Name })
+		for _, v := range values {
+			p.description("  ", v.Description)
+			fmt.Fprintf(&p.sb, "  %s\n", v.Name)
+		}
+		p.sb.WriteString("}\n")
+
+	case *graphql.Object:
+		p.description("", t.Description())
+		fmt.Fprintf(&p.sb, "type %s {\n", t.Name())
+		fields := t.Fields()
+		for _, name := range sortedKeys(fields) {
+			f := fields[name]
+			p.description("  ", f.Description)
+			fmt.Fprintf(&p.sb, "  %s%s: %s\n", name, p.args(f.Args), f.Type)
+		}
+		p.sb.WriteString("}\n")
+
+	case *graphql.InputObject:
+		p.description("", t.Description())
+		fmt.Fprintf(&p.sb, "input %s {\n", t.Name())
+		fields := t.Fields()
+		for _, name := range sortedKeys(fields) {
+			f := fields[name]
+			p.description("  ", f.Description())
+			fmt.Fprintf(&p.sb, "  %s: %s%s\n", name, f.Type, defaultSuffix(f.DefaultValue, f.Type))
+		}
+		p.sb.WriteString("}\n")
+
+	default:
+		return
+	}
+	p.sb.WriteByte('\n')
+}
+
+func (p *sdlPrinter) args(args []*graphql.Argument) string {
+	if len(args) == 0 {
+		return ""
+	}
+	sorted := append([]*graphql.Argument(nil), args...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name() < sorted[j].Name() })
+	parts := make([]string, len(sorted))
+	for i, a := range sorted {
+		parts[i] = fmt.Sprintf("%s: %s%s", a.Name(), a.Type, defaultSuffix(a.DefaultValue, a.Type))
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+// description prints desc as a string, or as a block string if it contains
+// characters a string would have to escape. Block strings take backslashes
+// literally and only need """ escaped.
+func (p *sdlPrinter) description(indent, desc string) {
+	if desc == "" {
+		return
+	}
+	if !strings.ContainsAny(desc, "\r\n\"\\") {
+		fmt.Fprintf(&p.sb, "%s\"%s\"\n", indent, desc)
+		return
+	}
+	desc = strings.ReplaceAll(desc, `"""`, `\"""`)
+	fmt.Fprintf(&p.sb, "%s\"\"\"\n", indent)
+	for _, line := range strings.Split(desc, "\n") {
+		fmt.Fprintf(&p.sb, "%s%s\n", indent, line)
+	}
+	fmt.Fprintf(&p.sb, "%s\"\"\"\n", indent)
+}
+
+func defaultSuffix(v any, t graphql.Input) string {
+	if v == nil {
+		return ""
+	}
+	return " = " + literal(v, t)
+}
+
+// literal renders a Go value as a GraphQL input literal of type t. Slices of
+// any element type are lists, and byte slices are base64 strings, as in
+// JSON.
+func literal(v any, t graphql.Input) string {
+	switch t := t.(type) {
+	case *graphql.NonNull:
+		return literal(v, t.OfType)
+	case *graphql.List:
+		items, ok := listItems(v)
+		if !ok {
+			return literal(v, t.OfType)
+		}
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = literal(item, t.OfType)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case *graphql.Enum:
+		return fmt.Sprint(v)
+	case *graphql.InputObject:
+		m, ok := v.(map[string]any)
+		if !ok {
+			break
+		}
+		fields := t.Fields()
+		parts := make([]string, 0, len(m))
+		for _, k := range sortedKeys(m) {
+			var ft graphql.Input = JSON
+			if f, ok := fields[k]; ok {
+				ft = f.Type
+			}
+			parts = append(parts, k+": "+literal(m[k], ft))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+
+	switch v := v.(type) {
+	case string:
+		return quote(v)
+	case []byte:
+		return quote(base64.StdEncoding.EncodeToString(v))
+	case map[string]any:
+		parts := make([]string, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			parts = append(parts, k+": "+literal(v[k], JSON))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	if items, ok := listItems(v); ok {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = literal(item, JSON)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	return fmt.Sprint(v)
+}
+
+// listItems returns the elements of a slice or array other than a byte
+// slice.
+func listItems(v any) ([]any, bool) {
+	if items, ok := v.([]any); ok {
+		return items, true
+	}
+	if _, ok := v.([]byte); ok {
+		return nil, false
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	items := make([]any, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, true
+}
+
+// quote renders s as a GraphQL string, which has fewer escapes than Go:
+// only \" \\ \b \f \n \r \t and \uXXXX for other control characters.
+// Other characters, including non-ASCII ones, are written as they are.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
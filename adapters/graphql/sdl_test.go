@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+func TestLiteral(t *testing.T) {
+	level := graphql.NewEnum(graphql.EnumConfig{
+		Name:   "Level",
+		Values: graphql.EnumValueConfigMap{"LOW": {Value: "LOW"}, "HIGH": {Value: "HIGH"}},
+	})
+	point := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "Point",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"x":     {Type: graphql.Int},
+			"label": {Type: graphql.String},
+		},
+	})
+
+	tests := []struct {
+		name string
+		v    any
+		t    graphql.Input
+		want string
+	}{
+		{"int", int32(3), graphql.Int, "3"},
+		{"float", 1.5, graphql.Float, "1.5"},
+		{"bool", true, graphql.Boolean, "true"},
+		{"string", "ada", graphql.String, `"ada"`},
+		{"string escapes", "a\"b\\c\n\t\r\b\f", graphql.String, `"a\"b\\c\n\t\r\b\f"`},
+		{"control characters", "\x00\x1b", graphql.String, `"\u0000\u001B"`},
+		{"non-ASCII kept", "é日本", graphql.String, `"é日本"`},
+		{"bytes", []byte("hi"), graphql.String, `"aGk="`},
+		{"enum", "LOW", level, "LOW"},
+		{"typed string list", []string{"a", "b"}, graphql.NewList(graphql.String), `["a", "b"]`},
+		{"typed int list", []int32{1, 2}, graphql.NewNonNull(graphql.NewList(graphql.Int)), "[1, 2]"},
+		{"enum list", []string{"LOW", "HIGH"}, graphql.NewList(level), "[LOW, HIGH]"},
+		{"any list", []any{"a", int64(1)}, graphql.NewList(graphql.String), `["a", 1]`},
+		{"nested list", [][]int{{1}, {2, 3}}, graphql.NewList(graphql.NewList(graphql.Int)), "[[1], [2, 3]]"},
+		{"single value for list", "a", graphql.NewList(graphql.String), `"a"`},
+		{"input object", map[string]any{"x": 1, "label": "a\"b"}, point, `{label: "a\"b", x: 1}`},
+		{"JSON object", map[string]any{"k": []string{"v"}}, JSON, `{k: ["v"]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := literal(tt.v, tt.t); got != tt.want {
+				t.Errorf("literal = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSDL(t *testing.T) {
+	level := schema.Enum("Level").Value("LOW", 1).Value("HIGH", 2).Build()
+	in := schema.Message("SearchRequest").
+		FieldWithDefault("tags", schema.Repeated(schema.String), []string{"a", `b"c`}).
+		FieldWithDefault("levels", schema.Repeated(level), []string{"LOW", "HIGH"}).
+		FieldWithDefault("query", schema.String, "tab\there").
+		Build()
+	out := schema.Message("SearchResponse").Field("count", schema.Int32).Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Search").
+		Method(schema.Unary("Search").Description(`Searches C:\data`).Input(in).Output(out).Build()).
+		MustBuild())
+
+	got, err := SDL(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `type Query {
+  """
+  Searches C:\data
+  """
+  search(levels: [Level] = [LOW, HIGH], query: String = "tab\there", tags: [String] = ["a", "b\"c"]): SearchResponse
+}
+
+enum Level {
+  HIGH
+  LOW
+}
+
+type SearchResponse {
+  count: Int
+}
+`
+	if strings.TrimSpace(got) != strings.TrimSpace(want) {
+		t.Errorf("SDL =\n%s\nwant\n%s", got, want)
+	}
+}
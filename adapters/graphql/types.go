@@ -0,0 +1,382 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// JSON is a scalar carrying arbitrary JSON. It is used for map types and for
+// messages without fields, which GraphQL object types cannot represent.
+var JSON = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Arbitrary JSON value.",
+	Serialize: func(value any) any {
+		return value
+	},
+	ParseValue: func(value any) any {
+		return value
+	},
+	ParseLiteral: parseJSONLiteral,
+})
+
+// Int64 is a 64-bit integer scalar. GraphQL's Int is limited to 32 bits.
+// Values are serialized as numbers and accepted as numbers or strings.
+var Int64 = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Int64",
+	Description: "64-bit signed integer.",
+	Serialize: func(value any) any {
+		n, err := toInt64(value)
+		if err != nil {
+			return nil
+		}
+		return n
+	},
+	ParseValue: func(value any) any {
+		n, err := toInt64(value)
+		if err != nil {
+			return nil
+		}
+		return n
+	},
+	ParseLiteral: func(v ast.Value) any {
+		switch v := v.(type) {
+		case *ast.IntValue:
+			n, err := strconv.ParseInt(v.Value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			return n
+		case *ast.StringValue:
+			n, err := strconv.ParseInt(v.Value, 10, 64)
+			if err != nil {
+				return nil
+			}
+			return n
+		}
+		return nil
+	},
+})
+
+func parseJSONLiteral(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.StringValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.IntValue:
+		n, _ := strconv.ParseInt(v.Value, 10, 64)
+		return n
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(v.Value, 64)
+		return f
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.ListValue:
+		out := make([]any, len(v.Values))
+		for i, item := range v.Values {
+			out[i] = parseJSONLiteral(item)
+		}
+		return out
+	case *ast.ObjectValue:
+		out := make(map[string]any, len(v.Fields))
+		for _, f := range v.Fields {
+			out[f.Name.Value] = parseJSONLiteral(f.Value)
+		}
+		return out
+	}
+	return nil
+}
+
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case uint32:
+		return int64(n), nil
+	case float64:
+		return int64(n), nil
+	case float32:
+		return int64(n), nil
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	return 0, fmt.Errorf("expected integer, got %T", v)
+}
+
+// typeBuilder converts schema types into GraphQL types. Named messages and
+// enums are built once and reused; the first definition of a name wins.
+// Type references are resolved against schema.
+type typeBuilder struct {
+	schema  *schema.Schema
+	objects map[string]graphql.Output
+	inputs  map[string]graphql.Input
+	enums   map[string]*graphql.Enum
+}
+
+func newTypeBuilder(s *schema.Schema) *typeBuilder {
+	return &typeBuilder{
+		schema:  s,
+		objects: make(map[string]graphql.Output),
+		inputs:  make(map[string]graphql.Input),
+		enums:   make(map[string]*graphql.Enum),
+	}
+}
+
+// resolve returns the type t refers to.
+func (b *typeBuilder) resolve(t schema.Type) (schema.Type, error) {
+	resolved, ok := b.schema.Resolve(t)
+	if !ok {
+		return t, fmt.Errorf("reference to unregistered type %s", t.Name)
+	}
+	return resolved, nil
+}
+
+// output returns the GraphQL output type for t.
+func (b *typeBuilder) output(t schema.Type, fallback string) (graphql.Output, error) {
+	t, err := b.resolve(t)
+	if err != nil {
+		return nil, err
+	}
+	switch t.Kind {
+	case schema.KindRepeated:
+		if t.Elem == nil {
+			return nil, errors.New("repeated type without element type")
+		}
+		elem, err := b.output(*t.Elem, fallback)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewList(elem), nil
+	case schema.KindMessage:
+		return b.object(t, fallback)
+	case schema.KindEnum:
+		return b.enum(t, fallback), nil
+	default:
+		return scalar(t)
+	}
+}
+
+// input returns the GraphQL input type for t. Required fields are wrapped in
+// NonNull by the caller, as they are for output types.
+func (b *typeBuilder) input(t schema.Type, fallback string) (graphql.Input, error) {
+	t, err := b.resolve(t)
+	if err != nil {
+		return nil, err
+	}
+	switch t.Kind {
+	case schema.KindRepeated:
+		if t.Elem == nil {
+			return nil, errors.New("repeated type without element type")
+		}
+		elem, err := b.input(*t.Elem, fallback)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewList(elem), nil
+	case schema.KindMessage:
+		return b.inputObject(t, fallback)
+	case schema.KindEnum:
+		return b.enum(t, fallback), nil
+	default:
+		return scalar(t)
+	}
+}
+
+func (b *typeBuilder) object(t schema.Type, fallback string) (graphql.Output, error) {
+	name := typeName(t, fallback)
+	if obj, ok := b.objects[name]; ok {
+		return obj, nil
+	}
+	if len(t.Fields) == 0 {
+		b.objects[name] = JSON
+		return JSON, nil
+	}
+
+	// Fields are resolved lazily so that recursive types can refer to the
+	// object before its fields are built.
+	fields := graphql.Fields{}
+	obj := graphql.NewObject(graphql.ObjectConfig{
+		Name:   name,
+		Fields: graphql.FieldsThunk(func() graphql.Fields { return fields }),
+	})
+	b.objects[name] = obj
+	for _, f := range t.Fields {
+		typ, err := b.output(f.Type, name+exportName(f.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, f.Name, err)
+		}
+		if f.Required {
+			typ = graphql.NewNonNull(typ)
+		}
+		fields[f.Name] = &graphql.Field{Type: typ, Description: f.Description}
+	}
+	return obj, nil
+}
+
+func (b *typeBuilder) inputObject(t schema.Type, fallback string) (graphql.Input, error) {
+	name := typeName(t, fallback) + "Input"
+	if obj, ok := b.inputs[name]; ok {
+		return obj, nil
+	}
+	if len(t.Fields) == 0 {
+		b.inputs[name] = JSON
+		return JSON, nil
+	}
+
+	fields := graphql.InputObjectConfigFieldMap{}
+	obj := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: name,
+		Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+			return fields
+		}),
+	})
+	b.inputs[name] = obj
+	for _, f := range t.Fields {
+		typ, err := b.input(f.Type, typeName(t, fallback)+exportName(f.Name))
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, f.Name, err)
+		}
+		if f.Required {
+			typ = graphql.NewNonNull(typ)
+		}
+		fields[f.Name] = &graphql.InputObjectFieldConfig{Type: typ, DefaultValue: f.Default, Description: f.Description}
+	}
+	return obj, nil
+}
+
+func (b *typeBuilder) enum(t schema.Type, fallback string) *graphql.Enum {
+	name := typeName(t, fallback)
+	if e, ok := b.enums[name]; ok {
+		return e
+	}
+	values := graphql.EnumValueConfigMap{}
+	for _, v := range t.Values {
+		values[v.Name] = &graphql.EnumValueConfig{Value: v.Name}
+	}
+	e := graphql.NewEnum(graphql.EnumConfig{Name: name, Values: values})
+	b.enums[name] = e
+	return e
+}
+
+// args converts the fields of a method's input message into field arguments.
+func (b *typeBuilder) args(t schema.Type, fallback string) (graphql.FieldConfigArgument, error) {
+	t, err := b.resolve(t)
+	if err != nil {
+		return nil, err
+	}
+	if t.Kind == schema.KindInvalid {
+		return nil, nil
+	}
+	if t.Kind != schema.KindMessage {
+		return nil, errors.New("input must be a message type")
+	}
+	args := graphql.FieldConfigArgument{}
+	for _, f := range t.Fields {
+		typ, err := b.input(f.Type, typeName(t, fallback)+exportName(f.Name))
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", f.Name, err)
+		}
+		if f.Required {
+			typ = graphql.NewNonNull(typ)
+		}
+		args[f.Name] = &graphql.ArgumentConfig{Type: typ, DefaultValue: f.Default}
+	}
+	return args, nil
+}
+
+func scalar(t schema.Type) (*graphql.Scalar, error) {
+	switch t.Kind {
+	case schema.KindBool:
+		return graphql.Boolean, nil
+	case schema.KindInt32:
+		return graphql.Int, nil
+	case schema.KindInt64:
+		return Int64, nil
+	case schema.KindFloat32, schema.KindFloat64:
+		return graphql.Float, nil
+	case schema.KindString, schema.KindBytes:
+		return graphql.String, nil
+	case schema.KindMap, schema.KindInvalid:
+		return JSON, nil
+	}
+	return nil, fmt.Errorf("unsupported kind %d", t.Kind)
+}
+
+func typeName(t schema.Type, fallback string) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return fallback
+}
+
+// lowerFirst lower-cases the first letter, turning method names into root
+// field names, e.g. GetUser → getUser.
+func lowerFirst(s string) string {
+	r := []rune(s)
+	if len(r) > 0 {
+		r[0] = unicode.ToLower(r[0])
+	}
+	return string(r)
+}
+
+// exportName upper-cases the first letter and drops underscores, turning
+// field names like "user_id" into "UserId" for generated type names.
+func exportName(s string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range s {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// operation reports the root type a method belongs to.
+type operation int
+
+const (
+	opQuery operation = iota
+	opMutation
+	opSubscription
+	opNone
+)
+
+// operationOf maps a method to a root operation type. Server-streaming
+// methods are subscriptions; client and bidirectional streams have no
+// GraphQL equivalent and are not exposed.
+func operationOf(m schema.Method) operation {
+	switch m.Type {
+	case schema.MethodServerStream:
+		return opSubscription
+	case schema.MethodClientStream, schema.MethodBidirectional:
+		return opNone
+	}
+	for _, p := range []string{"Get", "List", "Find", "Search"} {
+		if strings.HasPrefix(m.Name, p) {
+			return opQuery
+		}
+	}
+	return opMutation
+}
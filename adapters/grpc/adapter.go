@@ -0,0 +1,398 @@
+// Package grpc exposes the schema as a gRPC server. Protobuf descriptors are
+// built at runtime from the schema, so no generated code is required.
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/internal/grpcstatus"
+	"github.com/jekabolt/protokol/internal/protomap"
+	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/schema/protofile"
+	"github.com/jekabolt/protokol/transform"
+)
+
+// Config for gRPC adapter.
+type Config struct {
+	adapters.Config
+	Listen string
+	// Reflection registers the gRPC server reflection service so tools
+	// like grpcurl can discover the generated descriptors.
+	Reflection bool
+	// ServerOptions are passed to grpc.NewServer.
+	ServerOptions []grpc.ServerOption
+}
+
+// Adapter implements the gRPC protocol.
+type Adapter struct {
+	config Config
+	server *grpc.Server
+	files  *protoregistry.Files
+	// names are the schema names of enum values renamed in files.
+	names protomap.EnumNames
+	err   error
+}
+
+func New(cfg Config) *Adapter {
+	if cfg.Schema == nil {
+		panic("grpc: schema is required")
+	}
+	if cfg.Backends == nil {
+		panic("grpc: backends registry is required")
+	}
+
+	a := &Adapter{
+		config: cfg,
+		server: grpc.NewServer(cfg.ServerOptions...),
+	}
+	a.files, a.err = protofile.Files(cfg.Schema)
+	if a.err == nil {
+		a.names, a.err = protofile.EnumValueNames(cfg.Schema)
+	}
+	if a.err == nil {
+		a.err = a.registerServices()
+	}
+	if a.err == nil && cfg.Reflection {
+		opts := reflection.ServerOptions{
+			Services:           a.server,
+			DescriptorResolver: a.files,
+		}
+		reflectionv1.RegisterServerReflectionServer(a.server, reflection.NewServerV1(opts))
+		reflectionv1alpha.RegisterServerReflectionServer(a.server, reflection.NewServer(opts))
+	}
+	return a
+}
+
+func (a *Adapter) Name() string {
+	return "grpc"
+}
+
+// Start listens on the configured address and serves until the context is
+// cancelled. It returns any error encountered while building descriptors.
+func (a *Adapter) Start(ctx context.Context) error {
+	if a.err != nil {
+		return a.err
+	}
+	lis, err := net.Listen("tcp", a.config.Listen)
+	if err != nil {
+		return err
+	}
+	return a.Serve(ctx, lis)
+}
+
+// Serve serves on an existing listener until the context is cancelled.
+func (a *Adapter) Serve(ctx context.Context, lis net.Listener) error {
+	if a.err != nil {
+		return a.err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := a.server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return a.Stop(context.Background())
+	}
+}
+
+// Stop gracefully stops the server, forcing it closed if ctx expires first.
+func (a *Adapter) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		a.server.Stop()
+	}
+	return nil
+}
+
+// Server returns the underlying gRPC server, e.g. to register health checks.
+func (a *Adapter) Server() *grpc.Server {
+	return a.server
+}
+
+// Files returns the descriptors generated from the schema.
+func (a *Adapter) Files() *protoregistry.Files {
+	return a.files
+}
+
+func (a *Adapter) registerServices() error {
+	for _, svc := range a.config.Schema.Services {
+		name := fullServiceName(svc)
+		d, err := a.files.FindDescriptorByName(name)
+		if err != nil {
+			return err
+		}
+		sd := d.(protoreflect.ServiceDescriptor)
+
+		desc := &grpc.ServiceDesc{
+			ServiceName: string(name),
+			HandlerType: (*any)(nil),
+			Metadata:    sd.ParentFile().Path(),
+		}
+		for _, method := range svc.Methods {
+			md := sd.Methods().ByName(protoreflect.Name(method.Name))
+			if method.IsStreaming() {
+				desc.Streams = append(desc.Streams, grpc.StreamDesc{
+					StreamName:    method.Name,
+					Handler:       a.streamHandler(svc, method, md),
+					ServerStreams: method.IsServerStreaming(),
+					ClientStreams: method.IsClientStreaming(),
+				})
+			} else {
+				desc.Methods = append(desc.Methods, grpc.MethodDesc{
+					MethodName: method.Name,
+					Handler:    a.unaryHandler(svc, method, md, desc.ServiceName),
+				})
+			}
+		}
+		a.server.RegisterService(desc, nil)
+	}
+	return nil
+}
+
+// unaryHandler returns the method handler for a unary method of the service
+// named fullService. Unary interceptors from Config.ServerOptions run around
+// the call, as for generated services.
+func (a *Adapter) unaryHandler(svc schema.Service, method schema.Method, md protoreflect.MethodDescriptor, fullService string) func(any, context.Context, func(any) error, grpc.UnaryServerInterceptor) (any, error) {
+	handler := a.config.Handler(svc, method)
+
+	call := func(ctx context.Context, msg any) (any, error) {
+		req := a.newRequest(ctx, svc, method)
+		in, ok := msg.(proto.Message)
+		if !ok {
+			return nil, status.Errorf(codes.Internal, "unexpected request type %T", msg)
+		}
+		req.Input = a.names.ToMap(in.ProtoReflect())
+
+		resp, err := handler.Handle(ctx, req)
+		if err != nil {
+			return nil, a.toStatus(ctx, err)
+		}
+		if err := sendHeader(ctx, resp.Metadata); err != nil {
+			return nil, err
+		}
+
+		out := dynamicpb.NewMessage(md.Output())
+		if err := a.names.FromMap(resp.Output, out); err != nil {
+			return nil, status.Errorf(codes.Internal, "encode response: %v", err)
+		}
+		return out, nil
+	}
+
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := dynamicpb.NewMessage(md.Input())
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + fullService + "/" + method.Name}
+		return interceptor(ctx, in, info, call)
+	}
+}
+
+func (a *Adapter) streamHandler(svc schema.Service, method schema.Method, md protoreflect.MethodDescriptor) grpc.StreamHandler {
+	return func(_ any, ss grpc.ServerStream) error {
+		ctx := ss.Context()
+		req := a.newRequest(ctx, svc, method)
+
+		// Server-streaming calls carry a single request message that is
+		// passed to the backend when the stream is opened.
+		if !method.IsClientStreaming() {
+			in := dynamicpb.NewMessage(md.Input())
+			if err := ss.RecvMsg(in); err != nil {
+				return err
+			}
+			req.Input = a.names.ToMap(in)
+		}
+
+		stream, err := a.config.OpenStream(ctx, svc, method, req)
+		if err != nil {
+			return a.toStatus(ctx, err)
+		}
+		defer stream.Close()
+
+		switch method.Type {
+		case schema.MethodServerStream:
+			return a.sendAll(ss, stream, md)
+		case schema.MethodClientStream:
+			if err := a.recvAll(ss, stream, md); err != nil {
+				return err
+			}
+			msg, err := stream.Recv()
+			if err != nil {
+				return a.toStatus(ctx, err)
+			}
+			return a.sendOne(ss, msg, md)
+		default:
+			// The RPC ends when the backend finishes sending. A failure
+			// forwarding client messages, such as an invalid message,
+			// closes the backend stream to end it early and is returned
+			// instead of the send result. Returning cancels the stream
+			// context and unblocks the receive loop.
+			recvErr := make(chan error, 1)
+			go func() {
+				err := a.recvAll(ss, stream, md)
+				recvErr <- err
+				if err != nil {
+					stream.Close()
+				}
+			}()
+			sendErr := a.sendAll(ss, stream, md)
+			select {
+			case err := <-recvErr:
+				if err != nil {
+					return err
+				}
+			default:
+			}
+			return sendErr
+		}
+	}
+}
+
+// recvAll forwards client messages to the backend until the client
+// half-closes, then half-closes the backend stream.
+func (a *Adapter) recvAll(ss grpc.ServerStream, stream protokol.Stream, md protoreflect.MethodDescriptor) error {
+	for {
+		in := dynamicpb.NewMessage(md.Input())
+		err := ss.RecvMsg(in)
+		if err == io.EOF {
+			if cs, ok := stream.(protokol.CloseSender); ok {
+				return a.toStatus(ss.Context(), cs.CloseSend())
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(a.names.ToMap(in)); err != nil {
+			return a.toStatus(ss.Context(), err)
+		}
+	}
+}
+
+// sendAll forwards backend messages to the client until the backend ends
+// the stream.
+func (a *Adapter) sendAll(ss grpc.ServerStream, stream protokol.Stream, md protoreflect.MethodDescriptor) error {
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return a.toStatus(ss.Context(), err)
+		}
+		if err := a.sendOne(ss, msg, md); err != nil {
+			return err
+		}
+	}
+}
+
+func (a *Adapter) sendOne(ss grpc.ServerStream, msg map[string]any, md protoreflect.MethodDescriptor) error {
+	out := dynamicpb.NewMessage(md.Output())
+	if err := a.names.FromMap(msg, out); err != nil {
+		return status.Errorf(codes.Internal, "encode response: %v", err)
+	}
+	return ss.SendMsg(out)
+}
+
+func (a *Adapter) newRequest(ctx context.Context, svc schema.Service, method schema.Method) *protokol.Request {
+	req := &protokol.Request{
+		Service:  svc.Name,
+		Method:   method.Name,
+		Input:    make(map[string]any),
+		Metadata: make(map[string][]string),
+	}
+
+	// gRPC lower-cases metadata keys; canonicalize them so middleware can
+	// look up headers the same way it does for HTTP requests.
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, v := range md {
+			key := http.CanonicalHeaderKey(k)
+			req.Metadata[key] = append(req.Metadata[key], v...)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		req.RemoteAddr = p.Addr.String()
+	}
+	return req
+}
+
+func sendHeader(ctx context.Context, md map[string][]string) error {
+	if len(md) == 0 {
+		return nil
+	}
+	header := metadata.MD{}
+	for k, v := range md {
+		header.Append(k, v...)
+	}
+	return grpc.SetHeader(ctx, header)
+}
+
+// toStatus converts an error into a gRPC status error, as converted by
+// Config.PublicError. gRPC status errors from backends are passed through
+// unchanged.
+func (a *Adapter) toStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	var perr *protokol.Error
+	if _, ok := status.FromError(err); ok && !errors.As(err, &perr) {
+		return err
+	}
+	e := a.config.PublicError(ctx, err)
+	var verr *transform.ValidationError
+	if errors.As(e, &verr) {
+		br := &protokol.BadRequest{}
+		for _, v := range verr.Violations {
+			br.FieldViolations = append(br.FieldViolations, protokol.FieldViolation{
+				Field:       v.Field,
+				Description: v.Message,
+			})
+		}
+		e = e.WithDetails(br)
+	}
+	return grpcstatus.ToStatus(e).Err()
+}
+
+// fullServiceName returns the fully-qualified proto name of a service.
+func fullServiceName(svc schema.Service) protoreflect.FullName {
+	if svc.Package == "" {
+		return protoreflect.FullName(svc.Name)
+	}
+	return protoreflect.FullName(svc.Package + "." + svc.Name)
+}
@@ -0,0 +1,316 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/backend"
+	"github.com/jekabolt/protokol/schema"
+)
+
+// testServer serves s through the adapter, with the backend registered as
+// "test", and returns the adapter and a client connection to it.
+func testServer(t *testing.T, s *schema.Schema, b protokol.Backend) (*Adapter, *grpc.ClientConn) {
+	t.Helper()
+	reg := protokol.NewBackendRegistry()
+	reg.Register("test", b)
+	a := New(Config{Config: adapters.Config{
+		Schema:      s,
+		Backends:    reg,
+		ErrorLogger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(ctx, lis) }()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return a, conn
+}
+
+// newMessage returns an empty dynamic message of the named type.
+func newMessage(t *testing.T, a *Adapter, name string) *dynamicpb.Message {
+	t.Helper()
+	d, err := a.Files().FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor))
+}
+
+// set assigns fields of m by name.
+func set(m *dynamicpb.Message, fields map[string]protoreflect.Value) {
+	for name, v := range fields {
+		m.Set(m.Descriptor().Fields().ByName(protoreflect.Name(name)), v)
+	}
+}
+
+// recorder is a backend handler recording the inputs it receives.
+type recorder struct {
+	mu     sync.Mutex
+	inputs []map[string]any
+}
+
+func (r *recorder) record(ctx context.Context, input map[string]any) (map[string]any, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.inputs = append(r.inputs, input)
+	return map[string]any{}, nil
+}
+
+func (r *recorder) last() map[string]any {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.inputs) == 0 {
+		return nil
+	}
+	return r.inputs[len(r.inputs)-1]
+}
+
+func TestUnaryInput(t *testing.T) {
+	statusEnum := schema.Enum("Status").Value("ACTIVE", 1).Value("INACTIVE", 2).Build()
+	// UserState shares ACTIVE with Status, so its values are prefixed in
+	// the descriptors.
+	state := schema.Enum("UserState").Value("ACTIVE", 1).Value("BANNED", 2).Build()
+	input := schema.Message("FindRequest").
+		RequiredField("name", schema.String).
+		Field("count", schema.Int32).
+		FieldWithDefault("limit", schema.Int32, 10).
+		Field("enabled", schema.Bool).
+		Field("status", statusEnum).
+		Field("state", state).
+		Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Package("test.v1").Backend("test").
+		Method(schema.Unary("Find").Input(input).Output(schema.Message("FindResponse").Build()).Build()).
+		MustBuild())
+
+	rec := &recorder{}
+	h := backend.NewHandler()
+	h.Register("Users", "Find", rec.record)
+	a, conn := testServer(t, s, h)
+
+	tests := []struct {
+		name   string
+		fields map[string]protoreflect.Value
+		want   string
+		code   codes.Code
+	}{
+		{
+			name:   "omitted fields are missing or defaulted",
+			fields: map[string]protoreflect.Value{"name": protoreflect.ValueOfString("ada")},
+			want:   "map[limit:10 name:ada]",
+		},
+		{
+			name: "zero values are sent",
+			fields: map[string]protoreflect.Value{
+				"name":    protoreflect.ValueOfString(""),
+				"count":   protoreflect.ValueOfInt32(0),
+				"enabled": protoreflect.ValueOfBool(false),
+				"limit":   protoreflect.ValueOfInt32(0),
+			},
+			want: "map[count:0 enabled:false limit:0 name:]",
+		},
+		{
+			name: "enums by schema name",
+			fields: map[string]protoreflect.Value{
+				"name":   protoreflect.ValueOfString("ada"),
+				"status": protoreflect.ValueOfEnum(2),
+				"state":  protoreflect.ValueOfEnum(1),
+			},
+			want: "map[limit:10 name:ada state:ACTIVE status:INACTIVE]",
+		},
+		{
+			name: "unknown enum number",
+			fields: map[string]protoreflect.Value{
+				"name":   protoreflect.ValueOfString("ada"),
+				"status": protoreflect.ValueOfEnum(0),
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name:   "missing required field",
+			fields: map[string]protoreflect.Value{"count": protoreflect.ValueOfInt32(3)},
+			code:   codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newMessage(t, a, "test.v1.FindRequest")
+			set(req, tt.fields)
+			resp := newMessage(t, a, "test.v1.FindResponse")
+			err := conn.Invoke(context.Background(), "/test.v1.Users/Find", req, resp)
+			if got := status.Code(err); got != tt.code {
+				t.Fatalf("code = %v, want %v (%v)", got, tt.code, err)
+			}
+			if tt.code != codes.OK {
+				return
+			}
+			if got := fmt.Sprint(rec.last()); got != tt.want {
+				t.Errorf("input = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreams(t *testing.T) {
+	msg := schema.Message("Item").RequiredField("name", schema.String).Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Items").Package("test.v1").Backend("test").
+		Method(schema.ServerStream("List").Input(msg).Output(msg).Build()).
+		Method(schema.ClientStream("Upload").Input(msg).Output(msg).Build()).
+		Method(schema.Bidirectional("Echo").Input(msg).Output(msg).Build()).
+		MustBuild())
+
+	h := backend.NewHandler()
+	h.RegisterServerStream("Items", "List", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		for _, name := range []string{"a", "b"} {
+			if err := send(map[string]any{"name": name}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	h.RegisterClientStream("Items", "Upload", func(ctx context.Context, recv func() (map[string]any, error)) (map[string]any, error) {
+		var names []string
+		for {
+			msg, err := recv()
+			if err == io.EOF {
+				return map[string]any{"name": fmt.Sprint(names)}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, msg["name"].(string))
+		}
+	})
+	h.RegisterBidirectional("Items", "Echo", func(ctx context.Context, stream protokol.Stream) error {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	})
+	a, conn := testServer(t, s, h)
+
+	tests := []struct {
+		name   string
+		method string
+		desc   grpc.StreamDesc
+		send   []string
+		want   []string
+		code   codes.Code
+	}{
+		{
+			name:   "server stream ends with the backend",
+			method: "List",
+			desc:   grpc.StreamDesc{ServerStreams: true},
+			send:   []string{"x"},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "client stream ends with one response",
+			method: "Upload",
+			desc:   grpc.StreamDesc{ClientStreams: true},
+			send:   []string{"a", "b"},
+			want:   []string{"[a b]"},
+		},
+		{
+			name:   "invalid client stream message",
+			method: "Upload",
+			desc:   grpc.StreamDesc{ClientStreams: true},
+			send:   []string{"a", ""},
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "bidirectional stream ends after the client half-closes",
+			method: "Echo",
+			desc:   grpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+			send:   []string{"a", "b"},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "invalid bidirectional message fails the call",
+			method: "Echo",
+			desc:   grpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+			send:   []string{"a", ""},
+			code:   codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			cs, err := conn.NewStream(ctx, &tt.desc, "/test.v1.Items/"+tt.method)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range tt.send {
+				m := newMessage(t, a, "test.v1.Item")
+				if name != "" {
+					set(m, map[string]protoreflect.Value{"name": protoreflect.ValueOfString(name)})
+				}
+				if err := cs.SendMsg(m); err != nil {
+					break
+				}
+			}
+			if err := cs.CloseSend(); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for {
+				m := newMessage(t, a, "test.v1.Item")
+				err = cs.RecvMsg(m)
+				if err != nil {
+					break
+				}
+				got = append(got, m.Get(m.Descriptor().Fields().ByName("name")).String())
+			}
+			if err == io.EOF {
+				err = nil
+			}
+			if code := status.Code(err); code != tt.code {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.code, err)
+			}
+			// Messages sent before a failure may or may not arrive.
+			if tt.code == codes.OK && fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("received %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Package jsonrpc exposes the schema as a JSON-RPC 2.0 API. Every method is
+// available as "Service.Method" over HTTP and over a newline-delimited
+// TCP or Unix socket.
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/schema"
+)
+
+const (
+	defaultPath           = "/rpc"
+	defaultMaxMessageSize = 1 << 20
+	defaultMaxConcurrency = 32
+)
+
+// Config for JSON-RPC adapter.
+type Config struct {
+	adapters.Config
+	// Listen is the HTTP address. The HTTP endpoint is disabled if empty.
+	Listen string
+	// Path is the HTTP path accepting requests. Defaults to "/rpc".
+	Path string
+	// SocketNetwork is "tcp" or "unix". Defaults to "tcp".
+	SocketNetwork string
+	// SocketAddress is the stream socket address. The socket endpoint is
+	// disabled if empty.
+	SocketAddress string
+	// MaxMessageSize is the largest request accepted, in bytes.
+	MaxMessageSize int
+	// MaxConcurrency limits the requests executed at once for one HTTP
+	// request or socket connection, including the elements of batches.
+	// Defaults to 32.
+	MaxConcurrency int
+}
+
+// Adapter implements JSON-RPC 2.0.
+type Adapter struct {
+	config  Config
+	methods map[string]methodEntry
+
+	server   *http.Server
+	listener net.Listener
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+	wg    sync.WaitGroup
+}
+
+// methodEntry is a resolved service method with its prepared handler chain.
+type methodEntry struct {
+	svc    schema.Service
+	method schema.Method
+	// input is the method's input with references resolved.
+	input   schema.Type
+	handler adapters.Handler
+}
+
+func New(cfg Config) *Adapter {
+	if cfg.Schema == nil {
+		panic("jsonrpc: schema is required")
+	}
+	if cfg.Backends == nil {
+		panic("jsonrpc: backends registry is required")
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	if cfg.SocketNetwork == "" {
+		cfg.SocketNetwork = "tcp"
+	}
+	if cfg.MaxMessageSize == 0 {
+		cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = defaultMaxConcurrency
+	}
+
+	a := &Adapter{
+		config:  cfg,
+		methods: make(map[string]methodEntry),
+		conns:   make(map[net.Conn]struct{}),
+	}
+	for _, svc := range cfg.Schema.Services {
+		for _, method := range svc.Methods {
+			input, _ := cfg.Schema.Resolve(method.Input)
+			a.methods[svc.Name+"."+method.Name] = methodEntry{
+				svc:     svc,
+				method:  method,
+				input:   input,
+				handler: cfg.Handler(svc, method),
+			}
+		}
+	}
+	return a
+}
+
+func (a *Adapter) Name() string {
+	return "jsonrpc"
+}
+
+// Start serves the HTTP and socket endpoints that are configured until the
+// context is cancelled or either endpoint fails.
+func (a *Adapter) Start(ctx context.Context) error {
+	errCh := make(chan error, 2)
+
+	if a.config.Listen != "" {
+		mux := http.NewServeMux()
+		mux.Handle(a.config.Path, a)
+		a.server = &http.Server{
+			Addr:    a.config.Listen,
+			Handler: mux,
+		}
+		go func() {
+			if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				errCh <- err
+			}
+		}()
+	}
+
+	if a.config.SocketAddress != "" {
+		lis, err := net.Listen(a.config.SocketNetwork, a.config.SocketAddress)
+		if err != nil {
+			a.Stop(context.Background())
+			return err
+		}
+		go func() {
+			if err := a.Serve(lis); err != nil {
+				errCh <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-errCh:
+		a.Stop(context.Background())
+		return err
+	case <-ctx.Done():
+		return a.Stop(context.Background())
+	}
+}
+
+// Stop shuts down the HTTP server, closes the socket listener and all socket
+// connections, then waits for in-flight socket requests to finish.
+func (a *Adapter) Stop(ctx context.Context) error {
+	var firstErr error
+	if a.server != nil {
+		firstErr = a.server.Shutdown(ctx)
+	}
+
+	a.mu.Lock()
+	if a.listener != nil {
+		a.listener.Close()
+	}
+	for c := range a.conns {
+		c.Close()
+	}
+	a.mu.Unlock()
+
+	a.wg.Wait()
+	return firstErr
+}
+
+// ServeHTTP handles JSON-RPC requests sent as HTTP POST bodies. Responses
+// consisting only of notifications return 204 No Content, and bodies over
+// MaxMessageSize are answered with 413 Request Entity Too Large.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(a.config.MaxMessageSize)))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write(encode(errRequestTooLarge))
+			return
+		}
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	resp := a.handlePayload(r.Context(), body, transport{
+		metadata:   r.Header,
+		remoteAddr: r.RemoteAddr,
+	}, a.newLimit())
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+}
+
+// Serve accepts newline-delimited JSON-RPC connections on lis. Each line is
+// one request or batch; responses are written as lines in completion order.
+// At most MaxConcurrency lines are handled at once per connection, after
+// which reading pauses. A line over MaxMessageSize is answered with an error
+// and closes the connection.
+func (a *Adapter) Serve(lis net.Listener) error {
+	a.mu.Lock()
+	a.listener = lis
+	a.mu.Unlock()
+
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		a.mu.Lock()
+		a.conns[conn] = struct{}{}
+		a.wg.Add(1)
+		a.mu.Unlock()
+
+		go a.serveConn(conn)
+	}
+}
+
+func (a *Adapter) serveConn(conn net.Conn) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var reqs sync.WaitGroup
+	// Requests already read are answered even if the client half-closes.
+	defer func() {
+		reqs.Wait()
+		cancel()
+		conn.Close()
+		a.mu.Lock()
+		delete(a.conns, conn)
+		a.mu.Unlock()
+		a.wg.Done()
+	}()
+
+	t := transport{remoteAddr: conn.RemoteAddr().String()}
+	var writeMu sync.Mutex
+	limit := a.newLimit()
+	lines := a.newLimit()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, 4096), a.config.MaxMessageSize)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		if len(line) == 0 {
+			continue
+		}
+
+		lines <- struct{}{}
+		reqs.Add(1)
+		go func() {
+			defer reqs.Done()
+			defer func() { <-lines }()
+			resp := a.handlePayload(ctx, line, t, limit)
+			if resp == nil {
+				return
+			}
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			conn.Write(append(resp, '\n'))
+		}()
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		reqs.Wait()
+		conn.Write(append(encode(errRequestTooLarge), '\n'))
+	}
+}
+
+// newLimit returns a semaphore admitting MaxConcurrency holders.
+func (a *Adapter) newLimit() chan struct{} {
+	return make(chan struct{}, a.config.MaxConcurrency)
+}
@@ -0,0 +1,228 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/backend"
+	"github.com/jekabolt/protokol/schema"
+)
+
+func testAdapter(t *testing.T) *Adapter {
+	t.Helper()
+	user := schema.Message("User").
+		RequiredField("id", schema.Int64).
+		Field("name", schema.String).
+		Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Backend("test").
+		Method(schema.Unary("GetUser").Input(user).Output(user).Build()).
+		Method(schema.ServerStream("WatchUsers").Input(user).Output(user).Build()).
+		MustBuild())
+
+	h := backend.NewHandler()
+	h.Register("Users", "GetUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		switch input["name"] {
+		case "missing":
+			return nil, protokol.NewError(protokol.CodeNotFound, "user not found")
+		case "rpc":
+			return nil, &Error{Code: -32050, Message: "custom"}
+		}
+		return map[string]any{"id": input["id"], "name": "ada"}, nil
+	})
+	reg := protokol.NewBackendRegistry()
+	reg.Register("test", h)
+	return New(Config{Config: adapters.Config{
+		Schema:      s,
+		Backends:    reg,
+		ErrorLogger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}})
+}
+
+func TestServeHTTP(t *testing.T) {
+	a := testAdapter(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "named params",
+			body:   `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":7},"id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","result":{"id":7,"name":"ada"},"id":1}`,
+		},
+		{
+			name:   "positional params",
+			body:   `{"jsonrpc":"2.0","method":"Users.GetUser","params":[7],"id":"a"}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","result":{"id":7,"name":"ada"},"id":"a"}`,
+		},
+		{
+			name:   "too many positional params",
+			body:   `{"jsonrpc":"2.0","method":"Users.GetUser","params":[7,"ada","x"],"id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32602,"message":"too many positional params"},"id":1}`,
+		},
+		{
+			name:   "invalid params list the violations",
+			body:   `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"name":"ada"},"id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32602,"message":"invalid argument: id: required","data":[{"field":"id","message":"required"}]},"id":1}`,
+		},
+		{
+			name:   "backend error code",
+			body:   `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":7,"name":"missing"},"id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32003,"message":"user not found"},"id":1}`,
+		},
+		{
+			name:   "JSON-RPC error from the backend",
+			body:   `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":7,"name":"rpc"},"id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32050,"message":"custom"},"id":1}`,
+		},
+		{
+			name:   "unknown method",
+			body:   `{"jsonrpc":"2.0","method":"Users.DeleteUser","id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32601,"message":"protokol: method not found"},"id":1}`,
+		},
+		{
+			name:   "streaming method",
+			body:   `{"jsonrpc":"2.0","method":"Users.WatchUsers","id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32601,"message":"protokol: streaming not supported"},"id":1}`,
+		},
+		{
+			name:   "parse error",
+			body:   `{"jsonrpc":`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32700,"message":"parse error"},"id":null}`,
+		},
+		{
+			name:   "missing version",
+			body:   `{"method":"Users.GetUser","id":1}`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32600,"message":"invalid request"},"id":1}`,
+		},
+		{
+			name:   "batch",
+			body:   `[{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":1},"id":1},{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":2}},{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":3},"id":3}]`,
+			status: http.StatusOK,
+			want:   `[{"jsonrpc":"2.0","result":{"id":1,"name":"ada"},"id":1},{"jsonrpc":"2.0","result":{"id":3,"name":"ada"},"id":3}]`,
+		},
+		{
+			name:   "empty batch",
+			body:   `[]`,
+			status: http.StatusOK,
+			want:   `{"jsonrpc":"2.0","error":{"code":-32600,"message":"empty batch"},"id":null}`,
+		},
+		{
+			name:   "notification",
+			body:   `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":7}}`,
+			status: http.StatusNoContent,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			a.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/rpc", strings.NewReader(tt.body)))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	a.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/rpc", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want 405", rec.Code)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		code protokol.Code
+		want int
+	}{
+		{protokol.CodeInvalidArgument, CodeInvalidParams},
+		{protokol.CodeOutOfRange, CodeInvalidParams},
+		{protokol.CodeUnimplemented, CodeMethodNotFound},
+		{protokol.CodeUnauthenticated, CodeUnauthorized},
+		{protokol.CodeResourceExhausted, CodeRateLimited},
+		{protokol.CodeNotFound, CodeNotFound},
+		{protokol.CodePermissionDenied, CodePermissionDenied},
+		{protokol.CodeAlreadyExists, CodeConflict},
+		{protokol.CodeAborted, CodeConflict},
+		{protokol.CodeFailedPrecondition, CodeFailedPrecondition},
+		{protokol.CodeUnavailable, CodeUnavailable},
+		{protokol.CodeDeadlineExceeded, CodeDeadlineExceeded},
+		{protokol.CodeCanceled, CodeCancelled},
+		{protokol.CodeInternal, CodeInternalError},
+		{protokol.CodeUnknown, CodeInternalError},
+	}
+	for _, tt := range tests {
+		if got := errorCode(tt.code); got != tt.want {
+			t.Errorf("errorCode(%v) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestSocket(t *testing.T) {
+	a := testAdapter(t)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(lis) }()
+	t.Cleanup(func() {
+		a.Stop(context.Background())
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":1},"id":1}`+"\n")
+	fmt.Fprint(conn, `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":2}}`+"\n")
+	fmt.Fprint(conn, `{"jsonrpc":"2.0","method":"Users.GetUser","params":{"id":3},"id":3}`+"\n")
+	conn.(*net.TCPConn).CloseWrite()
+
+	// Responses arrive in completion order, and the connection closes after
+	// the last one once the client half-closed it.
+	var got []string
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		got = append(got, scanner.Text())
+	}
+	sort.Strings(got)
+	want := []string{
+		`{"jsonrpc":"2.0","result":{"id":1,"name":"ada"},"id":1}`,
+		`{"jsonrpc":"2.0","result":{"id":3,"name":"ada"},"id":3}`,
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("responses = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,305 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/transform"
+)
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Implementation-defined server error codes (-32000 to -32099) for
+// canonical codes without a standard equivalent.
+const (
+	CodeUnauthorized       = -32001 // protokol.CodeUnauthenticated
+	CodeRateLimited        = -32002 // protokol.CodeResourceExhausted
+	CodeNotFound           = -32003 // protokol.CodeNotFound
+	CodePermissionDenied   = -32004 // protokol.CodePermissionDenied
+	CodeConflict           = -32005 // protokol.CodeAlreadyExists, protokol.CodeAborted
+	CodeFailedPrecondition = -32006 // protokol.CodeFailedPrecondition
+	CodeUnavailable        = -32007 // protokol.CodeUnavailable
+	CodeDeadlineExceeded   = -32008 // protokol.CodeDeadlineExceeded
+	CodeCancelled          = -32009 // protokol.CodeCanceled
+)
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// request is a single JSON-RPC request or notification. A missing id marks a
+// notification; an explicit null id is a request that expects a response.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	ID      json.RawMessage `json:"id"`
+}
+
+func (r *request) isNotification() bool {
+	return len(r.ID) == 0
+}
+
+// response is a JSON-RPC response. Result is pre-encoded so that an empty
+// object is still sent while omitempty drops it from error responses.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+var nullID = json.RawMessage("null")
+
+// errRequestTooLarge answers requests over Config.MaxMessageSize.
+var errRequestTooLarge = errorResponse(nullID, &Error{Code: CodeInvalidRequest, Message: "request too large"})
+
+// transport carries connection details into each protokol.Request.
+type transport struct {
+	metadata   map[string][]string
+	remoteAddr string
+}
+
+// handlePayload processes a single request or a batch and returns the encoded
+// response, or nil if nothing should be sent back (only notifications).
+// Each request holds a slot of limit while it runs, so batch elements run
+// concurrently up to its capacity.
+func (a *Adapter) handlePayload(ctx context.Context, payload []byte, t transport, limit chan struct{}) []byte {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 {
+		return encode(errorResponse(nullID, &Error{Code: CodeInvalidRequest, Message: "empty request"}))
+	}
+
+	if payload[0] != '[' {
+		limit <- struct{}{}
+		resp := a.handleRaw(ctx, payload, t)
+		<-limit
+		if resp == nil {
+			return nil
+		}
+		return encode(resp)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(payload, &batch); err != nil {
+		return encode(errorResponse(nullID, &Error{Code: CodeParseError, Message: "parse error"}))
+	}
+	if len(batch) == 0 {
+		return encode(errorResponse(nullID, &Error{Code: CodeInvalidRequest, Message: "empty batch"}))
+	}
+
+	results := make([]*response, len(batch))
+	var wg sync.WaitGroup
+	for i, raw := range batch {
+		limit <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-limit }()
+			results[i] = a.handleRaw(ctx, raw, t)
+		}()
+	}
+	wg.Wait()
+
+	out := make([]*response, 0, len(results))
+	for _, r := range results {
+		if r != nil {
+			out = append(out, r)
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return encode(out)
+}
+
+// handleRaw decodes and executes one request. It returns nil for
+// notifications.
+func (a *Adapter) handleRaw(ctx context.Context, raw json.RawMessage, t transport) *response {
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return errorResponse(nullID, &Error{Code: CodeParseError, Message: "parse error"})
+		}
+		return errorResponse(nullID, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		id := req.ID
+		if len(id) == 0 {
+			id = nullID
+		}
+		return errorResponse(id, &Error{Code: CodeInvalidRequest, Message: "invalid request"})
+	}
+
+	result, rpcErr := a.call(ctx, &req, t)
+	if req.isNotification() {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr)
+	}
+	if result == nil {
+		result = map[string]any{}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(req.ID, &Error{Code: CodeInternalError, Message: "encode result"})
+	}
+	return &response{JSONRPC: "2.0", Result: data, ID: req.ID}
+}
+
+func (a *Adapter) call(ctx context.Context, r *request, t transport) (map[string]any, *Error) {
+	entry, ok := a.methods[r.Method]
+	if !ok {
+		return nil, &Error{Code: CodeMethodNotFound, Message: protokol.ErrMethodNotFound.Error()}
+	}
+	if entry.method.IsStreaming() {
+		return nil, &Error{Code: CodeMethodNotFound, Message: protokol.ErrStreamingNotSupported.Error()}
+	}
+
+	input, err := entry.params(r.Params)
+	if err != nil {
+		return nil, &Error{Code: CodeInvalidParams, Message: err.Error()}
+	}
+
+	req := &protokol.Request{
+		Service:    entry.svc.Name,
+		Method:     entry.method.Name,
+		Input:      input,
+		Metadata:   make(map[string][]string, len(t.metadata)),
+		RemoteAddr: t.remoteAddr,
+	}
+	for k, v := range t.metadata {
+		req.Metadata[k] = v
+	}
+
+	resp, err := entry.handler.Handle(ctx, req)
+	if err != nil {
+		return nil, a.toError(ctx, err)
+	}
+	return resp.Output, nil
+}
+
+// params decodes by-name params into the request input. By-position params
+// are assigned to the input message's fields in declaration order.
+func (e methodEntry) params(raw json.RawMessage) (map[string]any, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, nullID) {
+		return make(map[string]any), nil
+	}
+
+	switch raw[0] {
+	case '{':
+		var input map[string]any
+		if err := json.Unmarshal(raw, &input); err != nil {
+			return nil, errors.New("invalid params")
+		}
+		return input, nil
+	case '[':
+		var values []any
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, errors.New("invalid params")
+		}
+		fields := e.input.Fields
+		if len(values) > len(fields) {
+			return nil, errors.New("too many positional params")
+		}
+		input := make(map[string]any, len(values))
+		for i, v := range values {
+			input[fields[i].Name] = v
+		}
+		return input, nil
+	default:
+		return nil, errors.New("params must be an object or array")
+	}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *response {
+	return &response{JSONRPC: "2.0", Error: err, ID: id}
+}
+
+// toError converts err, as converted by Config.PublicError, to a JSON-RPC
+// error. Failed input validation lists the invalid fields in data; other
+// errors carry their details there. An *Error in the chain is sent as it is
+// unless the ErrorMapper maps err, or its code is CodeInternalError, which
+// is hidden in production mode like other internal errors.
+func (a *Adapter) toError(ctx context.Context, err error) *Error {
+	cfg := a.config.Config
+	if cfg.ErrorMapper != nil {
+		if e := cfg.ErrorMapper(ctx, err); e != nil {
+			return a.fromPublic(e)
+		}
+		cfg.ErrorMapper = nil
+	}
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) && (rpcErr.Code != CodeInternalError || cfg.DebugErrors) {
+		return rpcErr
+	}
+	return a.fromPublic(cfg.PublicError(ctx, err))
+}
+
+// fromPublic converts an error to report to the client to a JSON-RPC error.
+func (a *Adapter) fromPublic(e *protokol.Error) *Error {
+	var verr *transform.ValidationError
+	if errors.As(e, &verr) {
+		return &Error{Code: CodeInvalidParams, Message: e.Error(), Data: verr.Violations}
+	}
+	out := &Error{Code: errorCode(e.Code), Message: e.Error()}
+	if len(e.Details) > 0 {
+		out.Data = e.Details
+	}
+	return out
+}
+
+func errorCode(code protokol.Code) int {
+	switch code {
+	case protokol.CodeUnimplemented:
+		return CodeMethodNotFound
+	case protokol.CodeInvalidArgument, protokol.CodeOutOfRange:
+		return CodeInvalidParams
+	case protokol.CodeUnauthenticated:
+		return CodeUnauthorized
+	case protokol.CodeResourceExhausted:
+		return CodeRateLimited
+	case protokol.CodeNotFound:
+		return CodeNotFound
+	case protokol.CodePermissionDenied:
+		return CodePermissionDenied
+	case protokol.CodeAlreadyExists, protokol.CodeAborted:
+		return CodeConflict
+	case protokol.CodeFailedPrecondition:
+		return CodeFailedPrecondition
+	case protokol.CodeUnavailable:
+		return CodeUnavailable
+	case protokol.CodeDeadlineExceeded:
+		return CodeDeadlineExceeded
+	case protokol.CodeCanceled:
+		return CodeCancelled
+	default:
+		return CodeInternalError
+	}
+}
+
+func encode(v any) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
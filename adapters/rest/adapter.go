@@ -12,16 +12,19 @@ import (
 
 	"github.com/jekabolt/protokol"
 	"github.com/jekabolt/protokol/adapters"
-	"github.com/jekabolt/protokol/middleware/auth"
-	"github.com/jekabolt/protokol/middleware/ratelimit"
 	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/transform"
 )
 
+var errInvalidJSON error = protokol.NewError(protokol.CodeInvalidArgument, "invalid JSON body")
+
 // Config for REST adapter.
 type Config struct {
 	adapters.Config
 	Listen     string
 	PathPrefix string
+	// OpenAPI describes the generated OpenAPI document and where to serve it.
+	OpenAPI OpenAPIConfig
 }
 
 // Adapter implements REST/HTTP protocol.
@@ -53,6 +56,9 @@ func New(cfg Config) *Adapter {
 		},
 	}
 	a.buildRoutes()
+	if cfg.OpenAPI.Path != "" {
+		a.router.Get(cfg.OpenAPI.Path, a.serveOpenAPI)
+	}
 	return a
 }
 
@@ -119,7 +125,10 @@ func (a *Adapter) registerMethod(prefix string, svc schema.Service, method schem
 	case http.MethodPatch:
 		a.router.Patch(path, handler)
 	default:
-		a.router.Post(path, handler)
+		// Custom verbs, e.g. from google.api.http custom rules, must be
+		// known to chi before routes can use them.
+		chi.RegisterMethod(httpMethod)
+		a.router.Method(httpMethod, path, handler)
 	}
 }
 
@@ -148,17 +157,12 @@ func (a *Adapter) httpMethod(method schema.Method) string {
 }
 
 func (a *Adapter) makeHandler(svc schema.Service, method schema.Method) http.HandlerFunc {
-	// Build the handler chain: middleware -> backend call
-	var handler adapters.Handler = adapters.HandlerFunc(func(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
-		backend, ok := a.config.Backends.Get(svc.Backend)
-		if !ok {
-			return nil, protokol.ErrBackendNotFound
-		}
-		return backend.Call(ctx, req)
-	})
+	if method.Type == schema.MethodServerStream || method.Type == schema.MethodBidirectional {
+		return a.makeStreamHandler(svc, method)
+	}
 
-	// Apply middleware in reverse order
-	handler = adapters.Chain(handler, a.config.Middleware...)
+	// Build the handler chain: middleware -> backend call
+	handler := a.config.Handler(svc, method)
 
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -177,30 +181,14 @@ func (a *Adapter) makeHandler(svc schema.Service, method schema.Method) http.Han
 		req.Service = svc.Name
 		req.Method = method.Name
 
-		if r.Body != nil && r.ContentLength > 0 {
-			if err := json.NewDecoder(r.Body).Decode(&req.Input); err != nil {
-				a.writeError(w, http.StatusBadRequest, "invalid JSON body")
-				return
-			}
-		}
-
-		a.extractPathParams(r, req)
-
-		if r.Method == http.MethodGet {
-			a.extractQueryParams(r, req)
-		}
-
-		for k, v := range r.Header {
-			req.Metadata[k] = v
+		if err := a.decodeRequest(r, method, req); err != nil {
+			a.writeCallError(ctx, w, err)
+			return
 		}
 
-		// Set remote address from connection
-		req.RemoteAddr = r.RemoteAddr
-
 		resp, err := handler.Handle(ctx, req)
 		if err != nil {
-			status := a.errorStatus(err)
-			a.writeError(w, status, err.Error())
+			a.writeCallError(ctx, w, err)
 			return
 		}
 
@@ -209,40 +197,49 @@ func (a *Adapter) makeHandler(svc schema.Service, method schema.Method) http.Han
 	}
 }
 
-func (a *Adapter) errorStatus(err error) int {
-	switch {
-	case errors.Is(err, protokol.ErrBackendNotFound):
-		return http.StatusInternalServerError
-	case errors.Is(err, auth.ErrUnauthorized),
-		errors.Is(err, auth.ErrInvalidToken),
-		errors.Is(err, auth.ErrMissingToken):
-		return http.StatusUnauthorized
-	case errors.Is(err, ratelimit.ErrRateLimited):
-		return http.StatusTooManyRequests
-	default:
-		return http.StatusInternalServerError
+// decodeRequest fills req from the JSON body, path and query parameters,
+// and headers of r. Parameters are converted to the types of the input
+// fields they name.
+func (a *Adapter) decodeRequest(r *http.Request, method schema.Method, req *protokol.Request) error {
+	if r.Body != nil && r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req.Input); err != nil {
+			return errInvalidJSON
+		}
 	}
+
+	params := make(map[string][]string)
+	a.extractPathParams(r, params)
+	if r.Method == http.MethodGet {
+		a.extractQueryParams(r, params)
+	}
+	if err := transform.Coerce(a.config.Schema, method.Input, req.Input, params); err != nil {
+		return err
+	}
+
+	for k, v := range r.Header {
+		req.Metadata[k] = v
+	}
+
+	// Set remote address from connection
+	req.RemoteAddr = r.RemoteAddr
+	return nil
 }
 
-func (a *Adapter) extractPathParams(r *http.Request, req *protokol.Request) {
+func (a *Adapter) extractPathParams(r *http.Request, params map[string][]string) {
 	rctx := chi.RouteContext(r.Context())
 	if rctx == nil {
 		return
 	}
 	for i, key := range rctx.URLParams.Keys {
 		if i < len(rctx.URLParams.Values) {
-			req.Input[key] = rctx.URLParams.Values[i]
+			params[key] = []string{rctx.URLParams.Values[i]}
 		}
 	}
 }
 
-func (a *Adapter) extractQueryParams(r *http.Request, req *protokol.Request) {
+func (a *Adapter) extractQueryParams(r *http.Request, params map[string][]string) {
 	for k, v := range r.URL.Query() {
-		if len(v) == 1 {
-			req.Input[k] = v[0]
-		} else {
-			req.Input[k] = v
-		}
+		params[k] = v
 	}
 }
 
@@ -252,6 +249,29 @@ func (a *Adapter) writeError(w http.ResponseWriter, status int, msg string) {
 	json.NewEncoder(w).Encode(map[string]string{"error": msg})
 }
 
+// writeCallError writes an error returned by the handler chain, as
+// converted by Config.PublicError, with the HTTP status for its code.
+func (a *Adapter) writeCallError(ctx context.Context, w http.ResponseWriter, err error) {
+	e := a.config.PublicError(ctx, err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Code.HTTPStatus())
+	json.NewEncoder(w).Encode(errorBody(e))
+}
+
+// errorBody returns the JSON error object for e: its message, code and
+// details, and the violations of a *transform.ValidationError it wraps.
+func errorBody(e *protokol.Error) map[string]any {
+	body := map[string]any{"error": e.Error(), "code": e.Code.String()}
+	if len(e.Details) > 0 {
+		body["details"] = e.Details
+	}
+	var verr *transform.ValidationError
+	if errors.As(e, &verr) {
+		body["violations"] = verr.Violations
+	}
+	return body
+}
+
 func hasPrefix(s string, prefixes ...string) bool {
 	for _, p := range prefixes {
 		if strings.HasPrefix(s, p) {
@@ -0,0 +1,312 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/backend"
+	"github.com/jekabolt/protokol/schema"
+)
+
+// testAdapter serves s through the adapter, with the backend registered as
+// "test".
+func testAdapter(t *testing.T, s *schema.Schema, b protokol.Backend) *httptest.Server {
+	t.Helper()
+	reg := protokol.NewBackendRegistry()
+	reg.Register("test", b)
+	a := New(Config{Config: adapters.Config{
+		Schema:      s,
+		Backends:    reg,
+		ErrorLogger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}})
+	srv := httptest.NewServer(a.Router())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func usersSchema() *schema.Schema {
+	user := schema.Message("User").
+		RequiredField("id", schema.Int64).
+		Field("name", schema.String).
+		FieldWithDefault("limit", schema.Int32, 10).
+		Field("tags", schema.Repeated(schema.String)).
+		Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Backend("test").
+		Method(schema.Unary("GetUser").Input(user).Output(user).HTTP("GET", "/users/{id}").Build()).
+		Method(schema.Unary("UpdateUser").Input(user).Output(user).HTTP("PUT", "/users/{id}").Build()).
+		Method(schema.Unary("CreateUser").Input(user).Output(user).Build()).
+		Method(schema.ServerStream("WatchUsers").Input(user).Output(user).Build()).
+		Method(schema.Bidirectional("Chat").Input(user).Output(user).Build()).
+		MustBuild())
+	return s
+}
+
+func TestRequestMapping(t *testing.T) {
+	// Inputs are pooled, so they are recorded as text.
+	var got string
+	echo := func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		got = fmt.Sprint(input)
+		return input, nil
+	}
+	h := backend.NewHandler()
+	h.Register("Users", "GetUser", echo)
+	h.Register("Users", "UpdateUser", echo)
+	h.Register("Users", "CreateUser", echo)
+	srv := testAdapter(t, usersSchema(), h)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "path and query parameters",
+			method: http.MethodGet,
+			path:   "/users/7?name=ada&tags=a&tags=b",
+			status: http.StatusOK,
+			want:   "map[id:7 limit:10 name:ada tags:[a b]]",
+		},
+		{
+			name:   "path parameter and body",
+			method: http.MethodPut,
+			path:   "/users/7",
+			body:   `{"name":"ada","limit":0}`,
+			status: http.StatusOK,
+			want:   "map[id:7 limit:0 name:ada]",
+		},
+		{
+			name:   "default route",
+			method: http.MethodPost,
+			path:   "/Users/CreateUser",
+			body:   `{"id":"3"}`,
+			status: http.StatusOK,
+			want:   "map[id:3 limit:10]",
+		},
+		{
+			name:   "invalid path parameter",
+			method: http.MethodGet,
+			path:   "/users/x",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid JSON",
+			method: http.MethodPut,
+			path:   "/users/7",
+			body:   `{`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "missing required field",
+			method: http.MethodPost,
+			path:   "/Users/CreateUser",
+			body:   `{"name":"ada"}`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = ""
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.want == "" {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("input = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		msg    string
+	}{
+		{
+			name:   "not found",
+			err:    protokol.NewError(protokol.CodeNotFound, "user not found"),
+			status: http.StatusNotFound,
+			code:   "NOT_FOUND",
+			msg:    "user not found",
+		},
+		{
+			name:   "permission denied",
+			err:    protokol.NewError(protokol.CodePermissionDenied, "no access"),
+			status: http.StatusForbidden,
+			code:   "PERMISSION_DENIED",
+			msg:    "no access",
+		},
+		{
+			name:   "internal errors are hidden",
+			err:    fmt.Errorf("db password rejected"),
+			status: http.StatusInternalServerError,
+			code:   "INTERNAL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := backend.NewHandler()
+			h.Register("Users", "GetUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
+				return nil, tt.err
+			})
+			srv := testAdapter(t, usersSchema(), h)
+
+			resp, err := http.Get(srv.URL + "/users/1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var body map[string]any
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if body["code"] != tt.code {
+				t.Errorf("code = %v, want %s", body["code"], tt.code)
+			}
+			if tt.msg != "" && body["error"] != tt.msg {
+				t.Errorf("error = %v, want %s", body["error"], tt.msg)
+			}
+			if msg, _ := body["error"].(string); strings.Contains(msg, "password") {
+				t.Errorf("internal error leaked: %s", msg)
+			}
+		})
+	}
+}
+
+func TestStreamTermination(t *testing.T) {
+	h := backend.NewHandler()
+	h.RegisterServerStream("Users", "WatchUsers", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		for i := range 2 {
+			if err := send(map[string]any{"id": int64(i)}); err != nil {
+				return err
+			}
+		}
+		if input["name"] == "fail" {
+			return protokol.NewError(protokol.CodeUnavailable, "gone")
+		}
+		return nil
+	})
+	h.RegisterBidirectional("Users", "Chat", func(ctx context.Context, stream protokol.Stream) error {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	})
+	srv := testAdapter(t, usersSchema(), h)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		want   []string
+	}{
+		{
+			name:   "server stream ends at EOF",
+			path:   "/Users/WatchUsers",
+			body:   `{"id":"1"}`,
+			status: http.StatusOK,
+			want:   []string{`{"id":0}`, `{"id":1}`},
+		},
+		{
+			name:   "server stream error is the last line",
+			path:   "/Users/WatchUsers",
+			body:   `{"id":"1","name":"fail"}`,
+			status: http.StatusOK,
+			want:   []string{`{"id":0}`, `{"id":1}`, `{"code":"UNAVAILABLE","error":"gone"}`},
+		},
+		{
+			name:   "bidirectional stream gets the body once",
+			path:   "/Users/Chat",
+			body:   `{"id":"5"}`,
+			status: http.StatusOK,
+			want:   []string{`{"id":"5","limit":10}`},
+		},
+		{
+			name:   "bidirectional stream validates the body",
+			path:   "/Users/Chat",
+			body:   `{"name":"ada"}`,
+			status: http.StatusBadRequest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			data, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(strings.TrimSpace(string(data)), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAPISkipsRequestStreams(t *testing.T) {
+	s := usersSchema()
+	a := New(Config{Config: adapters.Config{Schema: s, Backends: protokol.NewBackendRegistry()}})
+	doc := a.openAPIDocument()
+	if _, ok := doc.Paths["/Users/Chat"]; ok {
+		t.Error("bidirectional method documented")
+	}
+	if _, ok := doc.Paths["/Users/WatchUsers"]; !ok {
+		t.Error("server-streaming method not documented")
+	}
+}
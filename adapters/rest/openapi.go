@@ -0,0 +1,466 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+const (
+	defaultOpenAPITitle   = "API"
+	defaultOpenAPIVersion = "1.0.0"
+	// errorComponent is namespaced so it cannot collide with schema types.
+	errorComponent = "protokol.Error"
+)
+
+// OpenAPIConfig describes the generated OpenAPI document.
+type OpenAPIConfig struct {
+	// Path serves the document as JSON at this path. It is not served if
+	// empty. The path is not prefixed with PathPrefix.
+	Path        string
+	Title       string // Defaults to "API".
+	Version     string // Defaults to "1.0.0".
+	Description string
+	// Servers lists base URLs of the API, e.g. "https://api.example.com".
+	Servers []string
+}
+
+// OpenAPI returns the OpenAPI 3.1 document describing the adapter's routes.
+func (a *Adapter) OpenAPI() ([]byte, error) {
+	return json.MarshalIndent(a.openAPIDocument(), "", "  ")
+}
+
+// WriteOpenAPI writes the OpenAPI 3.1 document describing the adapter's
+// routes to w.
+func (a *Adapter) WriteOpenAPI(w io.Writer) error {
+	doc, err := a.OpenAPI()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(doc)
+	return err
+}
+
+// GenerateOpenAPI writes the OpenAPI 3.1 document for the routes the REST
+// adapter would serve with cfg to the file at path. No backends are needed,
+// so it can run as part of a build.
+func GenerateOpenAPI(cfg Config, path string) error {
+	if cfg.Schema == nil {
+		return errors.New("rest: schema is required")
+	}
+	a := &Adapter{config: cfg}
+	doc, err := a.OpenAPI()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(doc, '\n'), 0o644)
+}
+
+func (a *Adapter) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := a.OpenAPI()
+	if err != nil {
+		a.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers,omitempty"`
+	Tags       []openAPITag                            `json:"tags,omitempty"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPITag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags"`
+	Parameters  []openAPIParameter          `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name"`
+	In       string         `json:"in"`
+	Required bool           `json:"required,omitempty"`
+	Schema   map[string]any `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema map[string]any `json:"schema"`
+}
+
+type openAPIComponents struct {
+	Schemas map[string]map[string]any `json:"schemas"`
+}
+
+// chi path parameters may carry a regular expression, as in {id:[0-9]+}.
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+func (a *Adapter) openAPIDocument() *openAPIDocument {
+	info := a.config.OpenAPI
+	doc := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       info.Title,
+			Version:     info.Version,
+			Description: info.Description,
+		},
+		Paths: make(map[string]map[string]*openAPIOperation),
+		Components: openAPIComponents{
+			Schemas: map[string]map[string]any{errorComponent: errorSchema()},
+		},
+	}
+	if doc.Info.Title == "" {
+		doc.Info.Title = defaultOpenAPITitle
+	}
+	if doc.Info.Version == "" {
+		doc.Info.Version = defaultOpenAPIVersion
+	}
+	for _, url := range info.Servers {
+		doc.Servers = append(doc.Servers, openAPIServer{URL: url})
+	}
+
+	g := &schemaGenerator{types: a.config.Schema, schemas: doc.Components.Schemas}
+	for name, t := range a.config.Schema.Types {
+		if t.Name == "" {
+			t.Name = name
+		}
+		g.component(t)
+	}
+
+	for _, svc := range a.config.Schema.Services {
+		doc.Tags = append(doc.Tags, openAPITag{Name: svc.Name, Description: svc.Description})
+		for _, method := range svc.Methods {
+			// Client-streaming and bidirectional methods have no REST
+			// mapping for their request stream.
+			if method.IsClientStreaming() {
+				continue
+			}
+			path := a.methodPath(a.config.PathPrefix, svc, method)
+			path = pathParamPattern.ReplaceAllString(path, "{$1}")
+			if doc.Paths[path] == nil {
+				doc.Paths[path] = make(map[string]*openAPIOperation)
+			}
+			httpMethod := a.httpMethod(method)
+			doc.Paths[path][strings.ToLower(httpMethod)] = g.operation(svc, method, path, httpMethod)
+		}
+	}
+	return doc
+}
+
+// schemaGenerator converts schema types to JSON Schema, collecting named
+// messages and enums as components.
+type schemaGenerator struct {
+	types   *schema.Schema
+	schemas map[string]map[string]any
+	// expanding holds the messages whose fields are being described as
+	// query parameters, so recursive messages are expanded once.
+	expanding map[string]bool
+}
+
+// resolve follows type references. Unknown references resolve to the
+// invalid type, which has no fields and an empty schema.
+func (g *schemaGenerator) resolve(t schema.Type) schema.Type {
+	t, ok := g.types.Resolve(t)
+	if !ok {
+		return schema.Type{}
+	}
+	return t
+}
+
+func (g *schemaGenerator) operation(svc schema.Service, method schema.Method, path, httpMethod string) *openAPIOperation {
+	op := &openAPIOperation{
+		OperationID: svc.Name + "_" + method.Name,
+		Summary:     method.Name,
+		Description: method.Description,
+		Tags:        []string{svc.Name},
+		Responses:   make(map[string]*openAPIResponse),
+	}
+	input := g.resolve(method.Input)
+
+	pathParams := make(map[string]bool)
+	for _, m := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		name := m[1]
+		pathParams[name] = true
+		s := map[string]any{"type": "string"}
+		if f, ok := fieldByName(input, name); ok {
+			s = g.schema(f.Type)
+		}
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   s,
+		})
+	}
+
+	if httpMethod == http.MethodGet {
+		g.queryParams(op, input, "", pathParams)
+	} else {
+		var body map[string]any
+		if len(pathParams) > 0 {
+			body = g.object(input, pathParams)
+		} else {
+			body = g.schema(method.Input)
+		}
+		op.RequestBody = &openAPIRequestBody{
+			Required: hasRequired(input, pathParams),
+			Content:  map[string]openAPIMediaType{"application/json": {Schema: body}},
+		}
+	}
+
+	output := g.schema(method.Output)
+	if method.IsServerStreaming() {
+		op.Responses["200"] = &openAPIResponse{
+			Description: "A stream of messages, one per Server-Sent Event or NDJSON line.",
+			Content: map[string]openAPIMediaType{
+				contentTypeSSE:    {Schema: output},
+				contentTypeNDJSON: {Schema: output},
+			},
+		}
+	} else {
+		op.Responses["200"] = &openAPIResponse{
+			Description: "Successful response.",
+			Content:     map[string]openAPIMediaType{"application/json": {Schema: output}},
+		}
+	}
+	errRef := map[string]any{"$ref": "#/components/schemas/" + errorComponent}
+	op.Responses["400"] = &openAPIResponse{
+		Description: "Invalid request.",
+		Content:     map[string]openAPIMediaType{"application/json": {Schema: errRef}},
+	}
+	op.Responses["default"] = &openAPIResponse{
+		Description: "Error response.",
+		Content:     map[string]openAPIMediaType{"application/json": {Schema: errRef}},
+	}
+	return op
+}
+
+// queryParams describes the input fields of GET methods as query parameters.
+// Nested message fields use dotted names, matching how the adapter decodes
+// them.
+func (g *schemaGenerator) queryParams(op *openAPIOperation, t schema.Type, prefix string, skip map[string]bool) {
+	if t.Name != "" {
+		if g.expanding[t.Name] {
+			return
+		}
+		if g.expanding == nil {
+			g.expanding = make(map[string]bool)
+		}
+		g.expanding[t.Name] = true
+		defer delete(g.expanding, t.Name)
+	}
+	for _, f := range t.Fields {
+		name := prefix + f.Name
+		if skip[name] {
+			continue
+		}
+		switch ft := g.resolve(f.Type); ft.Kind {
+		case schema.KindMessage:
+			g.queryParams(op, ft, name+".", skip)
+		case schema.KindMap:
+			// Map entries use arbitrary dotted keys, which OpenAPI cannot
+			// describe as named parameters.
+		default:
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name:     name,
+				In:       "query",
+				Required: f.Required,
+				Schema:   g.field(f),
+			})
+		}
+	}
+}
+
+// schema returns the JSON Schema for t, referencing a component for named
+// messages and enums.
+func (g *schemaGenerator) schema(t schema.Type) map[string]any {
+	switch t.Kind {
+	case schema.KindBool:
+		return map[string]any{"type": "boolean"}
+	case schema.KindInt32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case schema.KindInt64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case schema.KindFloat32:
+		return map[string]any{"type": "number", "format": "float"}
+	case schema.KindFloat64:
+		return map[string]any{"type": "number", "format": "double"}
+	case schema.KindString:
+		return map[string]any{"type": "string"}
+	case schema.KindBytes:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case schema.KindRepeated:
+		s := map[string]any{"type": "array"}
+		if t.Elem != nil {
+			s["items"] = g.schema(*t.Elem)
+		}
+		return s
+	case schema.KindMap:
+		s := map[string]any{"type": "object"}
+		if t.Elem != nil {
+			s["additionalProperties"] = g.schema(*t.Elem)
+		}
+		return s
+	case schema.KindMessage, schema.KindEnum:
+		if t.Name == "" {
+			return g.inline(t)
+		}
+		g.component(t)
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name}
+	case schema.KindRef:
+		if r := g.resolve(t); r.Kind != schema.KindInvalid {
+			return g.schema(r)
+		}
+		return map[string]any{}
+	default:
+		return map[string]any{}
+	}
+}
+
+// component registers a named message or enum. The first definition of a
+// name wins.
+func (g *schemaGenerator) component(t schema.Type) {
+	if t.Kind != schema.KindMessage && t.Kind != schema.KindEnum {
+		return
+	}
+	if _, ok := g.schemas[t.Name]; ok {
+		return
+	}
+	// Reserve the name before descending so recursive types terminate.
+	g.schemas[t.Name] = map[string]any{}
+	g.schemas[t.Name] = g.inline(t)
+}
+
+func (g *schemaGenerator) inline(t schema.Type) map[string]any {
+	if t.Kind == schema.KindEnum {
+		names := make([]any, len(t.Values))
+		for i, v := range t.Values {
+			names[i] = v.Name
+		}
+		return map[string]any{"type": "string", "enum": names}
+	}
+	return g.object(t, nil)
+}
+
+// object returns the JSON Schema object for message type t without the
+// fields named in skip.
+func (g *schemaGenerator) object(t schema.Type, skip map[string]bool) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	for _, f := range t.Fields {
+		if skip[f.Name] {
+			continue
+		}
+		props[f.Name] = g.field(f)
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *schemaGenerator) field(f schema.Field) map[string]any {
+	s := g.schema(f.Type)
+	if f.Default == nil && f.Description == "" {
+		return s
+	}
+	// Copy so that defaults never leak into a shared $ref object.
+	withAnnotations := make(map[string]any, len(s)+2)
+	for k, v := range s {
+		withAnnotations[k] = v
+	}
+	if f.Default != nil {
+		withAnnotations["default"] = f.Default
+	}
+	if f.Description != "" {
+		withAnnotations["description"] = f.Description
+	}
+	return withAnnotations
+}
+
+func errorSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"error": map[string]any{"type": "string"},
+			"code":  map[string]any{"type": "string", "description": "Canonical error code, e.g. NOT_FOUND"},
+			"details": map[string]any{
+				"type":  "array",
+				"items": map[string]any{"type": "object"},
+			},
+			"violations": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"field":   map[string]any{"type": "string"},
+						"message": map[string]any{"type": "string"},
+					},
+					"required": []string{"field", "message"},
+				},
+			},
+		},
+		"required": []string{"error"},
+	}
+}
+
+func fieldByName(t schema.Type, name string) (schema.Field, bool) {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return schema.Field{}, false
+}
+
+func hasRequired(t schema.Type, skip map[string]bool) bool {
+	for _, f := range t.Fields {
+		if f.Required && !skip[f.Name] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+)
+
+func TestOpenAPIOperations(t *testing.T) {
+	a := New(Config{Config: adapters.Config{Schema: usersSchema(), Backends: protokol.NewBackendRegistry()}})
+	doc := a.openAPIDocument()
+
+	tests := []struct {
+		path      string
+		method    string
+		params    string
+		body      bool
+		responses string
+	}{
+		{"/users/{id}", "get", "[id:path name:query limit:query tags:query]", false, "[application/json]"},
+		{"/users/{id}", "put", "[id:path]", true, "[application/json]"},
+		{"/Users/CreateUser", "post", "[]", true, "[application/json]"},
+		{"/Users/WatchUsers", "post", "[]", true, "[application/x-ndjson text/event-stream]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			op := doc.Paths[tt.path][tt.method]
+			if op == nil {
+				t.Fatalf("operation not documented; paths: %v", keys(doc.Paths))
+			}
+			var params []string
+			for _, p := range op.Parameters {
+				params = append(params, p.Name+":"+p.In)
+			}
+			if got := fmt.Sprint(params); got != tt.params {
+				t.Errorf("parameters = %s, want %s", got, tt.params)
+			}
+			if got := op.RequestBody != nil; got != tt.body {
+				t.Errorf("request body = %v, want %v", got, tt.body)
+			}
+			if got := fmt.Sprint(keys(op.Responses["200"].Content)); got != tt.responses {
+				t.Errorf("response content = %s, want %s", got, tt.responses)
+			}
+		})
+	}
+}
+
+func TestOpenAPIComponents(t *testing.T) {
+	a := New(Config{Config: adapters.Config{Schema: usersSchema(), Backends: protokol.NewBackendRegistry()}})
+	data, err := a.OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		OpenAPI    string `json:"openapi"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Errorf("openapi = %q", doc.OpenAPI)
+	}
+	want := `{"properties":{"id":{"format":"int64","type":"integer"},"limit":{"default":10,"format":"int32","type":"integer"},"name":{"type":"string"},"tags":{"items":{"type":"string"},"type":"array"}},"required":["id"],"type":"object"}`
+	if got := string(compact(t, doc.Components.Schemas["User"])); got != want {
+		t.Errorf("User = %s, want %s", got, want)
+	}
+	if _, ok := doc.Components.Schemas[errorComponent]; !ok {
+		t.Error("error schema missing")
+	}
+}
+
+func keys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func compact(t *testing.T, data json.RawMessage) []byte {
+	t.Helper()
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := json.Marshal(v)
+	return out
+}
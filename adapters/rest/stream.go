@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/schema"
+)
+
+// Content types for streamed responses.
+const (
+	contentTypeSSE    = "text/event-stream"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+// makeStreamHandler serves server-streaming and bidirectional methods. Each
+// message received from the backend stream is written and flushed
+// immediately, either as a Server-Sent Event or as a line of
+// newline-delimited JSON, depending on the Accept header. NDJSON is used
+// unless the client asks for text/event-stream. A bidirectional stream is
+// sent the decoded request as its only message and then half-closed.
+func (a *Adapter) makeStreamHandler(svc schema.Service, method schema.Method) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			a.writeError(w, http.StatusInternalServerError, "streaming unsupported")
+			return
+		}
+
+		// Streams outlive a pooled request's usual lifetime, so they get
+		// their own request.
+		req := &protokol.Request{
+			Service:  svc.Name,
+			Method:   method.Name,
+			Input:    make(map[string]any),
+			Metadata: make(map[string][]string),
+		}
+		if err := a.decodeRequest(r, method, req); err != nil {
+			a.writeCallError(ctx, w, err)
+			return
+		}
+
+		stream, err := a.config.OpenStream(ctx, svc, method, req)
+		if err != nil {
+			a.writeCallError(ctx, w, err)
+			return
+		}
+		defer stream.Close()
+
+		if method.Type == schema.MethodBidirectional {
+			if err := sendOnce(stream, req.Input); err != nil {
+				a.writeCallError(ctx, w, err)
+				return
+			}
+		}
+
+		// Closing the stream on disconnect unblocks Recv on backends that
+		// do not watch the context themselves.
+		stop := context.AfterFunc(ctx, func() { stream.Close() })
+		defer stop()
+
+		sse := strings.Contains(r.Header.Get("Accept"), contentTypeSSE)
+		if sse {
+			w.Header().Set("Content-Type", contentTypeSSE)
+		} else {
+			w.Header().Set("Content-Type", contentTypeNDJSON)
+		}
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF || ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				writeStreamEvent(w, sse, "error", errorBody(a.config.PublicError(ctx, err)))
+				flusher.Flush()
+				return
+			}
+			if err := writeStreamEvent(w, sse, "message", msg); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// sendOnce sends msg as the only message of stream and half-closes it.
+func sendOnce(stream protokol.Stream, msg map[string]any) error {
+	if err := stream.Send(msg); err != nil {
+		return err
+	}
+	if cs, ok := stream.(protokol.CloseSender); ok {
+		return cs.CloseSend()
+	}
+	return nil
+}
+
+func writeStreamEvent(w io.Writer, sse bool, event string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if sse {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
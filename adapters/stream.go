@@ -0,0 +1,65 @@
+package adapters
+
+import (
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/transform"
+)
+
+// defaultsStream applies schema defaults to messages sent to and received
+// from a backend stream, and validates the messages sent.
+type defaultsStream struct {
+	protokol.Stream
+	schema   *schema.Schema
+	method   schema.Method
+	validate bool
+	output   bool
+}
+
+// defaultsCloseSender is a defaultsStream over a stream that can be
+// half-closed.
+type defaultsCloseSender struct {
+	*defaultsStream
+}
+
+func (c Config) wrapStream(method schema.Method, s protokol.Stream) protokol.Stream {
+	if !method.IsClientStreaming() && !c.OutputDefaults {
+		return s
+	}
+	ds := &defaultsStream{
+		Stream:   s,
+		schema:   c.Schema,
+		method:   method,
+		validate: !c.SkipValidation,
+		output:   c.OutputDefaults,
+	}
+	if _, ok := s.(protokol.CloseSender); ok {
+		return defaultsCloseSender{ds}
+	}
+	return ds
+}
+
+// Send applies input defaults to msg and, unless validation is skipped,
+// rejects it with the same error as an invalid unary request.
+func (s *defaultsStream) Send(msg map[string]any) error {
+	transform.ApplyDefaults(s.schema, s.method.Input, msg)
+	if s.validate {
+		if err := transform.Validate(s.schema, s.method.Input, msg); err != nil {
+			return err
+		}
+	}
+	return s.Stream.Send(msg)
+}
+
+func (s *defaultsStream) Recv() (map[string]any, error) {
+	msg, err := s.Stream.Recv()
+	if err == nil && s.output {
+		transform.ApplyDefaults(s.schema, s.method.Output, msg)
+	}
+	return msg, err
+}
+
+// CloseSend half-closes the underlying stream.
+func (s defaultsCloseSender) CloseSend() error {
+	return s.Stream.(protokol.CloseSender).CloseSend()
+}
@@ -0,0 +1,113 @@
+package adapters
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/transform"
+)
+
+// fakeStream records the messages sent on it.
+type fakeStream struct {
+	sent []map[string]any
+}
+
+func (s *fakeStream) Send(msg map[string]any) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *fakeStream) Recv() (map[string]any, error) { return nil, io.EOF }
+func (s *fakeStream) Close() error                  { return nil }
+
+// closeSendStream is a fakeStream that can be half-closed.
+type closeSendStream struct {
+	fakeStream
+	closed bool
+}
+
+func (s *closeSendStream) CloseSend() error {
+	s.closed = true
+	return nil
+}
+
+func streamSchema() (*schema.Schema, schema.Method) {
+	input := schema.Message("Chunk").
+		RequiredField("data", schema.String).
+		FieldWithDefault("size", schema.Int32, 4).
+		Build()
+	m := schema.ClientStream("Upload").Input(input).Output(input).Build()
+	return schema.NewSchema(), m
+}
+
+func TestStreamSend(t *testing.T) {
+	tests := []struct {
+		name  string
+		skip  bool
+		msg   map[string]any
+		want  string
+		valid bool
+	}{
+		{
+			name:  "defaults applied",
+			msg:   map[string]any{"data": "a"},
+			want:  "[map[data:a size:4]]",
+			valid: true,
+		},
+		{
+			name: "invalid message rejected",
+			msg:  map[string]any{"size": 1},
+			want: "[]",
+		},
+		{
+			name:  "validation skipped",
+			skip:  true,
+			msg:   map[string]any{"size": 1},
+			want:  "[map[size:1]]",
+			valid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, m := streamSchema()
+			inner := &fakeStream{}
+			stream := Config{Schema: s, SkipValidation: tt.skip}.wrapStream(m, inner)
+
+			err := stream.Send(tt.msg)
+			if tt.valid && err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if !tt.valid {
+				var verr *transform.ValidationError
+				if !errors.As(err, &verr) || !errors.Is(err, protokol.ErrInvalidArgument) {
+					t.Fatalf("Send = %v, want an invalid argument validation error", err)
+				}
+			}
+			if got := fmt.Sprint(inner.sent); got != tt.want {
+				t.Errorf("sent = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamCloseSend(t *testing.T) {
+	s, m := streamSchema()
+	c := Config{Schema: s}
+
+	if _, ok := c.wrapStream(m, &fakeStream{}).(protokol.CloseSender); ok {
+		t.Error("wrapper of a stream without CloseSend implements CloseSender")
+	}
+
+	inner := &closeSendStream{}
+	cs, ok := c.wrapStream(m, inner).(protokol.CloseSender)
+	if !ok {
+		t.Fatal("wrapper of a stream with CloseSend does not implement CloseSender")
+	}
+	if err := cs.CloseSend(); err != nil || !inner.closed {
+		t.Errorf("CloseSend = %v, closed = %v", err, inner.closed)
+	}
+}
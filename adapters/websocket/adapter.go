@@ -0,0 +1,178 @@
+// Package websocket exposes the schema over WebSocket connections. Many calls
+// are multiplexed over one connection using JSON frames correlated by id, and
+// all streaming method types are supported.
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/schema"
+)
+
+const (
+	defaultPath               = "/ws"
+	defaultPingInterval       = 30 * time.Second
+	defaultPongTimeout        = 60 * time.Second
+	defaultWriteTimeout       = 10 * time.Second
+	defaultMaxMessageSize     = 1 << 20
+	defaultMaxConcurrentCalls = 100
+	defaultSendBuffer         = 16
+)
+
+// Config for WebSocket adapter.
+type Config struct {
+	adapters.Config
+	Listen string
+	// Path is the HTTP path accepting WebSocket upgrades. Defaults to "/ws".
+	Path string
+	// PingInterval is how often the server pings idle connections.
+	PingInterval time.Duration
+	// PongTimeout closes the connection if no pong or message arrives in time.
+	PongTimeout time.Duration
+	// WriteTimeout bounds each frame write.
+	WriteTimeout time.Duration
+	// MaxMessageSize is the largest frame accepted from a client, in bytes.
+	MaxMessageSize int64
+	// MaxConcurrentCalls limits in-flight calls per connection.
+	MaxConcurrentCalls int
+	// CheckOrigin validates the Origin header. Defaults to same-origin only.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Adapter implements the WebSocket protocol.
+type Adapter struct {
+	config   Config
+	server   *http.Server
+	upgrader websocket.Upgrader
+	methods  map[string]methodEntry
+
+	mu    sync.Mutex
+	conns map[*conn]struct{}
+}
+
+// methodEntry is a resolved service method with its prepared handler chain.
+type methodEntry struct {
+	svc     schema.Service
+	method  schema.Method
+	handler adapters.Handler
+}
+
+func New(cfg Config) *Adapter {
+	if cfg.Schema == nil {
+		panic("websocket: schema is required")
+	}
+	if cfg.Backends == nil {
+		panic("websocket: backends registry is required")
+	}
+	if cfg.Path == "" {
+		cfg.Path = defaultPath
+	}
+	if cfg.PingInterval == 0 {
+		cfg.PingInterval = defaultPingInterval
+	}
+	if cfg.PongTimeout == 0 {
+		cfg.PongTimeout = defaultPongTimeout
+	}
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = defaultWriteTimeout
+	}
+	if cfg.MaxMessageSize == 0 {
+		cfg.MaxMessageSize = defaultMaxMessageSize
+	}
+	if cfg.MaxConcurrentCalls == 0 {
+		cfg.MaxConcurrentCalls = defaultMaxConcurrentCalls
+	}
+
+	a := &Adapter{
+		config:   cfg,
+		upgrader: websocket.Upgrader{CheckOrigin: cfg.CheckOrigin},
+		methods:  make(map[string]methodEntry),
+		conns:    make(map[*conn]struct{}),
+	}
+	for _, svc := range cfg.Schema.Services {
+		for _, method := range svc.Methods {
+			a.methods[svc.Name+"."+method.Name] = methodEntry{
+				svc:     svc,
+				method:  method,
+				handler: cfg.Handler(svc, method),
+			}
+		}
+	}
+	return a
+}
+
+func (a *Adapter) Name() string {
+	return "websocket"
+}
+
+func (a *Adapter) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(a.config.Path, a)
+	a.server = &http.Server{
+		Addr:    a.config.Listen,
+		Handler: mux,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if err := a.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return a.Stop(context.Background())
+	}
+}
+
+// Stop shuts down the HTTP server and closes all open connections, which
+// cancels their in-flight calls.
+func (a *Adapter) Stop(ctx context.Context) error {
+	var err error
+	if a.server != nil {
+		err = a.server.Shutdown(ctx)
+	}
+
+	a.mu.Lock()
+	conns := make([]*conn, 0, len(a.conns))
+	for c := range a.conns {
+		conns = append(conns, c)
+	}
+	a.mu.Unlock()
+
+	for _, c := range conns {
+		c.close(websocket.CloseGoingAway, "server shutting down")
+	}
+	return err
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves calls
+// on it until the client disconnects. It can be mounted on any router when
+// the adapter's own server is not used.
+func (a *Adapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ws, err := a.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	c := newConn(a, ws, r)
+	a.mu.Lock()
+	a.conns[c] = struct{}{}
+	a.mu.Unlock()
+
+	c.serve()
+
+	a.mu.Lock()
+	delete(a.conns, c)
+	a.mu.Unlock()
+}
@@ -0,0 +1,251 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/backend"
+	"github.com/jekabolt/protokol/schema"
+)
+
+func usersSchema() *schema.Schema {
+	user := schema.Message("User").
+		RequiredField("id", schema.Int64).
+		Field("name", schema.String).
+		Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Backend("test").
+		Method(schema.Unary("GetUser").Input(user).Output(user).Build()).
+		Method(schema.ServerStream("ListUsers").Input(user).Output(user).Build()).
+		Method(schema.ServerStream("WatchUsers").Input(user).Output(user).Build()).
+		Method(schema.Bidirectional("Echo").Input(user).Output(user).Build()).
+		MustBuild())
+	return s
+}
+
+func usersHandler() *backend.Handler {
+	h := backend.NewHandler()
+	h.Register("Users", "GetUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		if input["name"] == "missing" {
+			return nil, protokol.NewError(protokol.CodeNotFound, "user not found")
+		}
+		return map[string]any{"id": input["id"], "name": "ada"}, nil
+	})
+	h.RegisterServerStream("Users", "ListUsers", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		for i := range 3 {
+			if err := send(map[string]any{"id": int64(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	h.RegisterServerStream("Users", "WatchUsers", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		if err := send(map[string]any{"id": int64(1)}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h.RegisterBidirectional("Users", "Echo", func(ctx context.Context, stream protokol.Stream) error {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	})
+	return h
+}
+
+// dial serves the users schema through the adapter and opens a connection
+// to it.
+func dial(t *testing.T) *websocket.Conn {
+	t.Helper()
+	reg := protokol.NewBackendRegistry()
+	reg.Register("test", usersHandler())
+	a := New(Config{Config: adapters.Config{
+		Schema:      usersSchema(),
+		Backends:    reg,
+		ErrorLogger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}})
+	srv := httptest.NewServer(a)
+	t.Cleanup(srv.Close)
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return ws
+}
+
+// exchange writes the frames and reads replies until a frame ends the call
+// or the connection.
+func exchange(t *testing.T, ws *websocket.Conn, frames ...string) []Frame {
+	t.Helper()
+	for _, f := range frames {
+		if err := ws.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []Frame
+	for {
+		var f Frame
+		if err := ws.ReadJSON(&f); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, f)
+		if f.Type != FrameMessage {
+			return got
+		}
+	}
+}
+
+// summary renders frames as "type:payload" or "type:code", dropping ids.
+func summary(frames []Frame) string {
+	var parts []string
+	for _, f := range frames {
+		switch {
+		case f.Error != nil:
+			parts = append(parts, f.Type+":"+f.Error.Code)
+		case f.Payload != nil:
+			parts = append(parts, f.Type+":"+fmt.Sprint(f.Payload))
+		default:
+			parts = append(parts, f.Type)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame string
+		want  string
+	}{
+		{
+			name:  "result",
+			frame: `{"id":1,"type":"call","service":"Users","method":"GetUser","payload":{"id":7}}`,
+			want:  "result:map[id:7 name:ada]",
+		},
+		{
+			name:  "backend error code",
+			frame: `{"id":1,"type":"call","service":"Users","method":"GetUser","payload":{"id":7,"name":"missing"}}`,
+			want:  "error:NOT_FOUND",
+		},
+		{
+			name:  "invalid input",
+			frame: `{"id":1,"type":"call","service":"Users","method":"GetUser","payload":{"name":"ada"}}`,
+			want:  "error:INVALID_ARGUMENT",
+		},
+		{
+			name:  "unknown service",
+			frame: `{"id":1,"type":"call","service":"Orders","method":"GetOrder"}`,
+			want:  "error:UNIMPLEMENTED",
+		},
+		{
+			name:  "unknown method",
+			frame: `{"id":1,"type":"call","service":"Users","method":"DeleteUser"}`,
+			want:  "error:UNIMPLEMENTED",
+		},
+		{
+			name:  "missing id",
+			frame: `{"type":"call","service":"Users","method":"GetUser"}`,
+			want:  "error:INVALID_ARGUMENT",
+		},
+		{
+			name:  "malformed frame",
+			frame: `{"id":1,"type":}`,
+			want:  "error:INVALID_ARGUMENT",
+		},
+		{
+			name:  "send to an unknown call",
+			frame: `{"id":9,"type":"send","payload":{"id":1}}`,
+			want:  "error:INVALID_ARGUMENT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := dial(t)
+			if got := summary(exchange(t, ws, tt.frame)); got != tt.want {
+				t.Errorf("frames = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamTermination(t *testing.T) {
+	tests := []struct {
+		name   string
+		frames []string
+		want   string
+	}{
+		{
+			name:   "server stream completes",
+			frames: []string{`{"id":"a","type":"call","service":"Users","method":"ListUsers","payload":{"id":1}}`},
+			want:   "message:map[id:0] message:map[id:1] message:map[id:2] complete",
+		},
+		{
+			name: "bidirectional stream completes after end",
+			frames: []string{
+				`{"id":"a","type":"call","service":"Users","method":"Echo"}`,
+				`{"id":"a","type":"send","payload":{"id":1}}`,
+				`{"id":"a","type":"send","payload":{"id":2}}`,
+				`{"id":"a","type":"end"}`,
+			},
+			want: "message:map[id:1] message:map[id:2] complete",
+		},
+		{
+			name: "invalid stream message fails the call",
+			frames: []string{
+				`{"id":"a","type":"call","service":"Users","method":"Echo"}`,
+				`{"id":"a","type":"send","payload":{"name":"ada"}}`,
+			},
+			want: "error:INVALID_ARGUMENT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := dial(t)
+			if got := summary(exchange(t, ws, tt.frames...)); got != tt.want {
+				t.Errorf("frames = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ws := dial(t)
+	call := `{"id":"w","type":"call","service":"Users","method":"WatchUsers","payload":{"id":1}}`
+	var first Frame
+	if err := ws.WriteMessage(websocket.TextMessage, []byte(call)); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.ReadJSON(&first); err != nil || first.Type != FrameMessage {
+		t.Fatalf("first frame = %+v, %v", first, err)
+	}
+	got := exchange(t, ws, `{"id":"w","type":"cancel"}`)
+	if s := summary(got); s != "error:CANCELLED" {
+		t.Errorf("frames = %s, want error:CANCELLED", s)
+	}
+	if id := string(got[0].ID); id != `"w"` {
+		t.Errorf("id = %s, want the call's id", id)
+	}
+}
@@ -0,0 +1,435 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/jekabolt/protokol"
+)
+
+// Frame types sent by clients.
+const (
+	FrameCall   = "call"   // Start a call; payload is the request input.
+	FrameSend   = "send"   // Send a message on a client or bidirectional stream.
+	FrameEnd    = "end"    // Half-close the client side of a stream.
+	FrameCancel = "cancel" // Abort a call.
+)
+
+// Frame types sent by the server.
+const (
+	FrameResult   = "result"   // Response to a unary call.
+	FrameMessage  = "message"  // One message from a streaming call.
+	FrameComplete = "complete" // A streaming call finished successfully.
+	FrameError    = "error"    // A call failed; no further frames follow for the id.
+)
+
+// Frame is the JSON envelope for every message on the connection. The id is
+// chosen by the client and echoed on every reply for that call.
+type Frame struct {
+	ID       json.RawMessage     `json:"id,omitempty"`
+	Type     string              `json:"type"`
+	Service  string              `json:"service,omitempty"`
+	Method   string              `json:"method,omitempty"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+	Payload  map[string]any      `json:"payload,omitempty"`
+	Error    *Error              `json:"error,omitempty"`
+}
+
+// Error describes a failed call. Code is the canonical code, e.g.
+// "NOT_FOUND", and Details are the details of a *protokol.Error.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details []any  `json:"details,omitempty"`
+}
+
+var (
+	errInvalidFrame   error = protokol.NewError(protokol.CodeInvalidArgument, "invalid frame")
+	errDuplicateID    error = protokol.NewError(protokol.CodeInvalidArgument, "duplicate call id")
+	errUnknownCall    error = protokol.NewError(protokol.CodeInvalidArgument, "unknown call id")
+	errNotStreaming   error = protokol.NewError(protokol.CodeInvalidArgument, "method does not accept client messages")
+	errTooManyCalls   error = protokol.NewError(protokol.CodeResourceExhausted, "too many concurrent calls")
+	errCallCancelled  error = protokol.NewError(protokol.CodeCanceled, "call cancelled")
+	errSendBufferFull error = protokol.NewError(protokol.CodeResourceExhausted, "too many pending stream messages")
+)
+
+type conn struct {
+	a          *Adapter
+	ws         *websocket.Conn
+	ctx        context.Context
+	cancel     context.CancelFunc
+	metadata   map[string][]string
+	remoteAddr string
+
+	writeMu sync.Mutex
+
+	mu    sync.Mutex
+	calls map[string]*call
+	wg    sync.WaitGroup
+}
+
+// call tracks one in-flight call on the connection.
+type call struct {
+	id     json.RawMessage
+	entry  methodEntry
+	ctx    context.Context
+	cancel context.CancelFunc
+	// in buffers client messages for streaming calls. The read loop never
+	// blocks on it; a call whose buffer is full fails instead, so a slow
+	// backend stream does not stall the other calls on the connection.
+	in chan map[string]any
+
+	mu          sync.Mutex
+	inputClosed bool  // the client sent an end frame
+	err         error // why the call was failed by the connection
+}
+
+// send queues a client message for the backend stream. It fails the call
+// with errSendBufferFull when the buffer is full, and reports
+// errInvalidFrame after the client half-closed the stream.
+func (cl *call) send(msg map[string]any) error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.inputClosed {
+		return errInvalidFrame
+	}
+	if cl.ctx.Err() != nil {
+		return nil
+	}
+	select {
+	case cl.in <- msg:
+	default:
+		if cl.err == nil {
+			cl.err = errSendBufferFull
+			cl.cancel()
+		}
+	}
+	return nil
+}
+
+// closeInput half-closes the client side of the call. Repeated calls do
+// nothing.
+func (cl *call) closeInput() {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if !cl.inputClosed {
+		cl.inputClosed = true
+		close(cl.in)
+	}
+}
+
+// fail records err as the reason the call failed and cancels it. Only the
+// first error is kept.
+func (cl *call) fail(err error) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.err == nil {
+		cl.err = err
+		cl.cancel()
+	}
+}
+
+// failure returns the error the call was failed with by the connection.
+func (cl *call) failure() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	return cl.err
+}
+
+func newConn(a *Adapter, ws *websocket.Conn, r *http.Request) *conn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &conn{
+		a:          a,
+		ws:         ws,
+		ctx:        ctx,
+		cancel:     cancel,
+		metadata:   r.Header.Clone(),
+		remoteAddr: r.RemoteAddr,
+		calls:      make(map[string]*call),
+	}
+}
+
+func (c *conn) serve() {
+	defer c.ws.Close()
+	defer c.wg.Wait()
+	defer c.cancel()
+
+	cfg := c.a.config
+	c.ws.SetReadLimit(cfg.MaxMessageSize)
+	c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	c.ws.SetPongHandler(func(string) error {
+		return c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+	})
+
+	go c.keepalive()
+
+	for {
+		var f Frame
+		if err := c.ws.ReadJSON(&f); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.writeError(nil, errInvalidFrame)
+				continue
+			}
+			return
+		}
+		c.ws.SetReadDeadline(time.Now().Add(cfg.PongTimeout))
+		c.dispatch(f)
+	}
+}
+
+func (c *conn) keepalive() {
+	ticker := time.NewTicker(c.a.config.PingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			deadline := time.Now().Add(c.a.config.WriteTimeout)
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+				c.close(websocket.CloseGoingAway, "ping failed")
+				return
+			}
+		}
+	}
+}
+
+func (c *conn) dispatch(f Frame) {
+	if len(f.ID) == 0 {
+		c.writeError(nil, errInvalidFrame)
+		return
+	}
+	key := string(f.ID)
+
+	switch f.Type {
+	case FrameCall:
+		c.start(key, f)
+
+	case FrameSend:
+		cl, ok := c.lookup(key)
+		if !ok {
+			c.writeError(f.ID, errUnknownCall)
+			return
+		}
+		if !cl.entry.method.IsClientStreaming() {
+			c.writeError(f.ID, errNotStreaming)
+			return
+		}
+		if err := cl.send(f.Payload); err != nil {
+			c.writeError(f.ID, err)
+		}
+
+	case FrameEnd:
+		if cl, ok := c.lookup(key); ok && cl.entry.method.IsClientStreaming() {
+			cl.closeInput()
+		}
+
+	case FrameCancel:
+		if cl, ok := c.lookup(key); ok {
+			cl.cancel()
+		}
+
+	default:
+		c.writeError(f.ID, errInvalidFrame)
+	}
+}
+
+func (c *conn) start(key string, f Frame) {
+	entry, ok := c.a.methods[f.Service+"."+f.Method]
+	if !ok {
+		if _, found := c.a.config.Schema.ServiceByName(f.Service); !found {
+			c.writeError(f.ID, protokol.ErrServiceNotFound)
+		} else {
+			c.writeError(f.ID, protokol.ErrMethodNotFound)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	cl := &call{
+		id:     f.ID,
+		entry:  entry,
+		ctx:    ctx,
+		cancel: cancel,
+		in:     make(chan map[string]any, defaultSendBuffer),
+	}
+
+	c.mu.Lock()
+	if _, exists := c.calls[key]; exists {
+		c.mu.Unlock()
+		cancel()
+		c.writeError(f.ID, errDuplicateID)
+		return
+	}
+	if len(c.calls) >= c.a.config.MaxConcurrentCalls {
+		c.mu.Unlock()
+		cancel()
+		c.writeError(f.ID, errTooManyCalls)
+		return
+	}
+	c.calls[key] = cl
+	c.wg.Add(1)
+	c.mu.Unlock()
+
+	req := &protokol.Request{
+		Service:    entry.svc.Name,
+		Method:     entry.method.Name,
+		Input:      f.Payload,
+		Metadata:   make(map[string][]string, len(c.metadata)+len(f.Metadata)),
+		RemoteAddr: c.remoteAddr,
+	}
+	if req.Input == nil {
+		req.Input = make(map[string]any)
+	}
+	for k, v := range c.metadata {
+		req.Metadata[k] = v
+	}
+	for k, v := range f.Metadata {
+		req.Metadata[http.CanonicalHeaderKey(k)] = v
+	}
+
+	go func() {
+		defer c.finish(key, cl)
+		if entry.method.IsStreaming() {
+			c.runStream(cl, req)
+		} else {
+			c.runUnary(cl, req)
+		}
+	}()
+}
+
+func (c *conn) runUnary(cl *call, req *protokol.Request) {
+	resp, err := cl.entry.handler.Handle(cl.ctx, req)
+	if err != nil {
+		c.writeError(cl.id, c.callErr(cl, err))
+		return
+	}
+	c.write(Frame{ID: cl.id, Type: FrameResult, Payload: resp.Output})
+}
+
+func (c *conn) runStream(cl *call, req *protokol.Request) {
+	stream, err := c.a.config.OpenStream(cl.ctx, cl.entry.svc, cl.entry.method, req)
+	if err != nil {
+		c.writeError(cl.id, c.callErr(cl, err))
+		return
+	}
+	defer stream.Close()
+
+	// Closing the stream when the call is cancelled unblocks Recv on
+	// backends that do not watch the context themselves.
+	stop := context.AfterFunc(cl.ctx, func() { stream.Close() })
+	defer stop()
+
+	if cl.entry.method.IsClientStreaming() {
+		go c.pump(cl, stream)
+	}
+
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			if err := cl.failure(); err != nil {
+				c.writeError(cl.id, err)
+				return
+			}
+			c.write(Frame{ID: cl.id, Type: FrameComplete})
+			return
+		}
+		if err != nil {
+			c.writeError(cl.id, c.callErr(cl, err))
+			return
+		}
+		if err := c.write(Frame{ID: cl.id, Type: FrameMessage, Payload: msg}); err != nil {
+			return
+		}
+	}
+}
+
+// pump forwards buffered client messages to the backend stream and
+// half-closes it when the client sends an end frame. A message the stream
+// rejects, such as one failing validation, fails the call.
+func (c *conn) pump(cl *call, stream protokol.Stream) {
+	for {
+		select {
+		case msg, ok := <-cl.in:
+			if !ok {
+				if cs, ok := stream.(protokol.CloseSender); ok {
+					cs.CloseSend()
+				}
+				return
+			}
+			if err := stream.Send(msg); err != nil {
+				// io.EOF and cancellation end the stream, and Recv
+				// reports why.
+				if err != io.EOF && cl.ctx.Err() == nil {
+					cl.fail(err)
+				}
+				return
+			}
+		case <-cl.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *conn) lookup(key string) (*call, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cl, ok := c.calls[key]
+	return cl, ok
+}
+
+func (c *conn) finish(key string, cl *call) {
+	cl.cancel()
+	c.mu.Lock()
+	delete(c.calls, key)
+	c.mu.Unlock()
+	c.wg.Done()
+}
+
+// callErr reports why the connection failed the call, or cancellation
+// requested by the client, rather than the context error surfaced by the
+// backend.
+func (c *conn) callErr(cl *call, err error) error {
+	if ferr := cl.failure(); ferr != nil {
+		return ferr
+	}
+	if cl.ctx.Err() != nil && c.ctx.Err() == nil {
+		return errCallCancelled
+	}
+	return err
+}
+
+func (c *conn) write(f Frame) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	c.ws.SetWriteDeadline(time.Now().Add(c.a.config.WriteTimeout))
+	return c.ws.WriteJSON(f)
+}
+
+// writeError sends err, as converted by Config.PublicError, as an error
+// frame.
+func (c *conn) writeError(id json.RawMessage, err error) {
+	e := c.a.config.PublicError(c.ctx, err)
+	c.write(Frame{
+		ID:    id,
+		Type:  FrameError,
+		Error: &Error{Code: e.Code.String(), Message: e.Error(), Details: e.Details},
+	})
+}
+
+// close sends a close frame and cancels all calls on the connection.
+func (c *conn) close(code int, reason string) {
+	deadline := time.Now().Add(c.a.config.WriteTimeout)
+	c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	c.cancel()
+	c.ws.Close()
+}
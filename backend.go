@@ -36,6 +36,20 @@ type Stream interface {
 	Close() error
 }
 
+// CloseSender is implemented by streams that can half-close the sending side,
+// signalling to the backend that no more messages will be sent while still
+// allowing Recv to drain the remaining responses.
+type CloseSender interface {
+	CloseSend() error
+}
+
+// HealthChecker is implemented by backends that can report whether they are
+// able to serve requests, e.g. by probing a remote service. Load balancers
+// use it to take unhealthy backends out of rotation.
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
 // BackendRegistry manages backend instances.
 type BackendRegistry struct {
 	mu       sync.RWMutex
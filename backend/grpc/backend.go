@@ -0,0 +1,277 @@
+// Package grpc provides a backend that forwards requests to an existing gRPC
+// server. Services are discovered through server reflection and called with
+// dynamic messages, so no generated code is required.
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/internal/grpcstatus"
+	"github.com/jekabolt/protokol/internal/protomap"
+	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/schema/protofile"
+)
+
+// Config for the gRPC backend.
+type Config struct {
+	// Target is the server address, in any form accepted by grpc.NewClient,
+	// e.g. "localhost:50051" or "dns:///users.internal:443".
+	Target string
+	// DialOptions are passed to grpc.NewClient. Without transport
+	// credentials among them, the connection is insecure.
+	DialOptions []grpc.DialOption
+}
+
+// hopByHopHeaders are request headers that are not forwarded as gRPC
+// metadata, because HTTP/2 forbids them or they describe the incoming
+// connection.
+var hopByHopHeaders = map[string]bool{
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Host":              true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Te":                true,
+	"Trailer":           true,
+	"Transfer-Encoding": true,
+	"Upgrade":           true,
+}
+
+// Backend calls the services of a gRPC server.
+type Backend struct {
+	conn     *grpc.ClientConn
+	files    []protoreflect.FileDescriptor
+	services map[string]protoreflect.ServiceDescriptor
+}
+
+// New connects to cfg.Target and discovers its services through server
+// reflection, using the v1 or v1alpha reflection service.
+func New(ctx context.Context, cfg Config) (*Backend, error) {
+	opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, cfg.DialOptions...)
+	conn, err := grpc.NewClient(cfg.Target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("grpc backend: %w", err)
+	}
+	b, err := NewFromConn(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// NewFromConn discovers the services of an existing connection through
+// server reflection. Close closes conn.
+func NewFromConn(ctx context.Context, conn *grpc.ClientConn) (*Backend, error) {
+	files, services, err := discover(ctx, conn)
+	if err != nil {
+		return nil, fmt.Errorf("grpc backend: %w", err)
+	}
+	return &Backend{conn: conn, files: files, services: services}, nil
+}
+
+// Import adds the discovered services and their messages and enums to s,
+// assigned to the backend registered under name.
+func (b *Backend) Import(s *schema.Schema, name string) error {
+	return protofile.Import(s, name, b.files...)
+}
+
+// Files returns the descriptors of the files declaring the discovered
+// services.
+func (b *Backend) Files() []protoreflect.FileDescriptor {
+	return b.files
+}
+
+// Call invokes a unary method. Request metadata is sent as gRPC metadata and
+// the response headers are returned as response metadata. Errors from the
+// server are returned as *protokol.Error with the status code, message and
+// details.
+func (b *Backend) Call(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+	md, err := b.method(req)
+	if err != nil {
+		return nil, err
+	}
+	in := dynamicpb.NewMessage(md.Input())
+	if err := protomap.FromMap(req.Input, in); err != nil {
+		return nil, protokol.Errorf(protokol.CodeInvalidArgument, "invalid request message", protokol.ErrInvalidArgument, err)
+	}
+	out := dynamicpb.NewMessage(md.Output())
+
+	var header metadata.MD
+	if err := b.conn.Invoke(outgoingContext(ctx, req), fullMethod(md), in, out, grpc.Header(&header)); err != nil {
+		return nil, toError(err)
+	}
+	return &protokol.Response{Output: protomap.ToMap(out), Metadata: responseMetadata(header)}, nil
+}
+
+// Stream opens a streaming call. For server-streaming methods the request
+// input is sent as the single request message.
+func (b *Backend) Stream(ctx context.Context, req *protokol.Request) (protokol.Stream, error) {
+	md, err := b.method(req)
+	if err != nil {
+		return nil, err
+	}
+	if !md.IsStreamingClient() && !md.IsStreamingServer() {
+		return nil, protokol.ErrStreamingNotSupported
+	}
+
+	ctx, cancel := context.WithCancel(outgoingContext(ctx, req))
+	desc := &grpc.StreamDesc{
+		StreamName:    string(md.Name()),
+		ServerStreams: md.IsStreamingServer(),
+		ClientStreams: md.IsStreamingClient(),
+	}
+	cs, err := b.conn.NewStream(ctx, desc, fullMethod(md))
+	if err != nil {
+		cancel()
+		return nil, toError(err)
+	}
+	s := &stream{cs: cs, md: md, cancel: cancel}
+	if !md.IsStreamingClient() {
+		if err := s.Send(req.Input); err != nil {
+			cancel()
+			return nil, err
+		}
+		if err := cs.CloseSend(); err != nil {
+			cancel()
+			return nil, toError(err)
+		}
+	}
+	return s, nil
+}
+
+// CheckHealth queries the server's grpc.health.v1 health service for the
+// overall server status. Servers without a health service are healthy when
+// they answer the call.
+func (b *Backend) CheckHealth(ctx context.Context) error {
+	resp, err := healthpb.NewHealthClient(b.conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return toError(err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return protokol.Errorf(protokol.CodeUnavailable, "grpc backend: server is %s", resp.GetStatus())
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (b *Backend) Close() error {
+	return b.conn.Close()
+}
+
+func (b *Backend) method(req *protokol.Request) (protoreflect.MethodDescriptor, error) {
+	sd, ok := b.services[req.Service]
+	if !ok {
+		return nil, protokol.ErrServiceNotFound
+	}
+	md := sd.Methods().ByName(protoreflect.Name(req.Method))
+	if md == nil {
+		return nil, protokol.ErrMethodNotFound
+	}
+	return md, nil
+}
+
+// stream adapts a gRPC client stream to protokol.Stream.
+type stream struct {
+	cs     grpc.ClientStream
+	md     protoreflect.MethodDescriptor
+	cancel context.CancelFunc
+}
+
+func (s *stream) Send(msg map[string]any) error {
+	in := dynamicpb.NewMessage(s.md.Input())
+	if err := protomap.FromMap(msg, in); err != nil {
+		return protokol.Errorf(protokol.CodeInvalidArgument, "invalid request message", protokol.ErrInvalidArgument, err)
+	}
+	return toError(s.cs.SendMsg(in))
+}
+
+// Recv returns the next response message, or io.EOF when the server has
+// finished the call successfully.
+func (s *stream) Recv() (map[string]any, error) {
+	out := dynamicpb.NewMessage(s.md.Output())
+	if err := s.cs.RecvMsg(out); err != nil {
+		return nil, toError(err)
+	}
+	return protomap.ToMap(out), nil
+}
+
+// CloseSend half-closes the call.
+func (s *stream) CloseSend() error {
+	return s.cs.CloseSend()
+}
+
+// Close cancels the call.
+func (s *stream) Close() error {
+	s.cancel()
+	return nil
+}
+
+// toError converts a gRPC status error to a *protokol.Error with the same
+// code, message and details, wrapping the original error. io.EOF and nil
+// are returned unchanged.
+func toError(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	e := grpcstatus.FromStatus(st)
+	e.Err = err
+	return e
+}
+
+func fullMethod(md protoreflect.MethodDescriptor) string {
+	return "/" + string(md.Parent().FullName()) + "/" + string(md.Name())
+}
+
+// outgoingContext adds the request metadata to ctx as outgoing gRPC
+// metadata.
+func outgoingContext(ctx context.Context, req *protokol.Request) context.Context {
+	md := metadata.MD{}
+	for k, v := range req.Metadata {
+		if hopByHopHeaders[http.CanonicalHeaderKey(k)] || strings.HasPrefix(k, ":") {
+			continue
+		}
+		md.Append(k, v...)
+	}
+	if len(md) == 0 {
+		return ctx
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func responseMetadata(md metadata.MD) map[string][]string {
+	if len(md) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(md))
+	for k, v := range md {
+		if strings.HasPrefix(k, ":") || k == "content-type" {
+			continue
+		}
+		key := http.CanonicalHeaderKey(k)
+		out[key] = append(out[key], v...)
+	}
+	return out
+}
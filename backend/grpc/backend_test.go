@@ -0,0 +1,279 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	grpcadapter "github.com/jekabolt/protokol/adapters/grpc"
+	"github.com/jekabolt/protokol/backend"
+	"github.com/jekabolt/protokol/schema"
+)
+
+// upstream serves s with h through the gRPC adapter, with reflection and a
+// health service, and returns a connection to it and the health server.
+func upstream(t *testing.T, s *schema.Schema, h *backend.Handler) (*grpc.ClientConn, *health.Server) {
+	t.Helper()
+	reg := protokol.NewBackendRegistry()
+	reg.Register("test", h)
+	a := grpcadapter.New(grpcadapter.Config{
+		Config: adapters.Config{
+			Schema:      s,
+			Backends:    reg,
+			ErrorLogger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		},
+		Reflection: true,
+	})
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(a.Server(), hs)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(ctx, lis) }()
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		if err := <-done; err != nil {
+			t.Error(err)
+		}
+	})
+	return conn, hs
+}
+
+func usersSchema() *schema.Schema {
+	user := schema.Message("User").
+		Field("id", schema.Int64).
+		Field("name", schema.String).
+		Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Package("acme.v1").Backend("test").
+		Method(schema.Unary("GetUser").Input(user).Output(user).Build()).
+		Method(schema.ServerStream("ListUsers").Input(user).Output(user).Build()).
+		Method(schema.Bidirectional("Echo").Input(user).Output(user).Build()).
+		MustBuild())
+	return s
+}
+
+func usersHandler() *backend.Handler {
+	h := backend.NewHandler()
+	h.Register("Users", "GetUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		if input["name"] == "missing" {
+			return nil, protokol.NewError(protokol.CodeNotFound, "user not found")
+		}
+		return map[string]any{"id": input["id"], "name": "ada"}, nil
+	})
+	h.RegisterServerStream("Users", "ListUsers", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		for i := range 3 {
+			if err := send(map[string]any{"id": int64(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	h.RegisterBidirectional("Users", "Echo", func(ctx context.Context, stream protokol.Stream) error {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	})
+	return h
+}
+
+func newBackend(t *testing.T) (*Backend, *health.Server) {
+	t.Helper()
+	conn, hs := upstream(t, usersSchema(), usersHandler())
+	b, err := NewFromConn(context.Background(), conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, hs
+}
+
+func TestDiscover(t *testing.T) {
+	b, _ := newBackend(t)
+	var names []string
+	for name := range b.services {
+		names = append(names, name)
+	}
+	if fmt.Sprint(names) != "[Users]" {
+		t.Errorf("services = %v, want [Users] without reflection and health", names)
+	}
+
+	s := schema.NewSchema()
+	if err := b.Import(s, "legacy"); err != nil {
+		t.Fatal(err)
+	}
+	svc, ok := s.ServiceByName("Users")
+	if !ok || svc.Backend != "legacy" || len(svc.Methods) != 3 {
+		t.Errorf("imported service = %+v", svc)
+	}
+}
+
+func TestDiscoverNameCollision(t *testing.T) {
+	s := schema.NewSchema()
+	for _, pkg := range []string{"a.v1", "b.v1"} {
+		msg := schema.Message(strings.ToUpper(pkg[:1])+"User").Field("id", schema.Int64).Build()
+		s.AddService(schema.NewService("Users").Package(pkg).Backend("test").
+			Method(schema.Unary("GetUser").Input(msg).Output(msg).Build()).
+			MustBuild())
+	}
+	conn, _ := upstream(t, s, backend.NewHandler())
+	_, err := NewFromConn(context.Background(), conn)
+	if err == nil || !strings.Contains(err.Error(), "are both named Users") {
+		t.Errorf("err = %v, want a name collision", err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	b, _ := newBackend(t)
+
+	tests := []struct {
+		name   string
+		method string
+		input  map[string]any
+		want   string
+		code   protokol.Code
+	}{
+		{
+			name:   "ok",
+			method: "GetUser",
+			input:  map[string]any{"id": int64(7)},
+			want:   "map[id:7 name:ada]",
+		},
+		{
+			name:   "server error code",
+			method: "GetUser",
+			input:  map[string]any{"name": "missing"},
+			code:   protokol.CodeNotFound,
+		},
+		{
+			name:   "invalid input",
+			method: "GetUser",
+			input:  map[string]any{"id": "x"},
+			code:   protokol.CodeInvalidArgument,
+		},
+		{
+			name:   "unknown method",
+			method: "DeleteUser",
+			code:   protokol.CodeUnimplemented,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := b.Call(context.Background(), &protokol.Request{Service: "Users", Method: tt.method, Input: tt.input})
+			if got := protokol.ErrorCode(err); got != tt.code {
+				t.Fatalf("code = %v, want %v (%v)", got, tt.code, err)
+			}
+			if tt.code != protokol.CodeOK {
+				if tt.code == protokol.CodeInvalidArgument && !errors.Is(err, protokol.ErrInvalidArgument) {
+					t.Errorf("err = %v, want ErrInvalidArgument", err)
+				}
+				return
+			}
+			if got := fmt.Sprint(resp.Output); got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	b, _ := newBackend(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		method string
+		send   []map[string]any
+		want   string
+	}{
+		{
+			name:   "server stream ends with EOF",
+			method: "ListUsers",
+			want:   "[map[id:0] map[id:1] map[id:2]]",
+		},
+		{
+			name:   "bidirectional stream ends after CloseSend",
+			method: "Echo",
+			send:   []map[string]any{{"id": int64(1)}, {"name": "b"}},
+			want:   "[map[id:1] map[name:b]]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := b.Stream(ctx, &protokol.Request{Service: "Users", Method: tt.method, Input: map[string]any{}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+			for _, msg := range tt.send {
+				if err := stream.Send(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(tt.send) > 0 {
+				if err := stream.(protokol.CloseSender).CloseSend(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []map[string]any
+			for {
+				msg, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, msg)
+			}
+			if s := fmt.Sprint(got); s != tt.want {
+				t.Errorf("received %s, want %s", s, tt.want)
+			}
+		})
+	}
+
+	if _, err := b.Stream(ctx, &protokol.Request{Service: "Users", Method: "GetUser"}); !errors.Is(err, protokol.ErrStreamingNotSupported) {
+		t.Errorf("Stream of a unary method = %v, want ErrStreamingNotSupported", err)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	b, hs := newBackend(t)
+	if err := b.CheckHealth(context.Background()); err != nil {
+		t.Errorf("serving: %v", err)
+	}
+	hs.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	if err := b.CheckHealth(context.Background()); protokol.ErrorCode(err) != protokol.CodeUnavailable {
+		t.Errorf("not serving: %v, want CodeUnavailable", err)
+	}
+}
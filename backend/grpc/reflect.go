@@ -0,0 +1,193 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Reflection service methods, newest first. The v1alpha messages are
+// identical on the wire, so both are spoken with the v1 types.
+var reflectionMethods = []string{
+	"/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+	"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+}
+
+// internalServices are not discovered as backend services: server
+// reflection, and the health service, which CheckHealth calls.
+var internalServices = map[string]bool{
+	"grpc.reflection.v1.ServerReflection":      true,
+	"grpc.reflection.v1alpha.ServerReflection": true,
+	"grpc.health.v1.Health":                    true,
+}
+
+// reflectionClient sends requests over a single reflection stream.
+type reflectionClient struct {
+	stream grpc.ClientStream
+}
+
+// newReflectionClient opens a reflection stream and lists the server's
+// services, falling back to v1alpha for servers without v1.
+func newReflectionClient(ctx context.Context, conn *grpc.ClientConn) (*reflectionClient, []string, error) {
+	var lastErr error
+	for _, method := range reflectionMethods {
+		stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, method)
+		if err != nil {
+			return nil, nil, err
+		}
+		c := &reflectionClient{stream: stream}
+		resp, err := c.send(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{},
+		})
+		if status.Code(err) == codes.Unimplemented {
+			lastErr = err
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var names []string
+		for _, svc := range resp.GetListServicesResponse().GetService() {
+			names = append(names, svc.GetName())
+		}
+		return c, names, nil
+	}
+	return nil, nil, fmt.Errorf("server reflection is not available: %w", lastErr)
+}
+
+func (c *reflectionClient) send(req *reflectionv1.ServerReflectionRequest) (*reflectionv1.ServerReflectionResponse, error) {
+	if err := c.stream.SendMsg(req); err != nil {
+		return nil, err
+	}
+	resp := new(reflectionv1.ServerReflectionResponse)
+	if err := c.stream.RecvMsg(resp); err != nil {
+		return nil, err
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, status.Error(codes.Code(e.GetErrorCode()), e.GetErrorMessage())
+	}
+	return resp, nil
+}
+
+// files requests the files for req and adds them to protos by name.
+func (c *reflectionClient) files(req *reflectionv1.ServerReflectionRequest, protos map[string]*descriptorpb.FileDescriptorProto) error {
+	resp, err := c.send(req)
+	if err != nil {
+		return err
+	}
+	for _, raw := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fdp := new(descriptorpb.FileDescriptorProto)
+		if err := proto.Unmarshal(raw, fdp); err != nil {
+			return err
+		}
+		protos[fdp.GetName()] = fdp
+	}
+	return nil
+}
+
+// discover returns the descriptors of every service the server lists through
+// reflection, except the reflection and health services, keyed by their
+// names without the package, along with the files declaring them. Services
+// of different packages sharing a name are an error, as requests name
+// services without their package.
+func discover(ctx context.Context, conn *grpc.ClientConn) ([]protoreflect.FileDescriptor, map[string]protoreflect.ServiceDescriptor, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c, names, err := newReflectionClient(ctx, conn)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protos := make(map[string]*descriptorpb.FileDescriptorProto)
+	var services []string
+	for _, name := range names {
+		if internalServices[name] {
+			continue
+		}
+		err := c.files(&reflectionv1.ServerReflectionRequest{
+			MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: name},
+		}, protos)
+		if err != nil {
+			return nil, nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		services = append(services, name)
+	}
+
+	// Servers usually send the dependencies along; fetch any that are
+	// missing, falling back to the descriptors linked into this binary.
+	for missing := missingDependencies(protos); len(missing) > 0; missing = missingDependencies(protos) {
+		for _, path := range missing {
+			err := c.files(&reflectionv1.ServerReflectionRequest{
+				MessageRequest: &reflectionv1.ServerReflectionRequest_FileByFilename{FileByFilename: path},
+			}, protos)
+			if _, ok := protos[path]; ok {
+				continue
+			}
+			fd, ferr := protoregistry.GlobalFiles.FindFileByPath(path)
+			if ferr != nil {
+				if err == nil {
+					err = ferr
+				}
+				return nil, nil, fmt.Errorf("file %s: %w", path, err)
+			}
+			protos[path] = protodesc.ToFileDescriptorProto(fd)
+		}
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, fdp := range protos {
+		set.File = append(set.File, fdp)
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fds []protoreflect.FileDescriptor
+	seen := make(map[string]bool)
+	byName := make(map[string]protoreflect.ServiceDescriptor)
+	for _, name := range services {
+		d, err := files.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return nil, nil, fmt.Errorf("service %s: %w", name, err)
+		}
+		sd, ok := d.(protoreflect.ServiceDescriptor)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s is not a service", name)
+		}
+		if other, ok := byName[string(sd.Name())]; ok {
+			return nil, nil, fmt.Errorf("services %s and %s are both named %s", other.FullName(), sd.FullName(), sd.Name())
+		}
+		byName[string(sd.Name())] = sd
+		if fd := sd.ParentFile(); !seen[fd.Path()] {
+			seen[fd.Path()] = true
+			fds = append(fds, fd)
+		}
+	}
+	return fds, byName, nil
+}
+
+// missingDependencies lists the imports of protos that are not in protos.
+func missingDependencies(protos map[string]*descriptorpb.FileDescriptorProto) []string {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, fdp := range protos {
+		for _, dep := range fdp.GetDependency() {
+			if _, ok := protos[dep]; !ok && !seen[dep] {
+				seen[dep] = true
+				missing = append(missing, dep)
+			}
+		}
+	}
+	return missing
+}
@@ -13,12 +13,14 @@ type HandlerFunc func(ctx context.Context, input map[string]any) (map[string]any
 // Handler is a backend that routes to Go function handlers.
 type Handler struct {
 	handlers map[string]map[string]HandlerFunc
+	streams  map[string]map[string]streamFunc
 }
 
 // NewHandler creates a new Handler with an empty handler registry.
 func NewHandler() *Handler {
 	return &Handler{
 		handlers: make(map[string]map[string]HandlerFunc),
+		streams:  make(map[string]map[string]streamFunc),
 	}
 }
 
@@ -49,9 +51,20 @@ func (h *Handler) Call(ctx context.Context, req *protokol.Request) (*protokol.Re
 	return &protokol.Response{Output: output}, nil
 }
 
-// Stream returns ErrStreamingNotSupported as Handler does not support streaming.
+// Stream starts the streaming handler registered for the request's service
+// and method in a new goroutine. It returns ErrStreamingNotSupported if the
+// method only has a unary handler.
 func (h *Handler) Stream(ctx context.Context, req *protokol.Request) (protokol.Stream, error) {
-	return nil, protokol.ErrStreamingNotSupported
+	if fn, ok := h.streams[req.Service][req.Method]; ok {
+		return startStream(ctx, req.Input, fn), nil
+	}
+	if _, ok := h.handlers[req.Service][req.Method]; ok {
+		return nil, protokol.ErrStreamingNotSupported
+	}
+	if h.handlers[req.Service] == nil && h.streams[req.Service] == nil {
+		return nil, protokol.ErrServiceNotFound
+	}
+	return nil, protokol.ErrMethodNotFound
 }
 
 // Close is a no-op for Handler as there are no resources to release.
@@ -0,0 +1,66 @@
+package httpproxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jekabolt/protokol"
+)
+
+// maxErrorBody limits how much of an error response is read for its
+// message.
+const maxErrorBody = 64 << 10
+
+// StatusError describes an upstream response without a 2xx status. It is
+// returned as the cause of a *protokol.Error whose code is derived from the
+// status with protokol.CodeFromHTTPStatus, e.g. CodeNotFound for 404.
+type StatusError struct {
+	StatusCode int
+	// Message is taken from the "error" or "message" field of a JSON error
+	// body, and is the status text otherwise.
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("upstream returned %d: %s", e.StatusCode, e.Message)
+}
+
+// newStatusError reads the error response and returns it with the code for
+// its status and the upstream message.
+func newStatusError(resp *http.Response) *protokol.Error {
+	e := &StatusError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if err == nil {
+		if msg := errorMessage(data); msg != "" {
+			e.Message = msg
+		}
+	}
+	return &protokol.Error{Code: protokol.CodeFromHTTPStatus(resp.StatusCode), Message: e.Message, Err: e}
+}
+
+// errorMessage returns the "error" or "message" field of a JSON error body.
+// The error field may be the message or, as in Google APIs, an object
+// holding it.
+func errorMessage(data []byte) string {
+	var body struct {
+		Error   json.RawMessage `json:"error"`
+		Message string          `json:"message"`
+	}
+	if json.Unmarshal(data, &body) != nil {
+		return ""
+	}
+	var msg string
+	if json.Unmarshal(body.Error, &msg) != nil {
+		var nested struct {
+			Message string `json:"message"`
+		}
+		json.Unmarshal(body.Error, &nested)
+		msg = nested.Message
+	}
+	if msg == "" {
+		msg = body.Message
+	}
+	return msg
+}
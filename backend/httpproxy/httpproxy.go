@@ -0,0 +1,351 @@
+// Package httpproxy provides a backend that forwards requests to an upstream
+// HTTP/JSON service, so existing REST services can be exposed through any
+// adapter without writing a handler per endpoint.
+package httpproxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/schema"
+)
+
+// Route maps a method to an upstream endpoint.
+type Route struct {
+	Service string
+	Method  string
+	// HTTPMethod is the upstream verb. Defaults to POST.
+	HTTPMethod string
+	// Path is the endpoint path relative to Config.BaseURL. {field}
+	// placeholders, which may name nested fields as in {user.id}, are
+	// replaced with input values, e.g. "/users/{id}".
+	Path string
+}
+
+// Config for the proxy backend.
+type Config struct {
+	// BaseURL is the upstream service address, e.g. "http://users:8080/api".
+	BaseURL string
+	Routes  []Route
+	// ForwardHeaders lists the request metadata headers sent upstream,
+	// e.g. "Authorization". No headers are forwarded by default.
+	ForwardHeaders []string
+	// ResponseHeaders lists the upstream response headers returned as
+	// response metadata.
+	ResponseHeaders []string
+	// HealthPath is an endpoint relative to BaseURL that CheckHealth
+	// requests with GET, e.g. "/healthz". A 2xx status means healthy.
+	HealthPath string
+	// Client sends the upstream requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// placeholderPattern matches {field} placeholders in route paths.
+var placeholderPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Backend forwards requests to an upstream HTTP service.
+type Backend struct {
+	config Config
+	base   *url.URL
+	routes map[string]map[string]Route
+}
+
+// New creates a proxy backend. It returns an error if BaseURL is not an
+// absolute URL or a route has no path.
+func New(cfg Config) (*Backend, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("httpproxy: invalid base URL %q", cfg.BaseURL)
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	b := &Backend{config: cfg, base: base, routes: make(map[string]map[string]Route)}
+	for _, r := range cfg.Routes {
+		if !strings.HasPrefix(r.Path, "/") {
+			return nil, fmt.Errorf("httpproxy: route %s.%s: path %q must start with /", r.Service, r.Method, r.Path)
+		}
+		if r.HTTPMethod == "" {
+			r.HTTPMethod = http.MethodPost
+		}
+		if b.routes[r.Service] == nil {
+			b.routes[r.Service] = make(map[string]Route)
+		}
+		b.routes[r.Service][r.Method] = r
+	}
+	return b, nil
+}
+
+// SchemaRoutes returns a route for every unary method served by backend that
+// has an HTTP mapping, using the method's HTTPMethod and HTTPPath. Path
+// parameters with a pattern, as in {id:[0-9]+}, become plain placeholders.
+func SchemaRoutes(s *schema.Schema, backend string) []Route {
+	var routes []Route
+	for _, svc := range s.Services {
+		for _, m := range svc.Methods {
+			if svc.BackendFor(m) != backend || m.IsStreaming() || m.HTTPPath == "" {
+				continue
+			}
+			routes = append(routes, Route{
+				Service:    svc.Name,
+				Method:     m.Name,
+				HTTPMethod: m.HTTPMethod,
+				Path:       patternSuffix.ReplaceAllString(m.HTTPPath, "}"),
+			})
+		}
+	}
+	return routes
+}
+
+// patternSuffix matches the pattern of a {name:pattern} path parameter.
+var patternSuffix = regexp.MustCompile(`:[^}]*\}`)
+
+// Call sends the request to the method's upstream endpoint. Input fields
+// named by path placeholders are put into the path, and must not be empty,
+// "." or ".."; the remaining fields
+// form the JSON body, or the query string for GET, HEAD and DELETE requests,
+// with nested fields as dotted names. A 2xx response body must be a JSON
+// object, which becomes the output; other responses are returned as a
+// *protokol.Error with the code for the status, wrapping a *StatusError.
+func (b *Backend) Call(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+	methods, ok := b.routes[req.Service]
+	if !ok {
+		return nil, protokol.ErrServiceNotFound
+	}
+	route, ok := methods[req.Method]
+	if !ok {
+		return nil, protokol.ErrMethodNotFound
+	}
+
+	httpReq, err := b.newRequest(ctx, route, req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := b.config.Client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("httpproxy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newStatusError(resp)
+	}
+	output, err := decodeObject(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("httpproxy: decode response: %w", err)
+	}
+	out := &protokol.Response{Output: output}
+	for _, h := range b.config.ResponseHeaders {
+		if v := resp.Header.Values(h); len(v) > 0 {
+			if out.Metadata == nil {
+				out.Metadata = make(map[string][]string)
+			}
+			out.Metadata[http.CanonicalHeaderKey(h)] = v
+		}
+	}
+	return out, nil
+}
+
+// Stream returns ErrStreamingNotSupported as upstream HTTP endpoints are
+// called one request at a time.
+func (b *Backend) Stream(ctx context.Context, req *protokol.Request) (protokol.Stream, error) {
+	return nil, protokol.ErrStreamingNotSupported
+}
+
+// CheckHealth requests Config.HealthPath and reports a non-2xx status as a
+// *protokol.Error with the code for the status. Without a HealthPath the
+// upstream is always healthy.
+func (b *Backend) CheckHealth(ctx context.Context) error {
+	if b.config.HealthPath == "" {
+		return nil
+	}
+	u := b.base.JoinPath(b.config.HealthPath)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("httpproxy: %w", err)
+	}
+	resp, err := b.config.Client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("httpproxy: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newStatusError(resp)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+	return nil
+}
+
+// Close releases idle upstream connections.
+func (b *Backend) Close() error {
+	b.config.Client.CloseIdleConnections()
+	return nil
+}
+
+func (b *Backend) newRequest(ctx context.Context, route Route, req *protokol.Request) (*http.Request, error) {
+	input := req.Input
+	var missing, invalid []string
+	path := placeholderPattern.ReplaceAllStringFunc(route.Path, func(p string) string {
+		name := p[1 : len(p)-1]
+		var v any
+		v, input = take(input, name)
+		if v == nil {
+			missing = append(missing, name)
+			return ""
+		}
+		s := formatValue(v)
+		// The joined path is cleaned, so dot segments would move the
+		// request to another endpoint and empty ones would merge with
+		// their neighbours.
+		if s == "" || s == "." || s == ".." {
+			invalid = append(invalid, name)
+			return ""
+		}
+		return url.PathEscape(s)
+	})
+	if len(missing) > 0 {
+		return nil, protokol.Errorf(protokol.CodeInvalidArgument, "missing path parameter %s", strings.Join(missing, ", "), protokol.ErrInvalidArgument)
+	}
+	if len(invalid) > 0 {
+		return nil, protokol.Errorf(protokol.CodeInvalidArgument, "invalid path parameter %s", strings.Join(invalid, ", "), protokol.ErrInvalidArgument)
+	}
+
+	u := b.base.JoinPath(path)
+	var body io.Reader
+	switch route.HTTPMethod {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		query := u.Query()
+		addQuery(query, "", input)
+		u.RawQuery = query.Encode()
+	default:
+		if input == nil {
+			input = map[string]any{}
+		}
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("httpproxy: encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, route.HTTPMethod, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("httpproxy: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for _, h := range b.config.ForwardHeaders {
+		for _, v := range metadataValues(req.Metadata, h) {
+			httpReq.Header.Add(h, v)
+		}
+	}
+	return httpReq, nil
+}
+
+// take returns the value at the dotted path in m and a copy of m without
+// it. m itself is not modified.
+func take(m map[string]any, path string) (any, map[string]any) {
+	name, rest, nested := strings.Cut(path, ".")
+	v, ok := m[name]
+	if !ok {
+		return nil, m
+	}
+	out := make(map[string]any, len(m))
+	for k, val := range m {
+		out[k] = val
+	}
+	if !nested {
+		delete(out, name)
+		return v, out
+	}
+	child, ok := v.(map[string]any)
+	if !ok {
+		return nil, m
+	}
+	v, out[name] = take(child, rest)
+	return v, out
+}
+
+// addQuery adds the fields of m to query. Nested messages and maps use
+// dotted names and lists repeat the parameter.
+func addQuery(query url.Values, prefix string, m map[string]any) {
+	for k, v := range m {
+		switch v := v.(type) {
+		case nil:
+		case map[string]any:
+			addQuery(query, prefix+k+".", v)
+		case []any:
+			for _, elem := range v {
+				if elem != nil {
+					query.Add(prefix+k, formatValue(elem))
+				}
+			}
+		default:
+			query.Add(prefix+k, formatValue(v))
+		}
+	}
+}
+
+// formatValue renders a scalar input value as text. Floats are printed
+// without exponents and bytes as standard base64, as in JSON.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	}
+	return fmt.Sprint(v)
+}
+
+// metadataValues looks up a header in request metadata, whose keys are
+// canonical for HTTP adapters but may differ in case for others.
+func metadataValues(md map[string][]string, name string) []string {
+	if v, ok := md[http.CanonicalHeaderKey(name)]; ok {
+		return v
+	}
+	for k, v := range md {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func decodeObject(r io.Reader) (map[string]any, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return make(map[string]any), nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out map[string]any
+	if err := dec.Decode(&out); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return nil, errors.New("expected a JSON object, got null")
+	}
+	return out, nil
+}
@@ -0,0 +1,195 @@
+package httpproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+)
+
+// upstream records the requests it receives and replies with status and
+// body.
+type upstream struct {
+	status int
+	body   string
+	header http.Header
+
+	method, path, query, reqBody string
+	reqHeader                    http.Header
+}
+
+func (u *upstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, _ := io.ReadAll(r.Body)
+	u.method, u.path, u.query, u.reqBody = r.Method, r.URL.EscapedPath(), r.URL.RawQuery, string(data)
+	u.reqHeader = r.Header.Clone()
+	for k, v := range u.header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(u.status)
+	io.WriteString(w, u.body)
+}
+
+func newBackend(t *testing.T, u *upstream) *Backend {
+	t.Helper()
+	srv := httptest.NewServer(u)
+	t.Cleanup(srv.Close)
+	b, err := New(Config{
+		BaseURL: srv.URL + "/api",
+		Routes: []Route{
+			{Service: "Users", Method: "GetUser", HTTPMethod: http.MethodGet, Path: "/users/{id}"},
+			{Service: "Users", Method: "CreateUser", Path: "/users"},
+			{Service: "Users", Method: "DeleteSessions", HTTPMethod: http.MethodDelete, Path: "/users/{user.id}/sessions"},
+		},
+		ForwardHeaders:  []string{"Authorization"},
+		ResponseHeaders: []string{"ETag"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestRequestMapping(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		input  map[string]any
+		want   string
+	}{
+		{
+			name:   "path and query",
+			method: "GetUser",
+			input:  map[string]any{"id": "7", "fields": []any{"name", "email"}, "page": map[string]any{"size": 10}},
+			want:   "GET /api/users/7 fields=name&fields=email&page.size=10 ",
+		},
+		{
+			name:   "path values are escaped",
+			method: "GetUser",
+			input:  map[string]any{"id": "a/b c"},
+			want:   "GET /api/users/a%2Fb%20c  ",
+		},
+		{
+			name:   "body",
+			method: "CreateUser",
+			input:  map[string]any{"name": "ada", "age": 36},
+			want:   `POST /api/users  {"age":36,"name":"ada"}`,
+		},
+		{
+			name:   "empty body",
+			method: "CreateUser",
+			want:   "POST /api/users  {}",
+		},
+		{
+			name:   "nested path field",
+			method: "DeleteSessions",
+			input:  map[string]any{"user": map[string]any{"id": int64(3), "name": "ada"}},
+			want:   "DELETE /api/users/3/sessions user.name=ada ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &upstream{status: http.StatusOK, body: `{}`}
+			b := newBackend(t, u)
+			_, err := b.Call(context.Background(), &protokol.Request{Service: "Users", Method: tt.method, Input: tt.input})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := fmt.Sprintf("%s %s %s %s", u.method, u.path, u.query, u.reqBody)
+			if got != tt.want {
+				t.Errorf("request = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvalidPathParameters(t *testing.T) {
+	tests := []struct {
+		name  string
+		input map[string]any
+	}{
+		{name: "missing", input: map[string]any{}},
+		{name: "empty", input: map[string]any{"user": map[string]any{"id": ""}}},
+		{name: "dot", input: map[string]any{"user": map[string]any{"id": "."}}},
+		{name: "dot dot", input: map[string]any{"user": map[string]any{"id": ".."}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &upstream{status: http.StatusOK, body: `{}`}
+			b := newBackend(t, u)
+			_, err := b.Call(context.Background(), &protokol.Request{Service: "Users", Method: "DeleteSessions", Input: tt.input})
+			if !errors.Is(err, protokol.ErrInvalidArgument) {
+				t.Errorf("err = %v, want ErrInvalidArgument", err)
+			}
+			if u.method != "" {
+				t.Errorf("upstream called: %s %s", u.method, u.path)
+			}
+		})
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	u := &upstream{
+		status: http.StatusOK,
+		body:   `{"id":"7"}`,
+		header: http.Header{"Etag": {`"v1"`}, "X-Internal": {"secret"}},
+	}
+	b := newBackend(t, u)
+	resp, err := b.Call(context.Background(), &protokol.Request{
+		Service:  "Users",
+		Method:   "GetUser",
+		Input:    map[string]any{"id": "7"},
+		Metadata: map[string][]string{"authorization": {"Bearer t"}, "Cookie": {"c=1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.reqHeader.Get("Authorization"); got != "Bearer t" {
+		t.Errorf("Authorization = %q, want forwarded", got)
+	}
+	if got := u.reqHeader.Get("Cookie"); got != "" {
+		t.Errorf("Cookie = %q, want not forwarded", got)
+	}
+	if got := fmt.Sprint(resp.Metadata); got != `map[Etag:["v1"]]` {
+		t.Errorf("metadata = %s", got)
+	}
+	if got := fmt.Sprint(resp.Output); got != "map[id:7]" {
+		t.Errorf("output = %s", got)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		code   protokol.Code
+		msg    string
+	}{
+		{status: http.StatusBadRequest, body: `{"error":"bad id"}`, code: protokol.CodeInvalidArgument, msg: "bad id"},
+		{status: http.StatusUnauthorized, body: ``, code: protokol.CodeUnauthenticated, msg: "Unauthorized"},
+		{status: http.StatusNotFound, body: `{"message":"no user"}`, code: protokol.CodeNotFound, msg: "no user"},
+		{status: http.StatusConflict, body: `{"error":{"message":"exists"}}`, code: protokol.CodeAlreadyExists, msg: "exists"},
+		{status: http.StatusServiceUnavailable, body: `oops`, code: protokol.CodeUnavailable, msg: "Service Unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			b := newBackend(t, &upstream{status: tt.status, body: tt.body})
+			_, err := b.Call(context.Background(), &protokol.Request{Service: "Users", Method: "GetUser", Input: map[string]any{"id": "7"}})
+			var e *protokol.Error
+			if !errors.As(err, &e) {
+				t.Fatalf("err = %v, want *protokol.Error", err)
+			}
+			if e.Code != tt.code || e.Message != tt.msg {
+				t.Errorf("error = %v %q, want %v %q", e.Code, e.Message, tt.code, tt.msg)
+			}
+			var se *StatusError
+			if !errors.As(err, &se) || se.StatusCode != tt.status {
+				t.Errorf("status error = %v, want %d", se, tt.status)
+			}
+		})
+	}
+}
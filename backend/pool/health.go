@@ -0,0 +1,148 @@
+package pool
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// healthLoop checks the members implementing protokol.HealthChecker every
+// HealthCheck.Interval until the pool is closed.
+func (p *Pool) healthLoop() {
+	defer close(p.done)
+
+	if p.config.HealthCheck.Interval < 0 || !p.hasCheckers() {
+		<-p.ctx.Done()
+		return
+	}
+
+	ticker := time.NewTicker(p.config.HealthCheck.Interval)
+	defer ticker.Stop()
+
+	p.checkAll()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-ticker.C:
+			p.checkAll()
+		}
+	}
+}
+
+func (p *Pool) hasCheckers() bool {
+	for _, m := range p.members {
+		if m.checker != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAll checks the members concurrently and waits for the results.
+// Checks interrupted by Close are not recorded.
+func (p *Pool) checkAll() {
+	var wg sync.WaitGroup
+	for _, m := range p.members {
+		if m.checker == nil {
+			continue
+		}
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(p.ctx, p.config.HealthCheck.Timeout)
+			defer cancel()
+			err := m.checker.CheckHealth(ctx)
+			if p.ctx.Err() != nil {
+				return
+			}
+			p.recordCheck(m, err)
+		})
+	}
+	wg.Wait()
+}
+
+// recordCheck updates the health of m after a check. A member leaves the
+// rotation after UnhealthyThreshold consecutive failed checks and rejoins it,
+// in slow start, after HealthyThreshold consecutive passed checks.
+func (p *Pool) recordCheck(m *member, err error) {
+	hc := p.config.HealthCheck
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		m.passed = 0
+		m.failed++
+		if m.healthy && m.failed >= hc.UnhealthyThreshold {
+			m.healthy = false
+			p.config.Logger.Warn("pool member unhealthy",
+				slog.String("member", m.Name),
+				slog.String("error", err.Error()),
+			)
+		}
+		return
+	}
+
+	m.failed = 0
+	m.passed++
+	if !m.healthy && m.passed >= hc.HealthyThreshold {
+		m.healthy = true
+		now := time.Now()
+		if m.since.Before(now) {
+			m.since = now
+		}
+		p.config.Logger.Info("pool member healthy", slog.String("member", m.Name))
+	}
+}
+
+// observe records the result of a call to m for outlier detection. After
+// Outlier.ConsecutiveErrors failed calls m is ejected, unless that would
+// exceed MaxEjectionPercent. Its ejection time grows with each ejection and
+// is reset once m has served for MaxEjectionTime without being ejected.
+func (p *Pool) observe(ctx context.Context, m *member, err error) {
+	o := p.config.Outlier
+	if o.ConsecutiveErrors < 0 {
+		return
+	}
+	failed := o.IsFailure(ctx, err)
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !failed {
+		m.errors = 0
+		if m.ejections > 0 && now.Sub(m.since) >= o.MaxEjectionTime {
+			m.ejections = 0
+		}
+		return
+	}
+
+	m.errors++
+	if m.errors < o.ConsecutiveErrors || now.Before(m.since) {
+		return
+	}
+	m.errors = 0
+	if !p.canEject(now) {
+		return
+	}
+	m.ejections++
+	d := min(time.Duration(m.ejections)*o.BaseEjectionTime, o.MaxEjectionTime)
+	m.since = now.Add(d)
+	p.config.Logger.Warn("pool member ejected",
+		slog.String("member", m.Name),
+		slog.Duration("duration", d),
+		slog.String("error", err.Error()),
+	)
+}
+
+// canEject reports whether another member may be ejected under
+// MaxEjectionPercent. Must be called with p.mu held.
+func (p *Pool) canEject(now time.Time) bool {
+	ejected := 0
+	for _, m := range p.members {
+		if now.Before(m.since) {
+			ejected++
+		}
+	}
+	limit := max(1, len(p.members)*p.config.Outlier.MaxEjectionPercent/100)
+	return ejected < limit
+}
@@ -0,0 +1,509 @@
+// Package pool provides a backend that spreads requests over several
+// replicas of another backend, registered together under one name. Members
+// are load balanced with a choice of strategies, checked with active health
+// checks, ejected as outliers when they keep failing, and brought back into
+// rotation gradually.
+package pool
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jekabolt/protokol"
+)
+
+// ErrNoHealthyBackends is returned when every member is unhealthy or
+// ejected. Its code is CodeUnavailable.
+var ErrNoHealthyBackends error = protokol.NewError(protokol.CodeUnavailable, "no healthy backends")
+
+// Strategy selects how requests are distributed over the members.
+type Strategy int
+
+const (
+	// RoundRobin sends requests to the members in turn.
+	RoundRobin Strategy = iota
+	// LeastRequests sends each request to the member with the fewest
+	// outstanding calls and open streams.
+	LeastRequests
+	// ConsistentHash sends requests with the same key, as returned by
+	// Config.Key, to the same member, moving few keys when members leave
+	// or rejoin the rotation.
+	ConsistentHash
+)
+
+const (
+	defaultHealthInterval     = 10 * time.Second
+	defaultHealthTimeout      = 2 * time.Second
+	defaultUnhealthyThreshold = 3
+	defaultHealthyThreshold   = 2
+
+	defaultConsecutiveErrors  = 5
+	defaultBaseEjectionTime   = 30 * time.Second
+	defaultMaxEjectionTime    = 5 * time.Minute
+	defaultMaxEjectionPercent = 50
+
+	defaultSlowStart = 30 * time.Second
+	// minSlowStartWeight is the share of its traffic a member receives at
+	// the start of slow start.
+	minSlowStartWeight = 0.1
+
+	// virtualNodes is the number of ring positions per member for
+	// ConsistentHash.
+	virtualNodes = 100
+)
+
+// KeyFunc extracts the hash key of a request for ConsistentHash. Requests
+// with an empty key are sent round robin.
+type KeyFunc func(req *protokol.Request) string
+
+// Member is a backend in the pool.
+type Member struct {
+	// Name identifies the member in logs and Status, e.g. its address.
+	Name    string
+	Backend protokol.Backend
+}
+
+// HealthCheck configures active health checks of members implementing
+// protokol.HealthChecker. Other members are always considered healthy.
+// Zero values use the defaults.
+type HealthCheck struct {
+	// Interval between checks. Defaults to 10s; negative disables checks.
+	Interval time.Duration
+	// Timeout of a single check. Defaults to 2s.
+	Timeout time.Duration
+	// UnhealthyThreshold is the number of consecutive failed checks after
+	// which a member leaves the rotation. Defaults to 3.
+	UnhealthyThreshold int
+	// HealthyThreshold is the number of consecutive passed checks after
+	// which an unhealthy member rejoins the rotation. Defaults to 2.
+	HealthyThreshold int
+}
+
+// Outlier configures outlier detection, which ejects members whose calls
+// keep failing. Zero values use the defaults.
+type Outlier struct {
+	// ConsecutiveErrors is the number of consecutive failed calls after
+	// which a member is ejected. Defaults to 5; negative disables outlier
+	// detection.
+	ConsecutiveErrors int
+	// BaseEjectionTime is how long a member is ejected the first time.
+	// Each further ejection lasts BaseEjectionTime longer, up to
+	// MaxEjectionTime. Defaults to 30s.
+	BaseEjectionTime time.Duration
+	// MaxEjectionTime defaults to 5m.
+	MaxEjectionTime time.Duration
+	// MaxEjectionPercent limits the share of members ejected at once,
+	// though one member can always be ejected. Defaults to 50.
+	MaxEjectionPercent int
+	// IsFailure reports whether a call error counts against the member.
+	// Defaults to errors with code CodeUnavailable, CodeInternal,
+	// CodeUnknown, CodeDataLoss or CodeDeadlineExceeded, unless the
+	// caller's context is done.
+	IsFailure func(ctx context.Context, err error) bool
+}
+
+// Config for a pool.
+type Config struct {
+	Members  []Member
+	Strategy Strategy
+	// Key extracts the hash key for ConsistentHash, e.g. a user id.
+	Key         KeyFunc
+	HealthCheck HealthCheck
+	Outlier     Outlier
+	// SlowStart is how long a member rejoining the rotation takes to ramp
+	// up from a tenth to its full share of requests. Defaults to 30s;
+	// negative disables slow start.
+	SlowStart time.Duration
+	// Logger receives ejections and health changes. Defaults to
+	// slog.Default().
+	Logger *slog.Logger
+}
+
+// Pool is a backend distributing requests over its members.
+type Pool struct {
+	config  Config
+	members []*member
+	ring    []ringNode
+	next    atomic.Uint64
+
+	mu sync.Mutex // guards the health and ejection state of members
+
+	// ctx is canceled by Close to stop health checking.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// member is a pool member with its health and load.
+type member struct {
+	Member
+	checker     protokol.HealthChecker
+	outstanding atomic.Int64
+
+	// The following fields are guarded by Pool.mu.
+	healthy   bool
+	passed    int // consecutive passed health checks
+	failed    int // consecutive failed health checks
+	errors    int // consecutive failed calls
+	ejections int
+	// since is when the member last rejoined the rotation, and is in the
+	// future while it is ejected.
+	since time.Time
+}
+
+// ringNode is a position of a member on the ConsistentHash ring.
+type ringNode struct {
+	hash   uint64
+	member *member
+}
+
+// New creates a pool and starts health checking its members. It returns an
+// error if there are no members or a member has no name, no backend or a
+// duplicate name. The pool owns its members and closes them on Close.
+func New(cfg Config) (*Pool, error) {
+	if len(cfg.Members) == 0 {
+		return nil, fmt.Errorf("pool: no members")
+	}
+	applyDefaults(&cfg)
+
+	p := &Pool{config: cfg, done: make(chan struct{})}
+	names := make(map[string]bool)
+	for i, m := range cfg.Members {
+		if m.Name == "" || m.Backend == nil {
+			return nil, fmt.Errorf("pool: member %d needs a name and a backend", i)
+		}
+		if names[m.Name] {
+			return nil, fmt.Errorf("pool: duplicate member %q", m.Name)
+		}
+		names[m.Name] = true
+
+		pm := &member{Member: m, healthy: true}
+		pm.checker, _ = m.Backend.(protokol.HealthChecker)
+		p.members = append(p.members, pm)
+	}
+	if cfg.Strategy == ConsistentHash {
+		p.buildRing()
+	}
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	go p.healthLoop()
+	return p, nil
+}
+
+func applyDefaults(cfg *Config) {
+	hc := &cfg.HealthCheck
+	if hc.Interval == 0 {
+		hc.Interval = defaultHealthInterval
+	}
+	if hc.Timeout <= 0 {
+		hc.Timeout = defaultHealthTimeout
+	}
+	if hc.UnhealthyThreshold <= 0 {
+		hc.UnhealthyThreshold = defaultUnhealthyThreshold
+	}
+	if hc.HealthyThreshold <= 0 {
+		hc.HealthyThreshold = defaultHealthyThreshold
+	}
+
+	o := &cfg.Outlier
+	if o.ConsecutiveErrors == 0 {
+		o.ConsecutiveErrors = defaultConsecutiveErrors
+	}
+	if o.BaseEjectionTime <= 0 {
+		o.BaseEjectionTime = defaultBaseEjectionTime
+	}
+	if o.MaxEjectionTime <= 0 {
+		o.MaxEjectionTime = defaultMaxEjectionTime
+	}
+	if o.MaxEjectionTime < o.BaseEjectionTime {
+		o.MaxEjectionTime = o.BaseEjectionTime
+	}
+	if o.MaxEjectionPercent <= 0 {
+		o.MaxEjectionPercent = defaultMaxEjectionPercent
+	}
+	if o.IsFailure == nil {
+		o.IsFailure = isFailure
+	}
+
+	if cfg.SlowStart == 0 {
+		cfg.SlowStart = defaultSlowStart
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+}
+
+// isFailure is the default Outlier.IsFailure.
+func isFailure(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	switch protokol.ErrorCode(err) {
+	case protokol.CodeUnavailable, protokol.CodeInternal, protokol.CodeUnknown,
+		protokol.CodeDataLoss, protokol.CodeDeadlineExceeded:
+		return true
+	}
+	return false
+}
+
+// Call forwards the request to a member chosen by the strategy.
+func (p *Pool) Call(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+	m := p.pick(req)
+	if m == nil {
+		return nil, ErrNoHealthyBackends
+	}
+	m.outstanding.Add(1)
+	defer m.outstanding.Add(-1)
+	resp, err := m.Backend.Call(ctx, req)
+	p.observe(ctx, m, err)
+	return resp, err
+}
+
+// Stream opens the stream on a member chosen by the strategy. Open streams
+// count as outstanding requests for LeastRequests until they are closed:
+// the slot is released only by the returned stream's Close, not when Recv
+// returns io.EOF or an error, so callers must always close it. Only errors
+// opening the stream count for outlier detection.
+func (p *Pool) Stream(ctx context.Context, req *protokol.Request) (protokol.Stream, error) {
+	m := p.pick(req)
+	if m == nil {
+		return nil, ErrNoHealthyBackends
+	}
+	m.outstanding.Add(1)
+	opened := false
+	defer func() {
+		if !opened {
+			m.outstanding.Add(-1)
+		}
+	}()
+	s, err := m.Backend.Stream(ctx, req)
+	p.observe(ctx, m, err)
+	if err != nil {
+		return nil, err
+	}
+	opened = true
+	return &stream{Stream: s, member: m}, nil
+}
+
+// Close stops health checking and closes every member, returning the first
+// error encountered.
+func (p *Pool) Close() error {
+	p.cancel()
+	<-p.done
+
+	var firstErr error
+	for _, m := range p.members {
+		if err := m.Backend.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// CheckHealth reports the pool healthy while any member is in rotation, so
+// pools can be members of other pools.
+func (p *Pool) CheckHealth(ctx context.Context) error {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, m := range p.members {
+		if m.weight(now, p.config.SlowStart) > 0 {
+			return nil
+		}
+	}
+	return ErrNoHealthyBackends
+}
+
+// MemberStatus describes the state of a member.
+type MemberStatus struct {
+	Name    string
+	Healthy bool
+	// EjectedUntil is set while the member is ejected.
+	EjectedUntil time.Time
+	// Weight is the share of its full traffic the member receives, from 0
+	// while it is out of rotation to 1.
+	Weight      float64
+	Outstanding int64
+}
+
+// Status returns the state of every member, in the order of Config.Members.
+func (p *Pool) Status() []MemberStatus {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := make([]MemberStatus, len(p.members))
+	for i, m := range p.members {
+		status[i] = MemberStatus{
+			Name:        m.Name,
+			Healthy:     m.healthy,
+			Weight:      m.weight(now, p.config.SlowStart),
+			Outstanding: m.outstanding.Load(),
+		}
+		if m.since.After(now) {
+			status[i].EjectedUntil = m.since
+		}
+	}
+	return status
+}
+
+// weight returns the share of its full traffic m receives: 0 while it is
+// unhealthy or ejected, rising from minSlowStartWeight to 1 over slowStart
+// after it rejoins the rotation. Must be called with Pool.mu held.
+func (m *member) weight(now time.Time, slowStart time.Duration) float64 {
+	if !m.healthy || now.Before(m.since) {
+		return 0
+	}
+	elapsed := now.Sub(m.since)
+	if slowStart <= 0 || elapsed >= slowStart {
+		return 1
+	}
+	return max(minSlowStartWeight, float64(elapsed)/float64(slowStart))
+}
+
+// pick chooses a member for req, or returns nil if none is in rotation.
+// Members in slow start are passed over with a probability falling as their
+// weight rises.
+func (p *Pool) pick(req *protokol.Request) *member {
+	now := time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	start := int(p.next.Add(1) % uint64(len(p.members)))
+	switch p.config.Strategy {
+	case LeastRequests:
+		return p.pickLeastRequests(now, start)
+	case ConsistentHash:
+		if p.config.Key != nil {
+			if key := p.config.Key(req); key != "" {
+				return p.pickHash(now, key)
+			}
+		}
+	}
+	return p.pickRoundRobin(now, start)
+}
+
+func (p *Pool) pickRoundRobin(now time.Time, start int) *member {
+	var fallback *member
+	for i := range p.members {
+		m := p.members[(start+i)%len(p.members)]
+		w := m.weight(now, p.config.SlowStart)
+		if w == 0 {
+			continue
+		}
+		if w == 1 || rand.Float64() < w {
+			return m
+		}
+		if fallback == nil {
+			fallback = m
+		}
+	}
+	return fallback
+}
+
+// pickLeastRequests chooses the member with the fewest outstanding requests
+// relative to its weight. Scanning from start spreads ties.
+func (p *Pool) pickLeastRequests(now time.Time, start int) *member {
+	var best *member
+	var bestLoad float64
+	for i := range p.members {
+		m := p.members[(start+i)%len(p.members)]
+		w := m.weight(now, p.config.SlowStart)
+		if w == 0 {
+			continue
+		}
+		load := float64(m.outstanding.Load()+1) / w
+		if best == nil || load < bestLoad {
+			best, bestLoad = m, load
+		}
+	}
+	return best
+}
+
+// pickHash walks the ring from the key's position to the first member in
+// rotation, so keys of a member out of rotation move to the next members on
+// the ring while other keys stay in place.
+func (p *Pool) pickHash(now time.Time, key string) *member {
+	h := hash(key)
+	i, _ := slices.BinarySearchFunc(p.ring, h, func(n ringNode, h uint64) int {
+		switch {
+		case n.hash < h:
+			return -1
+		case n.hash > h:
+			return 1
+		}
+		return 0
+	})
+	var fallback *member
+	for j := range p.ring {
+		m := p.ring[(i+j)%len(p.ring)].member
+		w := m.weight(now, p.config.SlowStart)
+		if w == 0 {
+			continue
+		}
+		if w == 1 || rand.Float64() < w {
+			return m
+		}
+		if fallback == nil {
+			fallback = m
+		}
+	}
+	return fallback
+}
+
+func (p *Pool) buildRing() {
+	p.ring = make([]ringNode, 0, len(p.members)*virtualNodes)
+	for _, m := range p.members {
+		for i := range virtualNodes {
+			p.ring = append(p.ring, ringNode{hash: hash(m.Name + "#" + strconv.Itoa(i)), member: m})
+		}
+	}
+	slices.SortFunc(p.ring, func(a, b ringNode) int {
+		switch {
+		case a.hash < b.hash:
+			return -1
+		case a.hash > b.hash:
+			return 1
+		}
+		return 0
+	})
+}
+
+// hash returns the FNV-1a hash of s, mixed with the SplitMix64 finalizer
+// to spread similar strings such as ring node names over the ring.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// stream releases its member's outstanding request when closed.
+type stream struct {
+	protokol.Stream
+	member *member
+	once   sync.Once
+}
+
+// CloseSend half-closes the underlying stream if it supports it.
+func (s *stream) CloseSend() error {
+	if cs, ok := s.Stream.(protokol.CloseSender); ok {
+		return cs.CloseSend()
+	}
+	return nil
+}
+
+func (s *stream) Close() error {
+	err := s.Stream.Close()
+	s.once.Do(func() { s.member.outstanding.Add(-1) })
+	return err
+}
@@ -0,0 +1,395 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jekabolt/protokol"
+)
+
+var quiet = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// fake is a member answering calls with its name, or failing with err.
+type fake struct {
+	name     string
+	err      error
+	closeErr error
+	closed   bool
+}
+
+func (f *fake) Call(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &protokol.Response{Output: map[string]any{"member": f.name}}, nil
+}
+
+func (f *fake) Stream(ctx context.Context, req *protokol.Request) (protokol.Stream, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return emptyStream{}, nil
+}
+
+func (f *fake) Close() error {
+	f.closed = true
+	return f.closeErr
+}
+
+type emptyStream struct{}
+
+func (emptyStream) Send(msg map[string]any) error { return nil }
+func (emptyStream) Recv() (map[string]any, error) { return nil, io.EOF }
+func (emptyStream) Close() error                  { return nil }
+
+// checked is a fake implementing protokol.HealthChecker.
+type checked struct {
+	*fake
+	mu     sync.Mutex
+	health error
+}
+
+func (c *checked) CheckHealth(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.health
+}
+
+func (c *checked) setHealth(err error) {
+	c.mu.Lock()
+	c.health = err
+	c.mu.Unlock()
+}
+
+func members(backends ...*fake) []Member {
+	ms := make([]Member, len(backends))
+	for i, b := range backends {
+		ms[i] = Member{Name: b.name, Backend: b}
+	}
+	return ms
+}
+
+func newPool(t *testing.T, cfg Config) *Pool {
+	t.Helper()
+	cfg.SlowStart = -1
+	cfg.Logger = quiet
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Close() })
+	return p
+}
+
+// served calls p n times and returns the member names that answered, or
+// the error of a failed call.
+func served(p *Pool, n int, input map[string]any) []string {
+	var names []string
+	for range n {
+		resp, err := p.Call(context.Background(), &protokol.Request{Service: "Users", Method: "GetUser", Input: input})
+		if err != nil {
+			names = append(names, protokol.ErrorCode(err).String())
+			continue
+		}
+		names = append(names, resp.Output["member"].(string))
+	}
+	return names
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		members []Member
+		err     string
+	}{
+		{name: "no members", err: "pool: no members"},
+		{name: "member without a name", members: []Member{{Backend: &fake{}}}, err: "pool: member 0 needs a name and a backend"},
+		{name: "member without a backend", members: []Member{{Name: "a"}}, err: "pool: member 0 needs a name and a backend"},
+		{name: "duplicate member", members: members(&fake{name: "a"}, &fake{name: "a"}), err: `pool: duplicate member "a"`},
+		{name: "valid", members: members(&fake{name: "a"}, &fake{name: "b"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(Config{Members: tt.members, Logger: quiet})
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				p.Close()
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("err = %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	p := newPool(t, Config{Members: members(&fake{name: "a"}, &fake{name: "b"}, &fake{name: "c"})})
+
+	count := make(map[string]int)
+	for _, name := range served(p, 6, nil) {
+		count[name]++
+	}
+	if got := fmt.Sprint(count); got != "map[a:2 b:2 c:2]" {
+		t.Errorf("calls per member = %s, want map[a:2 b:2 c:2]", got)
+	}
+}
+
+func TestLeastRequests(t *testing.T) {
+	p := newPool(t, Config{Members: members(&fake{name: "a"}, &fake{name: "b"}), Strategy: LeastRequests})
+
+	stream, err := p.Stream(context.Background(), &protokol.Request{Service: "Users", Method: "WatchUsers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	busy := ""
+	for _, s := range p.Status() {
+		if s.Outstanding == 1 {
+			busy = s.Name
+		}
+	}
+	if busy == "" {
+		t.Fatalf("status = %+v, want a member with an open stream", p.Status())
+	}
+
+	// The open stream holds its slot after Recv returns io.EOF.
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatalf("Recv = %v, want io.EOF", err)
+	}
+	if names := served(p, 4, nil); slices.Contains(names, busy) {
+		t.Errorf("calls served by %v, want none by %s with an open stream", names, busy)
+	}
+
+	stream.Close()
+	stream.Close()
+	for _, s := range p.Status() {
+		if s.Outstanding != 0 {
+			t.Errorf("%s has %d outstanding requests after Close, want 0", s.Name, s.Outstanding)
+		}
+	}
+}
+
+func TestConsistentHash(t *testing.T) {
+	p := newPool(t, Config{
+		Members:  members(&fake{name: "a"}, &fake{name: "b"}, &fake{name: "c"}),
+		Strategy: ConsistentHash,
+		Key: func(req *protokol.Request) string {
+			id, _ := req.Input["user"].(string)
+			return id
+		},
+	})
+
+	used := make(map[string]bool)
+	for i := range 30 {
+		names := served(p, 3, map[string]any{"user": fmt.Sprint("user-", i)})
+		if names[0] != names[1] || names[1] != names[2] {
+			t.Errorf("user-%d served by %v, want a single member", i, names)
+		}
+		used[names[0]] = true
+	}
+	if len(used) != 3 {
+		t.Errorf("keys hashed to %v, want every member", used)
+	}
+
+	// Requests without a key are sent round robin.
+	if names := served(p, 3, nil); len(slices.Compact(slices.Sorted(slices.Values(names)))) != 3 {
+		t.Errorf("requests without a key served by %v, want every member", names)
+	}
+}
+
+func TestOutlier(t *testing.T) {
+	unavailable := protokol.NewError(protokol.CodeUnavailable, "connection refused")
+
+	tests := []struct {
+		name    string
+		errs    []error
+		outlier Outlier
+		calls   int
+		ejected int
+		last    string
+	}{
+		{
+			name:    "failing member ejected",
+			errs:    []error{unavailable, nil},
+			outlier: Outlier{ConsecutiveErrors: 2},
+			calls:   4,
+			ejected: 1,
+			last:    "b",
+		},
+		{
+			name:    "errors that are not failures",
+			errs:    []error{protokol.NewError(protokol.CodeNotFound, "user not found"), nil},
+			outlier: Outlier{ConsecutiveErrors: 2},
+			calls:   8,
+			ejected: 0,
+		},
+		{
+			name:    "custom failures",
+			errs:    []error{protokol.NewError(protokol.CodeNotFound, "user not found"), nil},
+			outlier: Outlier{ConsecutiveErrors: 2, IsFailure: func(ctx context.Context, err error) bool { return err != nil }},
+			calls:   4,
+			ejected: 1,
+			last:    "b",
+		},
+		{
+			name:    "disabled",
+			errs:    []error{unavailable, nil},
+			outlier: Outlier{ConsecutiveErrors: -1},
+			calls:   8,
+			ejected: 0,
+		},
+		{
+			name:    "limited by MaxEjectionPercent",
+			errs:    []error{unavailable, unavailable, unavailable, unavailable},
+			outlier: Outlier{ConsecutiveErrors: 1, MaxEjectionPercent: 50},
+			calls:   8,
+			ejected: 2,
+		},
+		{
+			name:    "single member ejected",
+			errs:    []error{unavailable},
+			outlier: Outlier{ConsecutiveErrors: 1},
+			calls:   1,
+			ejected: 1,
+			last:    "UNAVAILABLE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var backends []*fake
+			for i, err := range tt.errs {
+				backends = append(backends, &fake{name: string(rune('a' + i)), err: err})
+			}
+			p := newPool(t, Config{Members: members(backends...), Outlier: tt.outlier})
+
+			served(p, tt.calls, nil)
+			ejected := 0
+			for _, s := range p.Status() {
+				if !s.EjectedUntil.IsZero() {
+					ejected++
+					if s.Weight != 0 {
+						t.Errorf("ejected %s has weight %v, want 0", s.Name, s.Weight)
+					}
+				}
+			}
+			if ejected != tt.ejected {
+				t.Errorf("%d members ejected, want %d", ejected, tt.ejected)
+			}
+			if tt.last == "" {
+				return
+			}
+			for _, name := range served(p, 2, nil) {
+				if name != tt.last {
+					t.Errorf("call served by %s after ejection, want %s", name, tt.last)
+				}
+			}
+		})
+	}
+}
+
+func TestIsFailure(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"success", context.Background(), nil, false},
+		{"unavailable", context.Background(), protokol.NewError(protokol.CodeUnavailable, ""), true},
+		{"plain error", context.Background(), errors.New("boom"), true},
+		{"deadline exceeded", context.Background(), context.DeadlineExceeded, true},
+		{"client error", context.Background(), protokol.NewError(protokol.CodeInvalidArgument, ""), false},
+		{"caller canceled", canceled, protokol.NewError(protokol.CodeUnavailable, ""), false},
+	}
+	for _, tt := range tests {
+		if got := isFailure(tt.ctx, tt.err); got != tt.want {
+			t.Errorf("%s: isFailure = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	a := &checked{fake: &fake{name: "a"}}
+	b := &checked{fake: &fake{name: "b"}}
+	p := newPool(t, Config{
+		Members: []Member{{Name: "a", Backend: a}, {Name: "b", Backend: b}},
+		HealthCheck: HealthCheck{
+			Interval:           5 * time.Millisecond,
+			UnhealthyThreshold: 1,
+			HealthyThreshold:   1,
+		},
+	})
+
+	// waitFor polls until the members' health matches want.
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			var got []string
+			for _, s := range p.Status() {
+				got = append(got, fmt.Sprintf("%s:%v", s.Name, s.Healthy))
+			}
+			if fmt.Sprint(got) == want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("health = %v, want %s", got, want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	a.setHealth(errors.New("not serving"))
+	waitFor("[a:false b:true]")
+	for _, name := range served(p, 4, nil) {
+		if name != "b" {
+			t.Errorf("call served by %s, want b", name)
+		}
+	}
+
+	b.setHealth(errors.New("not serving"))
+	waitFor("[a:false b:false]")
+	if err := p.CheckHealth(context.Background()); err != ErrNoHealthyBackends {
+		t.Errorf("CheckHealth = %v, want ErrNoHealthyBackends", err)
+	}
+	_, err := p.Call(context.Background(), &protokol.Request{Service: "Users", Method: "GetUser"})
+	if err != ErrNoHealthyBackends || protokol.ErrorCode(err) != protokol.CodeUnavailable {
+		t.Errorf("Call = %v, want ErrNoHealthyBackends", err)
+	}
+	if _, err := p.Stream(context.Background(), &protokol.Request{Service: "Users", Method: "WatchUsers"}); err != ErrNoHealthyBackends {
+		t.Errorf("Stream = %v, want ErrNoHealthyBackends", err)
+	}
+
+	a.setHealth(nil)
+	waitFor("[a:true b:false]")
+	if err := p.CheckHealth(context.Background()); err != nil {
+		t.Errorf("CheckHealth = %v, want nil", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	errClose := errors.New("close failed")
+	a, b, c := &fake{name: "a"}, &fake{name: "b", closeErr: errClose}, &fake{name: "c", closeErr: errors.New("other")}
+	p, err := New(Config{Members: members(a, b, c), Logger: quiet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != errClose {
+		t.Errorf("Close = %v, want the first member error", err)
+	}
+	if !a.closed || !b.closed || !c.closed {
+		t.Errorf("closed = %v %v %v, want every member closed", a.closed, b.closed, c.closed)
+	}
+}
@@ -0,0 +1,125 @@
+// Package router provides a backend that routes each request to one of the
+// backends in a registry, by method, by metadata header or by a custom
+// function. It supports moving a service to a new backend one method at a
+// time, or sending selected clients to a canary.
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/jekabolt/protokol"
+)
+
+// RouteFunc picks the backend for a request. It returns the name of a
+// backend in the registry, or "" to leave the request to the next rule.
+type RouteFunc func(ctx context.Context, req *protokol.Request) string
+
+// Router is a backend forwarding every request to a backend from a
+// registry, chosen by the first matching rule or the fallback. Backends are
+// looked up by name on each request, so they may be registered after the
+// router. Rules must be added before the router serves requests.
+type Router struct {
+	backends *protokol.BackendRegistry
+	fallback string
+	rules    []RouteFunc
+}
+
+// New creates a router over backends. Requests matching no rule go to the
+// fallback backend; with an empty fallback they fail with
+// protokol.ErrBackendNotFound.
+func New(backends *protokol.BackendRegistry, fallback string) *Router {
+	return &Router{backends: backends, fallback: fallback}
+}
+
+// Method routes a method of a service to backend. An empty method matches
+// every method of the service.
+func (r *Router) Method(service, method, backend string) {
+	r.Func(func(_ context.Context, req *protokol.Request) string {
+		if req.Service == service && (method == "" || req.Method == method) {
+			return backend
+		}
+		return ""
+	})
+}
+
+// Header routes requests whose metadata header name has the given value to
+// backend. Header names are matched case-insensitively.
+func (r *Router) Header(name, value, backend string) {
+	r.Func(func(_ context.Context, req *protokol.Request) string {
+		if slices.Contains(metadataValues(req.Metadata, name), value) {
+			return backend
+		}
+		return ""
+	})
+}
+
+// Func adds a custom rule.
+func (r *Router) Func(fn RouteFunc) {
+	r.rules = append(r.rules, fn)
+}
+
+// Route returns the name of the backend for req.
+func (r *Router) Route(ctx context.Context, req *protokol.Request) string {
+	for _, rule := range r.rules {
+		if name := rule(ctx, req); name != "" {
+			return name
+		}
+	}
+	return r.fallback
+}
+
+// Call forwards the request to the backend chosen by Route.
+func (r *Router) Call(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+	b, err := r.backend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return b.Call(ctx, req)
+}
+
+// Stream opens the stream on the backend chosen by Route.
+func (r *Router) Stream(ctx context.Context, req *protokol.Request) (protokol.Stream, error) {
+	b, err := r.backend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return b.Stream(ctx, req)
+}
+
+// Close is a no-op; the routed backends are closed by their registry.
+func (r *Router) Close() error {
+	return nil
+}
+
+func (r *Router) backend(ctx context.Context, req *protokol.Request) (protokol.Backend, error) {
+	name := r.Route(ctx, req)
+	if name == "" {
+		return nil, fmt.Errorf("%w: no route for %s.%s", protokol.ErrBackendNotFound, req.Service, req.Method)
+	}
+	b, ok := r.backends.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", protokol.ErrBackendNotFound, name)
+	}
+	if b == protokol.Backend(r) {
+		return nil, fmt.Errorf("router: %s.%s is routed to the router itself", req.Service, req.Method)
+	}
+	return b, nil
+}
+
+// metadataValues looks up a header in request metadata, whose keys are
+// canonical for HTTP adapters but may differ in case for others.
+func metadataValues(md map[string][]string, name string) []string {
+	if v, ok := md[http.CanonicalHeaderKey(name)]; ok {
+		return v
+	}
+	for k, v := range md {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/backend"
+)
+
+// named returns a backend answering every call with its name.
+func named(name string) *backend.Handler {
+	h := backend.NewHandler()
+	reply := func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		return map[string]any{"backend": name}, nil
+	}
+	h.Register("Users", "GetUser", reply)
+	h.Register("Users", "DeleteUser", reply)
+	h.Register("Orders", "GetOrder", reply)
+	h.RegisterServerStream("Users", "WatchUsers", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		return send(map[string]any{"backend": name})
+	})
+	return h
+}
+
+func TestRouter(t *testing.T) {
+	reg := protokol.NewBackendRegistry()
+	for _, name := range []string{"legacy", "users", "canary"} {
+		reg.Register(name, named(name))
+	}
+	r := New(reg, "legacy")
+	r.Header("X-Canary", "1", "canary")
+	r.Method("Users", "GetUser", "users")
+	r.Method("Orders", "", "missing")
+	r.Func(func(ctx context.Context, req *protokol.Request) string {
+		if req.Method == "DeleteUser" && req.Input["id"] == "self" {
+			return "router"
+		}
+		return ""
+	})
+	reg.Register("router", r)
+
+	tests := []struct {
+		name     string
+		service  string
+		method   string
+		metadata map[string][]string
+		input    map[string]any
+		want     string
+		err      error
+		loop     bool
+	}{
+		{name: "method rule", service: "Users", method: "GetUser", want: "users"},
+		{name: "fallback", service: "Users", method: "DeleteUser", want: "legacy"},
+		{name: "header rule first", service: "Users", method: "GetUser", metadata: map[string][]string{"X-Canary": {"1"}}, want: "canary"},
+		{name: "header in another case", service: "Users", method: "GetUser", metadata: map[string][]string{"x-canary": {"1"}}, want: "canary"},
+		{name: "header with another value", service: "Users", method: "GetUser", metadata: map[string][]string{"X-Canary": {"0"}}, want: "users"},
+		{name: "unregistered backend", service: "Orders", method: "GetOrder", err: protokol.ErrBackendNotFound},
+		{name: "routed to itself", service: "Users", method: "DeleteUser", input: map[string]any{"id": "self"}, loop: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := r.Call(context.Background(), &protokol.Request{
+				Service:  tt.service,
+				Method:   tt.method,
+				Metadata: tt.metadata,
+				Input:    tt.input,
+			})
+			switch {
+			case tt.loop:
+				if err == nil || errors.Is(err, protokol.ErrBackendNotFound) {
+					t.Errorf("err = %v, want a routing loop error", err)
+				}
+			case tt.err != nil:
+				if !errors.Is(err, tt.err) {
+					t.Errorf("err = %v, want %v", err, tt.err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case resp.Output["backend"] != tt.want:
+				t.Errorf("backend = %v, want %s", resp.Output["backend"], tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterWithoutFallback(t *testing.T) {
+	r := New(protokol.NewBackendRegistry(), "")
+	_, err := r.Call(context.Background(), &protokol.Request{Service: "Users", Method: "GetUser"})
+	if !errors.Is(err, protokol.ErrBackendNotFound) || protokol.ErrorCode(err) != protokol.CodeInternal {
+		t.Errorf("err = %v, want ErrBackendNotFound", err)
+	}
+}
+
+func TestRouterStream(t *testing.T) {
+	reg := protokol.NewBackendRegistry()
+	reg.Register("legacy", named("legacy"))
+	reg.Register("users", named("users"))
+	r := New(reg, "legacy")
+	r.Method("Users", "", "users")
+
+	stream, err := r.Stream(context.Background(), &protokol.Request{Service: "Users", Method: "WatchUsers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	msg, err := stream.Recv()
+	if err != nil || msg["backend"] != "users" {
+		t.Fatalf("Recv = %v, %v", msg, err)
+	}
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Errorf("stream ended with %v, want io.EOF", err)
+	}
+}
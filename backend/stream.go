@@ -0,0 +1,195 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/jekabolt/protokol"
+)
+
+// ServerStreamFunc handles a server-streaming method. It receives the single
+// request input and calls send for every response message; returning ends
+// the stream.
+type ServerStreamFunc func(ctx context.Context, input map[string]any, send func(map[string]any) error) error
+
+// ClientStreamFunc handles a client-streaming method. It calls recv for
+// every request message until recv returns io.EOF, when the client has
+// finished sending, and returns the single response.
+type ClientStreamFunc func(ctx context.Context, recv func() (map[string]any, error)) (map[string]any, error)
+
+// BidiStreamFunc handles a bidirectional streaming method. stream.Recv
+// returns request messages until io.EOF, when the client has finished
+// sending, and stream.Send sends response messages; returning ends the
+// stream.
+type BidiStreamFunc func(ctx context.Context, stream protokol.Stream) error
+
+// streamFunc is the common form of the streaming handlers.
+type streamFunc func(ctx context.Context, input map[string]any, stream protokol.Stream) error
+
+// RegisterServerStream adds a server-streaming handler for the given service
+// and method.
+func (h *Handler) RegisterServerStream(service, method string, fn ServerStreamFunc) {
+	h.registerStream(service, method, func(ctx context.Context, input map[string]any, stream protokol.Stream) error {
+		return fn(ctx, input, stream.Send)
+	})
+}
+
+// RegisterClientStream adds a client-streaming handler for the given service
+// and method.
+func (h *Handler) RegisterClientStream(service, method string, fn ClientStreamFunc) {
+	h.registerStream(service, method, func(ctx context.Context, _ map[string]any, stream protokol.Stream) error {
+		output, err := fn(ctx, stream.Recv)
+		if err != nil {
+			return err
+		}
+		return stream.Send(output)
+	})
+}
+
+// RegisterBidirectional adds a bidirectional streaming handler for the given
+// service and method.
+func (h *Handler) RegisterBidirectional(service, method string, fn BidiStreamFunc) {
+	h.registerStream(service, method, func(ctx context.Context, _ map[string]any, stream protokol.Stream) error {
+		return fn(ctx, stream)
+	})
+}
+
+func (h *Handler) registerStream(service, method string, fn streamFunc) {
+	if h.streams[service] == nil {
+		h.streams[service] = make(map[string]streamFunc)
+	}
+	h.streams[service][method] = fn
+}
+
+// pipe connects an adapter to a streaming handler running in its own
+// goroutine. Messages are passed over unbuffered channels, so either side
+// blocks until the other is ready to receive, which propagates backpressure.
+type pipe struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	in  chan map[string]any // adapter to handler
+	out chan map[string]any // handler to adapter
+
+	// sendClosed is closed when the adapter half-closes the stream.
+	sendClosed    chan struct{}
+	closeSendOnce sync.Once
+
+	// done is closed when the handler returns, after err is set.
+	done chan struct{}
+	err  error
+}
+
+// startStream runs fn in a new goroutine and returns the adapter's end of
+// the stream. The handler's context is cancelled when ctx is done or the
+// stream is closed.
+func startStream(ctx context.Context, input map[string]any, fn streamFunc) protokol.Stream {
+	ctx, cancel := context.WithCancel(ctx)
+	p := &pipe{
+		ctx:        ctx,
+		cancel:     cancel,
+		in:         make(chan map[string]any),
+		out:        make(chan map[string]any),
+		sendClosed: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+		defer func() {
+			if r := recover(); r != nil {
+				p.err = fmt.Errorf("backend: stream handler panic: %v", r)
+			}
+		}()
+		p.err = fn(ctx, input, (*handlerStream)(p))
+	}()
+	return (*adapterStream)(p)
+}
+
+// result returns the handler's error, or io.EOF if it succeeded.
+func (p *pipe) result() error {
+	if p.err != nil {
+		return p.err
+	}
+	return io.EOF
+}
+
+// adapterStream is the adapter's end of a pipe.
+type adapterStream pipe
+
+// Send passes msg to the handler, blocking until the handler receives it.
+// After the handler returns, Send reports its error, or io.EOF.
+func (s *adapterStream) Send(msg map[string]any) error {
+	select {
+	case <-s.sendClosed:
+		return errors.New("backend: send on half-closed stream")
+	default:
+	}
+	select {
+	case s.in <- msg:
+		return nil
+	case <-s.done:
+		return (*pipe)(s).result()
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Recv returns the next message sent by the handler. After the handler
+// returns, Recv reports its error, or io.EOF.
+func (s *adapterStream) Recv() (map[string]any, error) {
+	select {
+	case msg := <-s.out:
+		return msg, nil
+	case <-s.done:
+		return nil, (*pipe)(s).result()
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// CloseSend tells the handler that no more messages will be sent; its Recv
+// returns io.EOF.
+func (s *adapterStream) CloseSend() error {
+	s.closeSendOnce.Do(func() { close(s.sendClosed) })
+	return nil
+}
+
+// Close cancels the handler's context.
+func (s *adapterStream) Close() error {
+	s.cancel()
+	return nil
+}
+
+// handlerStream is the handler's end of a pipe.
+type handlerStream pipe
+
+// Send passes msg to the adapter, blocking until the adapter receives it.
+func (s *handlerStream) Send(msg map[string]any) error {
+	select {
+	case s.out <- msg:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// Recv returns the next message sent by the adapter, or io.EOF once the
+// adapter has half-closed the stream.
+func (s *handlerStream) Recv() (map[string]any, error) {
+	select {
+	case msg := <-s.in:
+		return msg, nil
+	case <-s.sendClosed:
+		return nil, io.EOF
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+// Close is a no-op; the handler ends the stream by returning.
+func (s *handlerStream) Close() error {
+	return nil
+}
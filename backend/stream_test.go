@@ -0,0 +1,209 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/jekabolt/protokol"
+)
+
+func streamHandler() *Handler {
+	h := NewHandler()
+	h.Register("Users", "GetUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		return input, nil
+	})
+	h.RegisterServerStream("Users", "ListUsers", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		for i := range 3 {
+			if err := send(map[string]any{"id": i}); err != nil {
+				return err
+			}
+		}
+		if input["fail"] == true {
+			return protokol.NewError(protokol.CodeAborted, "list aborted")
+		}
+		return nil
+	})
+	h.RegisterClientStream("Users", "CountUsers", func(ctx context.Context, recv func() (map[string]any, error)) (map[string]any, error) {
+		n := 0
+		for {
+			_, err := recv()
+			if err == io.EOF {
+				return map[string]any{"count": n}, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			n++
+		}
+	})
+	h.RegisterBidirectional("Users", "Echo", func(ctx context.Context, stream protokol.Stream) error {
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+	})
+	h.RegisterServerStream("Users", "Panic", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		panic("boom")
+	})
+	h.RegisterServerStream("Users", "Wait", func(ctx context.Context, input map[string]any, send func(map[string]any) error) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	return h
+}
+
+func TestStream(t *testing.T) {
+	h := streamHandler()
+
+	tests := []struct {
+		name   string
+		method string
+		input  map[string]any
+		send   []map[string]any
+		want   string
+		err    string
+	}{
+		{
+			name:   "server stream ends with EOF",
+			method: "ListUsers",
+			want:   "[map[id:0] map[id:1] map[id:2]]",
+		},
+		{
+			name:   "server stream error after messages",
+			method: "ListUsers",
+			input:  map[string]any{"fail": true},
+			want:   "[map[id:0] map[id:1] map[id:2]]",
+			err:    "list aborted",
+		},
+		{
+			name:   "client stream replies after CloseSend",
+			method: "CountUsers",
+			send:   []map[string]any{{}, {}},
+			want:   "[map[count:2]]",
+		},
+		{
+			name:   "bidirectional stream",
+			method: "Echo",
+			send:   []map[string]any{{"id": 1}, {"id": 2}},
+			want:   "[map[id:1] map[id:2]]",
+		},
+		{
+			name:   "handler panic",
+			method: "Panic",
+			want:   "[]",
+			err:    "backend: stream handler panic: boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := h.Stream(context.Background(), &protokol.Request{Service: "Users", Method: tt.method, Input: tt.input})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer stream.Close()
+
+			// Bidirectional handlers reply to each message before reading
+			// the next, so receive concurrently.
+			go func() {
+				for _, msg := range tt.send {
+					if err := stream.Send(msg); err != nil {
+						t.Error(err)
+						return
+					}
+				}
+				stream.(protokol.CloseSender).CloseSend()
+			}()
+
+			got := []map[string]any{}
+			var recvErr error
+			for {
+				msg, err := stream.Recv()
+				if err != nil {
+					recvErr = err
+					break
+				}
+				got = append(got, msg)
+			}
+			if s := fmt.Sprint(got); s != tt.want {
+				t.Errorf("received %s, want %s", s, tt.want)
+			}
+			switch {
+			case tt.err == "" && recvErr != io.EOF:
+				t.Errorf("stream ended with %v, want io.EOF", recvErr)
+			case tt.err != "" && (recvErr == nil || recvErr.Error() != tt.err):
+				t.Errorf("stream ended with %v, want %s", recvErr, tt.err)
+			}
+		})
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	h := streamHandler()
+	stream, err := h.Stream(context.Background(), &protokol.Request{Service: "Users", Method: "Wait"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := stream.Recv()
+		done <- err
+	}()
+	stream.Close()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Recv after Close = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Recv did not return after Close")
+	}
+
+	if err := stream.(protokol.CloseSender).CloseSend(); err != nil {
+		t.Errorf("CloseSend after Close = %v", err)
+	}
+}
+
+func TestStreamLookup(t *testing.T) {
+	h := streamHandler()
+
+	tests := []struct {
+		service string
+		method  string
+		want    error
+	}{
+		{"Users", "GetUser", protokol.ErrStreamingNotSupported},
+		{"Users", "DeleteUser", protokol.ErrMethodNotFound},
+		{"Orders", "ListOrders", protokol.ErrServiceNotFound},
+	}
+	for _, tt := range tests {
+		_, err := h.Stream(context.Background(), &protokol.Request{Service: tt.service, Method: tt.method})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Stream(%s.%s) = %v, want %v", tt.service, tt.method, err, tt.want)
+		}
+	}
+}
+
+func TestStreamSendAfterCloseSend(t *testing.T) {
+	h := streamHandler()
+	stream, err := h.Stream(context.Background(), &protokol.Request{Service: "Users", Method: "Echo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	stream.(protokol.CloseSender).CloseSend()
+	if err := stream.Send(map[string]any{}); err == nil {
+		t.Error("Send after CloseSend succeeded")
+	}
+}
@@ -0,0 +1,58 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jekabolt/protokol"
+)
+
+// TypedFunc is the signature for handlers registered with RegisterTyped.
+type TypedFunc[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+// RegisterTyped adds a handler that works on Go values instead of maps. The
+// request input is decoded into In and the returned Out is encoded as the
+// response output, both following encoding/json and its struct tags, so the
+// structs may be the ones passed to schema.FromStruct. Input that cannot be
+// decoded into In is rejected with an error matching
+// protokol.ErrInvalidArgument, which wraps the decoding error without
+// sending it to the client.
+func RegisterTyped[In, Out any](h *Handler, service, method string, fn TypedFunc[In, Out]) {
+	h.Register(service, method, func(ctx context.Context, input map[string]any) (map[string]any, error) {
+		var in In
+		if err := decode(input, &in); err != nil {
+			return nil, protokol.Errorf(protokol.CodeInvalidArgument, "invalid input", protokol.ErrInvalidArgument, err)
+		}
+		out, err := fn(ctx, in)
+		if err != nil {
+			return nil, err
+		}
+		return encode(out)
+	})
+}
+
+func decode(input map[string]any, dst any) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// encode converts v to a map. Numbers are kept as json.Number so that 64-bit
+// integers do not lose precision.
+func encode(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode output: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var out map[string]any
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("encode output: output must be a JSON object: %w", err)
+	}
+	return out, nil
+}
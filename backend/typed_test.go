@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+)
+
+type getUserRequest struct {
+	ID    int64  `json:"id"`
+	Fetch bool   `json:"fetch,omitempty"`
+	Note  string `json:"-"`
+}
+
+type user struct {
+	ID   int64    `json:"id"`
+	Name string   `json:"name"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+func TestRegisterTyped(t *testing.T) {
+	h := NewHandler()
+	RegisterTyped(h, "Users", "GetUser", func(ctx context.Context, in getUserRequest) (user, error) {
+		if in.ID == 0 {
+			return user{}, protokol.NewError(protokol.CodeNotFound, "user not found")
+		}
+		return user{ID: in.ID, Name: "ada"}, nil
+	})
+	RegisterTyped(h, "Users", "Scalar", func(ctx context.Context, in getUserRequest) (int, error) {
+		return 1, nil
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		input  map[string]any
+		want   string
+		code   protokol.Code
+	}{
+		{
+			name:   "decoded and encoded with json tags",
+			method: "GetUser",
+			input:  map[string]any{"id": float64(7), "extra": true},
+			want:   "map[id:7 name:ada]",
+		},
+		{
+			name:   "large integers keep their precision",
+			method: "GetUser",
+			input:  map[string]any{"id": int64(1) << 60},
+			want:   "map[id:1152921504606846976 name:ada]",
+		},
+		{
+			name:   "handler error",
+			method: "GetUser",
+			input:  map[string]any{},
+			code:   protokol.CodeNotFound,
+		},
+		{
+			name:   "input that does not decode",
+			method: "GetUser",
+			input:  map[string]any{"id": "seven"},
+			code:   protokol.CodeInvalidArgument,
+		},
+		{
+			name:   "output that is not an object",
+			method: "Scalar",
+			input:  map[string]any{},
+			code:   protokol.CodeInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := h.Call(context.Background(), &protokol.Request{Service: "Users", Method: tt.method, Input: tt.input})
+			if got := protokol.ErrorCode(err); got != tt.code {
+				t.Fatalf("code = %v, want %v (%v)", got, tt.code, err)
+			}
+			if err != nil {
+				return
+			}
+			if got := fmt.Sprint(resp.Output); got != tt.want {
+				t.Errorf("output = %s, want %s", got, tt.want)
+			}
+			if _, ok := resp.Output["id"].(json.Number); !ok {
+				t.Errorf("id = %T, want json.Number", resp.Output["id"])
+			}
+		})
+	}
+}
+
+func TestRegisterTypedInvalidInput(t *testing.T) {
+	h := NewHandler()
+	RegisterTyped(h, "Users", "GetUser", func(ctx context.Context, in getUserRequest) (user, error) {
+		return user{}, nil
+	})
+	_, err := h.Call(context.Background(), &protokol.Request{Service: "Users", Method: "GetUser", Input: map[string]any{"id": "seven"}})
+	if !errors.Is(err, protokol.ErrInvalidArgument) {
+		t.Errorf("err = %v, want ErrInvalidArgument", err)
+	}
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Errorf("err = %v, want the decoding error as its cause", err)
+	}
+	if e := protokol.AsError(err); e == nil || e.Message != "invalid input" {
+		t.Errorf("public message = %v, want invalid input", e)
+	}
+}
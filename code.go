@@ -0,0 +1,131 @@
+package protokol
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// Code is a canonical error code. The values and meanings match the gRPC
+// status codes, so every adapter can report an error the same way.
+type Code int
+
+// Canonical error codes.
+const (
+	CodeOK                 Code = 0  // Not an error.
+	CodeCanceled           Code = 1  // The caller cancelled the request.
+	CodeUnknown            Code = 2  // An error without more information.
+	CodeInvalidArgument    Code = 3  // The request input is invalid.
+	CodeDeadlineExceeded   Code = 4  // The deadline expired before the request completed.
+	CodeNotFound           Code = 5  // A requested entity was not found.
+	CodeAlreadyExists      Code = 6  // An entity the request tried to create already exists.
+	CodePermissionDenied   Code = 7  // The caller may not perform the request.
+	CodeResourceExhausted  Code = 8  // A quota or rate limit was exceeded.
+	CodeFailedPrecondition Code = 9  // The system is not in a state required for the request.
+	CodeAborted            Code = 10 // The request was aborted, e.g. by a concurrency conflict.
+	CodeOutOfRange         Code = 11 // The request went past the valid range.
+	CodeUnimplemented      Code = 12 // The request is not implemented or supported.
+	CodeInternal           Code = 13 // An internal invariant was broken.
+	CodeUnavailable        Code = 14 // The service is unavailable; the request may be retried.
+	CodeDataLoss           Code = 15 // Unrecoverable data loss or corruption.
+	CodeUnauthenticated    Code = 16 // The request has no valid credentials.
+)
+
+var codeNames = [...]string{
+	CodeOK:                 "OK",
+	CodeCanceled:           "CANCELLED",
+	CodeUnknown:            "UNKNOWN",
+	CodeInvalidArgument:    "INVALID_ARGUMENT",
+	CodeDeadlineExceeded:   "DEADLINE_EXCEEDED",
+	CodeNotFound:           "NOT_FOUND",
+	CodeAlreadyExists:      "ALREADY_EXISTS",
+	CodePermissionDenied:   "PERMISSION_DENIED",
+	CodeResourceExhausted:  "RESOURCE_EXHAUSTED",
+	CodeFailedPrecondition: "FAILED_PRECONDITION",
+	CodeAborted:            "ABORTED",
+	CodeOutOfRange:         "OUT_OF_RANGE",
+	CodeUnimplemented:      "UNIMPLEMENTED",
+	CodeInternal:           "INTERNAL",
+	CodeUnavailable:        "UNAVAILABLE",
+	CodeDataLoss:           "DATA_LOSS",
+	CodeUnauthenticated:    "UNAUTHENTICATED",
+}
+
+// String returns the code's name as used by gRPC, e.g. "NOT_FOUND".
+func (c Code) String() string {
+	if c >= 0 && int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return "CODE(" + strconv.Itoa(int(c)) + ")"
+}
+
+// HTTPStatus returns the HTTP status code for c, following the mapping of
+// google.rpc.Code. Unknown codes map to 500.
+func (c Code) HTTPStatus() int {
+	switch c {
+	case CodeOK:
+		return http.StatusOK
+	case CodeCanceled:
+		return 499 // Client Closed Request
+	case CodeInvalidArgument, CodeFailedPrecondition, CodeOutOfRange:
+		return http.StatusBadRequest
+	case CodeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeAlreadyExists, CodeAborted:
+		return http.StatusConflict
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	case CodeUnimplemented:
+		return http.StatusNotImplemented
+	case CodeUnavailable:
+		return http.StatusServiceUnavailable
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CodeFromHTTPStatus returns the code for an HTTP error status, for backends
+// calling HTTP services. Statuses without a specific code map to
+// CodeFailedPrecondition for other 4xx, CodeInternal for other 5xx and
+// CodeUnknown otherwise.
+func CodeFromHTTPStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeAlreadyExists
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return CodeDeadlineExceeded
+	case http.StatusPreconditionFailed:
+		return CodeFailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return CodeOutOfRange
+	case http.StatusTooManyRequests:
+		return CodeResourceExhausted
+	case 499:
+		return CodeCanceled
+	case http.StatusNotImplemented:
+		return CodeUnimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return CodeUnavailable
+	}
+	switch {
+	case status >= 400 && status < 500:
+		return CodeFailedPrecondition
+	case status >= 500 && status < 600:
+		return CodeInternal
+	default:
+		return CodeUnknown
+	}
+}
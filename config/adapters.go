@@ -0,0 +1,153 @@
+package config
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/adapters/graphql"
+	"github.com/jekabolt/protokol/adapters/grpc"
+	"github.com/jekabolt/protokol/adapters/jsonrpc"
+	"github.com/jekabolt/protokol/adapters/rest"
+	"github.com/jekabolt/protokol/adapters/websocket"
+)
+
+// adapterKinds lists the values of an adapter's "type" key.
+var adapterKinds = map[string]bool{
+	"rest":      true,
+	"grpc":      true,
+	"graphql":   true,
+	"websocket": true,
+	"jsonrpc":   true,
+}
+
+// adapters creates every entry of the adapters section and adds it to the
+// instance.
+func (l *loader) adapters(n *yaml.Node) error {
+	return l.sequence(n, func(item *yaml.Node) error {
+		a, err := l.adapter(item)
+		if err != nil {
+			return err
+		}
+		l.p.AddAdapter(a)
+		return nil
+	})
+}
+
+func (l *loader) adapter(n *yaml.Node) (protokol.Adapter, error) {
+	if err := l.require(n, "type"); err != nil {
+		return nil, err
+	}
+	var kind string
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "type" {
+			if err := l.str(n.Content[i+1], &kind); err != nil {
+				return nil, err
+			}
+			if !adapterKinds[kind] {
+				return nil, l.errorf(n.Content[i+1], "unknown adapter type %q, expected one of %s", kind, keyList(adapterKinds))
+			}
+		}
+	}
+
+	var (
+		common = adapters.Config{
+			Schema:      l.p.Schema(),
+			Backends:    l.p.Backends(),
+			ErrorMapper: l.opts.ErrorMapper,
+			ErrorLogger: l.opts.Logger,
+		}
+		listen string
+		path   string
+
+		restCfg rest.Config
+		grpcCfg grpc.Config
+		wsCfg   websocket.Config
+		rpcCfg  jsonrpc.Config
+	)
+	fields := map[string]func(*yaml.Node) error{
+		"type":   func(v *yaml.Node) error { return nil },
+		"listen": func(v *yaml.Node) error { return l.str(v, &listen) },
+		"middleware": func(v *yaml.Node) error {
+			var err error
+			common.Middleware, err = l.middlewareRef(v)
+			return err
+		},
+		"skip_validation": func(v *yaml.Node) error { return l.boolean(v, &common.SkipValidation) },
+		"output_defaults": func(v *yaml.Node) error { return l.boolean(v, &common.OutputDefaults) },
+		"debug_errors":    func(v *yaml.Node) error { return l.boolean(v, &common.DebugErrors) },
+	}
+
+	switch kind {
+	case "rest":
+		fields["prefix"] = func(v *yaml.Node) error { return l.str(v, &restCfg.PathPrefix) }
+		fields["openapi"] = func(v *yaml.Node) error { return l.openAPI(v, &restCfg.OpenAPI) }
+	case "grpc":
+		fields["reflection"] = func(v *yaml.Node) error { return l.boolean(v, &grpcCfg.Reflection) }
+	case "graphql":
+		fields["path"] = func(v *yaml.Node) error { return l.str(v, &path) }
+	case "websocket":
+		fields["path"] = func(v *yaml.Node) error { return l.str(v, &path) }
+		fields["ping_interval"] = func(v *yaml.Node) error { return l.duration(v, &wsCfg.PingInterval) }
+		fields["pong_timeout"] = func(v *yaml.Node) error { return l.duration(v, &wsCfg.PongTimeout) }
+		fields["write_timeout"] = func(v *yaml.Node) error { return l.duration(v, &wsCfg.WriteTimeout) }
+		fields["max_message_size"] = func(v *yaml.Node) error {
+			var size int
+			err := l.integer(v, &size)
+			wsCfg.MaxMessageSize = int64(size)
+			return err
+		}
+		fields["max_concurrent_calls"] = func(v *yaml.Node) error { return l.integer(v, &wsCfg.MaxConcurrentCalls) }
+	case "jsonrpc":
+		fields["path"] = func(v *yaml.Node) error { return l.str(v, &path) }
+		fields["socket_network"] = func(v *yaml.Node) error {
+			if err := l.str(v, &rpcCfg.SocketNetwork); err != nil {
+				return err
+			}
+			if rpcCfg.SocketNetwork != "tcp" && rpcCfg.SocketNetwork != "unix" {
+				return l.errorf(v, "unknown socket network %q, expected tcp or unix", rpcCfg.SocketNetwork)
+			}
+			return nil
+		}
+		fields["socket_address"] = func(v *yaml.Node) error { return l.str(v, &rpcCfg.SocketAddress) }
+		fields["max_message_size"] = func(v *yaml.Node) error { return l.integer(v, &rpcCfg.MaxMessageSize) }
+		fields["max_concurrency"] = func(v *yaml.Node) error { return l.integer(v, &rpcCfg.MaxConcurrency) }
+	}
+	if err := l.object(n, fields); err != nil {
+		return nil, err
+	}
+	if listen == "" && !(kind == "jsonrpc" && rpcCfg.SocketAddress != "") {
+		return nil, l.errorf(n, "%s adapter requires a listen address", kind)
+	}
+
+	switch kind {
+	case "rest":
+		restCfg.Config, restCfg.Listen = common, listen
+		return rest.New(restCfg), nil
+	case "grpc":
+		grpcCfg.Config, grpcCfg.Listen = common, listen
+		return grpc.New(grpcCfg), nil
+	case "graphql":
+		a := graphql.New(graphql.Config{Config: common, Listen: listen, Path: path})
+		if _, err := a.Schema(); err != nil {
+			return nil, l.errorf(n, "%v", err)
+		}
+		return a, nil
+	case "websocket":
+		wsCfg.Config, wsCfg.Listen, wsCfg.Path = common, listen, path
+		return websocket.New(wsCfg), nil
+	default:
+		rpcCfg.Config, rpcCfg.Listen, rpcCfg.Path = common, listen, path
+		return jsonrpc.New(rpcCfg), nil
+	}
+}
+
+func (l *loader) openAPI(n *yaml.Node, cfg *rest.OpenAPIConfig) error {
+	return l.object(n, map[string]func(*yaml.Node) error{
+		"path":        func(v *yaml.Node) error { return l.str(v, &cfg.Path) },
+		"title":       func(v *yaml.Node) error { return l.str(v, &cfg.Title) },
+		"version":     func(v *yaml.Node) error { return l.str(v, &cfg.Version) },
+		"description": func(v *yaml.Node) error { return l.str(v, &cfg.Description) },
+		"servers":     func(v *yaml.Node) error { return l.strings(v, &cfg.Servers) },
+	})
+}
@@ -0,0 +1,145 @@
+// Package config builds a ready-to-run Protokol instance from a declarative
+// YAML or JSON file describing types, services, middleware stacks and
+// adapters. Backends are implemented in Go and referenced by name.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/middleware/auth"
+)
+
+// Options supplies the Go values a configuration file refers to by name.
+type Options struct {
+	// Backends maps the names used in a service's "backend" key to their
+	// implementations. Every backend is registered with the instance.
+	Backends map[string]protokol.Backend
+	// Validators maps the names used in the "validator" option of the auth
+	// middleware to token validators.
+	Validators map[string]auth.Validator
+	// Middleware adds middleware kinds beyond the built-in ones, keyed by
+	// the name used in middleware stacks.
+	Middleware map[string]MiddlewareFactory
+	// ErrorMapper is set on every adapter to translate errors before they
+	// are sent to clients.
+	ErrorMapper adapters.ErrorMapper
+	// Logger is passed to the logging and recover middleware and logs the
+	// internal errors hidden from clients. Defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+// MiddlewareFactory creates a middleware from its options in the file.
+// decode unmarshals the options into v, which should be a pointer to a
+// struct with yaml tags; it is a no-op when no options are given.
+type MiddlewareFactory func(decode func(v any) error) (adapters.Middleware, error)
+
+// Error reports an invalid entry in a configuration file.
+type Error struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Msg)
+}
+
+// Load reads the configuration file at path and returns a Protokol instance
+// with its schema, backends and adapters set up. Invalid entries are reported
+// as *Error; a schema that is inconsistent as a whole as
+// *schema.ValidationError.
+func Load(path string, opts Options) (*protokol.Protokol, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data, opts)
+}
+
+// Parse builds a Protokol instance from configuration data. name identifies
+// the data in error messages. JSON is accepted as it is a subset of YAML.
+func Parse(name string, data []byte, opts Options) (*protokol.Protokol, error) {
+	var doc yaml.Node
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	if err := dec.Decode(&doc); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, &Error{File: name, Line: 1, Column: 1, Msg: "empty configuration"}
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+
+	l := &loader{
+		decoder: decoder{file: name},
+		opts:    opts,
+		p:       protokol.New(),
+		stacks:  make(map[string][]adapters.Middleware),
+	}
+	if err := l.root(&doc); err != nil {
+		return nil, err
+	}
+	return l.p, nil
+}
+
+// loader builds the instance while walking the document.
+type loader struct {
+	decoder
+	typeResolver
+	opts   Options
+	p      *protokol.Protokol
+	stacks map[string][]adapters.Middleware
+}
+
+// root loads the sections in dependency order: types before services, and
+// middleware stacks before the adapters that use them.
+func (l *loader) root(doc *yaml.Node) error {
+	var types, services, middleware, adapterList *yaml.Node
+	err := l.object(doc, map[string]func(*yaml.Node) error{
+		"types":      func(n *yaml.Node) error { types = n; return nil },
+		"services":   func(n *yaml.Node) error { services = n; return nil },
+		"middleware": func(n *yaml.Node) error { middleware = n; return nil },
+		"adapters":   func(n *yaml.Node) error { adapterList = n; return nil },
+	})
+	if err != nil {
+		return err
+	}
+
+	if types != nil {
+		if err := l.types(types); err != nil {
+			return err
+		}
+	}
+	if services != nil {
+		if err := l.services(services); err != nil {
+			return err
+		}
+	}
+	for name, b := range l.opts.Backends {
+		l.p.Backends().Register(name, b)
+	}
+	if middleware != nil {
+		if err := l.middlewareStacks(middleware); err != nil {
+			return err
+		}
+	}
+	if adapterList != nil {
+		if err := l.adapters(adapterList); err != nil {
+			return err
+		}
+	}
+	// Problems spanning several entries, such as path parameters missing
+	// from the input, are found by the schema itself.
+	return l.p.Schema().Validate()
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/backend"
+	"github.com/jekabolt/protokol/schema"
+)
+
+const usersConfig = `
+types:
+  Role:
+    enum: [USER, ADMIN]
+  User:
+    fields:
+      id: {type: string, required: true}
+      role: {type: Role, default: USER}
+      tags: "[]string"
+      labels: map<string, string>
+
+services:
+  - name: Users
+    package: acme.user.v1
+    backend: users
+    methods:
+      - name: GetUser
+        input:
+          fields:
+            id: {type: string, required: true}
+        output: User
+        http: GET /users/{id}
+      - name: PurgeUser
+        input: {fields: {id: string}}
+        output: User
+        http: PURGE /users/{id}
+      - name: WatchUsers
+        type: server_stream
+        input: {fields: {role: Role}}
+        output: User
+`
+
+func options() Options {
+	return Options{Backends: map[string]protokol.Backend{"users": backend.NewHandler()}}
+}
+
+func TestParse(t *testing.T) {
+	p, err := Parse("users.yaml", []byte(usersConfig), options())
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, ok := p.Schema().ServiceByName("Users")
+	if !ok {
+		t.Fatal("service Users not loaded")
+	}
+	if svc.Package != "acme.user.v1" || svc.Backend != "users" {
+		t.Errorf("service = %q %q", svc.Package, svc.Backend)
+	}
+
+	tests := []struct {
+		method string
+		typ    schema.MethodType
+		http   string
+		input  string
+	}{
+		{"GetUser", schema.MethodUnary, "GET /users/{id}", "GetUserRequest"},
+		{"PurgeUser", schema.MethodUnary, "PURGE /users/{id}", "PurgeUserRequest"},
+		{"WatchUsers", schema.MethodServerStream, " ", "WatchUsersRequest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			m, ok := svc.MethodByName(tt.method)
+			if !ok {
+				t.Fatal("method not loaded")
+			}
+			if m.Type != tt.typ {
+				t.Errorf("type = %v, want %v", m.Type, tt.typ)
+			}
+			if got := m.HTTPMethod + " " + m.HTTPPath; got != tt.http {
+				t.Errorf("http = %q, want %q", got, tt.http)
+			}
+			if m.Input.Name != tt.input {
+				t.Errorf("input = %q, want %q", m.Input.Name, tt.input)
+			}
+		})
+	}
+
+	user, ok := p.Schema().Resolve(schema.Ref("User"))
+	if !ok {
+		t.Fatal("type User not registered")
+	}
+	for _, f := range user.Fields {
+		if f.Name == "role" && f.Default != "USER" {
+			t.Errorf("role default = %v, want USER", f.Default)
+		}
+	}
+	if _, ok := p.Backends().Get("users"); !ok {
+		t.Error("backend users not registered")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "empty",
+			src:  "",
+			want: "users.yaml:1:1: empty configuration",
+		},
+		{
+			name: "http rule without a path",
+			src:  "services:\n  - name: Users\n    backend: users\n    methods:\n      - name: Get\n        input: {fields: {id: string}}\n        output: {fields: {id: string}}\n        http: GET users\n",
+			want: `users.yaml:8:15: invalid http rule "GET users"`,
+		},
+		{
+			name: "http method that is not a token",
+			src:  "services:\n  - name: Users\n    backend: users\n    methods:\n      - name: Get\n        input: {fields: {id: string}}\n        output: {fields: {id: string}}\n        http: G(ET /users\n",
+			want: `users.yaml:8:15: invalid http rule "G(ET /users"`,
+		},
+		{
+			name: "unknown method type",
+			src:  "services:\n  - name: Users\n    backend: users\n    methods:\n      - name: Get\n        type: duplex\n        input: {fields: {id: string}}\n        output: {fields: {id: string}}\n",
+			want: "users.yaml:6:15:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("users.yaml", []byte(tt.src), options())
+			var cerr *Error
+			if !errors.As(err, &cerr) {
+				t.Fatalf("err = %v, want *Error", err)
+			}
+			if !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("err = %v, want prefix %q", err, tt.want)
+			}
+		})
+	}
+}
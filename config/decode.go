@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decoder reads values from YAML nodes, reporting errors at the position of
+// the offending node.
+type decoder struct {
+	file string
+}
+
+func (d *decoder) errorf(n *yaml.Node, format string, args ...any) error {
+	return &Error{File: d.file, Line: n.Line, Column: n.Column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// resolve skips document nodes and follows aliases.
+func resolve(n *yaml.Node) *yaml.Node {
+	for {
+		switch n.Kind {
+		case yaml.DocumentNode:
+			if len(n.Content) == 0 {
+				return n
+			}
+			n = n.Content[0]
+		case yaml.AliasNode:
+			n = n.Alias
+		default:
+			return n
+		}
+	}
+}
+
+// object calls the function for each key of mapping n. Keys without a
+// function are rejected.
+func (d *decoder) object(n *yaml.Node, fields map[string]func(*yaml.Node) error) error {
+	return d.mapping(n, func(key, value *yaml.Node) error {
+		fn, ok := fields[key.Value]
+		if !ok {
+			return d.errorf(key, "unknown key %q, expected one of %s", key.Value, keyList(fields))
+		}
+		return fn(value)
+	})
+}
+
+// mapping calls fn for each key and value of mapping n, in file order.
+func (d *decoder) mapping(n *yaml.Node, fn func(key, value *yaml.Node) error) error {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		return d.errorf(n, "expected a mapping, got %s", describe(n))
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], resolve(n.Content[i+1])
+		if key.Kind != yaml.ScalarNode {
+			return d.errorf(key, "expected a string key, got %s", describe(key))
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sequence calls fn for each item of sequence n.
+func (d *decoder) sequence(n *yaml.Node, fn func(item *yaml.Node) error) error {
+	n = resolve(n)
+	if n.Kind != yaml.SequenceNode {
+		return d.errorf(n, "expected a list, got %s", describe(n))
+	}
+	for _, item := range n.Content {
+		if err := fn(resolve(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *decoder) scalar(n *yaml.Node, v any) error {
+	n = resolve(n)
+	if n.Kind != yaml.ScalarNode {
+		return d.errorf(n, "expected a scalar, got %s", describe(n))
+	}
+	if err := n.Decode(v); err != nil {
+		return d.errorf(n, "invalid value %q", n.Value)
+	}
+	return nil
+}
+
+func (d *decoder) str(n *yaml.Node, dst *string) error {
+	return d.scalar(n, dst)
+}
+
+func (d *decoder) integer(n *yaml.Node, dst *int) error {
+	return d.scalar(n, dst)
+}
+
+func (d *decoder) number(n *yaml.Node, dst *float64) error {
+	return d.scalar(n, dst)
+}
+
+func (d *decoder) boolean(n *yaml.Node, dst *bool) error {
+	return d.scalar(n, dst)
+}
+
+func (d *decoder) duration(n *yaml.Node, dst *time.Duration) error {
+	var s string
+	if err := d.str(n, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return d.errorf(n, "invalid duration %q", s)
+	}
+	*dst = v
+	return nil
+}
+
+func (d *decoder) strings(n *yaml.Node, dst *[]string) error {
+	return d.sequence(n, func(item *yaml.Node) error {
+		var s string
+		if err := d.str(item, &s); err != nil {
+			return err
+		}
+		*dst = append(*dst, s)
+		return nil
+	})
+}
+
+// value decodes any YAML value, such as a field default or method option.
+func (d *decoder) value(n *yaml.Node, dst *any) error {
+	if err := resolve(n).Decode(dst); err != nil {
+		return d.errorf(n, "invalid value: %v", err)
+	}
+	return nil
+}
+
+// require reports the first key in keys missing from mapping n. Other
+// nodes are left for object or mapping to reject.
+func (d *decoder) require(n *yaml.Node, keys ...string) error {
+	n = resolve(n)
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for _, k := range keys {
+		found := false
+		for i := 0; i < len(n.Content); i += 2 {
+			if n.Content[i].Value == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return d.errorf(n, "missing required key %q", k)
+		}
+	}
+	return nil
+}
+
+func describe(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		if n.Tag == "!!null" {
+			return "null"
+		}
+		return fmt.Sprintf("%q", n.Value)
+	}
+	return "an empty document"
+}
+
+func keyList[V any](m map[string]V) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ", ")
+}
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/middleware/auth"
+	"github.com/jekabolt/protokol/middleware/logging"
+	"github.com/jekabolt/protokol/middleware/ratelimit"
+	"github.com/jekabolt/protokol/middleware/recover"
+	"github.com/jekabolt/protokol/middleware/validate"
+)
+
+// rateLimitKeys maps the values of the ratelimit "key" option to key
+// functions.
+var rateLimitKeys = map[string]ratelimit.KeyFunc{
+	"ip":      ratelimit.ByIP,
+	"service": ratelimit.ByService,
+	"method":  ratelimit.ByMethod,
+}
+
+// middlewareStacks reads the named middleware stacks that adapters refer to.
+func (l *loader) middlewareStacks(n *yaml.Node) error {
+	return l.mapping(n, func(key, value *yaml.Node) error {
+		stack, err := l.middlewareList(value)
+		if err != nil {
+			return err
+		}
+		l.stacks[key.Value] = stack
+		return nil
+	})
+}
+
+// middlewareRef reads an adapter's middleware: the name of a stack or an
+// inline list.
+func (l *loader) middlewareRef(n *yaml.Node) ([]adapters.Middleware, error) {
+	if resolve(n).Kind == yaml.SequenceNode {
+		return l.middlewareList(n)
+	}
+	var name string
+	if err := l.str(n, &name); err != nil {
+		return nil, err
+	}
+	stack, ok := l.stacks[name]
+	if !ok {
+		return nil, l.errorf(n, "unknown middleware stack %q", name)
+	}
+	return stack, nil
+}
+
+// middlewareList reads a list whose items are either a middleware name or
+// a mapping from a single name to its options.
+func (l *loader) middlewareList(n *yaml.Node) ([]adapters.Middleware, error) {
+	var stack []adapters.Middleware
+	err := l.sequence(n, func(item *yaml.Node) error {
+		kindNode, opts := item, (*yaml.Node)(nil)
+		if item.Kind == yaml.MappingNode {
+			if len(item.Content) != 2 {
+				return l.errorf(item, "middleware entry must have a single key naming the middleware")
+			}
+			kindNode, opts = item.Content[0], resolve(item.Content[1])
+			if opts.Tag == "!!null" {
+				opts = nil
+			}
+		}
+		var kind string
+		if err := l.str(kindNode, &kind); err != nil {
+			return err
+		}
+		mw, err := l.middleware(kindNode, kind, opts)
+		if err != nil {
+			return err
+		}
+		stack = append(stack, mw)
+		return nil
+	})
+	return stack, err
+}
+
+// middleware creates a built-in or custom middleware of the given kind.
+// opts is nil if no options were given.
+func (l *loader) middleware(at *yaml.Node, kind string, opts *yaml.Node) (adapters.Middleware, error) {
+	if factory, ok := l.opts.Middleware[kind]; ok {
+		mw, err := factory(func(v any) error {
+			if opts == nil {
+				return nil
+			}
+			if err := opts.Decode(v); err != nil {
+				return l.errorf(opts, "invalid %s options: %v", kind, err)
+			}
+			return nil
+		})
+		if err != nil {
+			if _, ok := err.(*Error); ok {
+				return nil, err
+			}
+			return nil, l.errorf(at, "%s: %v", kind, err)
+		}
+		return mw, nil
+	}
+
+	switch kind {
+	case "recover", "logging", "validate":
+		if opts != nil {
+			return nil, l.errorf(opts, "%s middleware takes no options", kind)
+		}
+		switch kind {
+		case "recover":
+			return recover.New(l.opts.Logger), nil
+		case "logging":
+			return logging.New(l.opts.Logger), nil
+		default:
+			return validate.New(l.p.Schema()), nil
+		}
+	case "ratelimit":
+		return l.rateLimit(at, opts)
+	case "auth":
+		return l.auth(at, opts)
+	}
+	return nil, l.errorf(at, "unknown middleware %q", kind)
+}
+
+func (l *loader) rateLimit(at, n *yaml.Node) (adapters.Middleware, error) {
+	if n == nil {
+		return nil, l.errorf(at, "ratelimit middleware requires rate and burst options")
+	}
+	if err := l.require(n, "rate", "burst"); err != nil {
+		return nil, err
+	}
+	var (
+		rate         float64
+		burst        int
+		keyFunc      ratelimit.KeyFunc
+		rateLimitOpt []ratelimit.Option
+	)
+	err := l.object(n, map[string]func(*yaml.Node) error{
+		"rate": func(v *yaml.Node) error {
+			if err := l.number(v, &rate); err != nil {
+				return err
+			}
+			if rate <= 0 {
+				return l.errorf(v, "rate must be positive")
+			}
+			return nil
+		},
+		"burst": func(v *yaml.Node) error {
+			if err := l.integer(v, &burst); err != nil {
+				return err
+			}
+			if burst <= 0 {
+				return l.errorf(v, "burst must be positive")
+			}
+			return nil
+		},
+		"key": func(v *yaml.Node) error {
+			var s string
+			if err := l.str(v, &s); err != nil {
+				return err
+			}
+			var ok bool
+			if keyFunc, ok = rateLimitKeys[s]; !ok {
+				return l.errorf(v, "unknown rate limit key %q, expected one of %s", s, keyList(rateLimitKeys))
+			}
+			return nil
+		},
+		"cleanup_interval": func(v *yaml.Node) error {
+			var d time.Duration
+			if err := l.duration(v, &d); err != nil {
+				return err
+			}
+			rateLimitOpt = append(rateLimitOpt, ratelimit.WithCleanupInterval(d))
+			return nil
+		},
+		"max_idle_time": func(v *yaml.Node) error {
+			var d time.Duration
+			if err := l.duration(v, &d); err != nil {
+				return err
+			}
+			rateLimitOpt = append(rateLimitOpt, ratelimit.WithMaxIdleTime(d))
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ratelimit.New(rate, burst, keyFunc, rateLimitOpt...), nil
+}
+
+func (l *loader) auth(at, n *yaml.Node) (adapters.Middleware, error) {
+	if n == nil {
+		return nil, l.errorf(at, "auth middleware requires a validator or api_keys option")
+	}
+	var (
+		validator auth.Validator
+		authOpts  []auth.Option
+	)
+	err := l.object(n, map[string]func(*yaml.Node) error{
+		"validator": func(v *yaml.Node) error {
+			if validator != nil {
+				return l.errorf(v, "validator and api_keys are mutually exclusive")
+			}
+			var name string
+			if err := l.str(v, &name); err != nil {
+				return err
+			}
+			var ok bool
+			if validator, ok = l.opts.Validators[name]; !ok {
+				return l.errorf(v, "unknown validator %q", name)
+			}
+			return nil
+		},
+		"api_keys": func(v *yaml.Node) error {
+			if validator != nil {
+				return l.errorf(v, "validator and api_keys are mutually exclusive")
+			}
+			var keys []string
+			if err := l.strings(v, &keys); err != nil {
+				return err
+			}
+			validator = auth.APIKey(keys...)
+			return nil
+		},
+		"header": func(v *yaml.Node) error {
+			var s string
+			if err := l.str(v, &s); err != nil {
+				return err
+			}
+			authOpts = append(authOpts, auth.WithHeader(s))
+			return nil
+		},
+		"scheme": func(v *yaml.Node) error {
+			var s string
+			if err := l.str(v, &s); err != nil {
+				return err
+			}
+			authOpts = append(authOpts, auth.WithScheme(s))
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if validator == nil {
+		return nil, l.errorf(n, "auth middleware requires a validator or api_keys option")
+	}
+	return auth.New(validator, authOpts...), nil
+}
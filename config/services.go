@@ -0,0 +1,171 @@
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// methodTypes maps the values of a method's "type" key to method types.
+var methodTypes = map[string]schema.MethodType{
+	"unary":         schema.MethodUnary,
+	"server_stream": schema.MethodServerStream,
+	"client_stream": schema.MethodClientStream,
+	"bidirectional": schema.MethodBidirectional,
+}
+
+// services adds every entry of the services section to the schema.
+func (l *loader) services(n *yaml.Node) error {
+	names := make(map[string]bool)
+	return l.sequence(n, func(item *yaml.Node) error {
+		svc, err := l.service(item)
+		if err != nil {
+			return err
+		}
+		if names[svc.Name] {
+			return l.errorf(item, "duplicate service %q", svc.Name)
+		}
+		names[svc.Name] = true
+		l.p.Schema().AddService(svc)
+		return nil
+	})
+}
+
+func (l *loader) service(n *yaml.Node) (schema.Service, error) {
+	if err := l.require(n, "name"); err != nil {
+		return schema.Service{}, err
+	}
+	var svc schema.Service
+	var methods *yaml.Node
+	err := l.object(n, map[string]func(*yaml.Node) error{
+		"name":        func(v *yaml.Node) error { return l.str(v, &svc.Name) },
+		"package":     func(v *yaml.Node) error { return l.str(v, &svc.Package) },
+		"description": func(v *yaml.Node) error { return l.str(v, &svc.Description) },
+		"backend":     func(v *yaml.Node) error { return l.backend(v, &svc.Backend) },
+		"methods":     func(v *yaml.Node) error { methods = v; return nil },
+	})
+	if err != nil {
+		return schema.Service{}, err
+	}
+	if svc.Name == "" {
+		return schema.Service{}, l.errorf(n, "service name must not be empty")
+	}
+	if methods != nil {
+		err = l.sequence(methods, func(item *yaml.Node) error {
+			m, err := l.method(item)
+			if err != nil {
+				return err
+			}
+			if _, exists := svc.MethodByName(m.Name); exists {
+				return l.errorf(item, "duplicate method %q in service %s", m.Name, svc.Name)
+			}
+			if m.Backend == "" && svc.Backend == "" {
+				return l.errorf(item, "method %s has no backend and service %s sets none", m.Name, svc.Name)
+			}
+			svc.Methods = append(svc.Methods, m)
+			return nil
+		})
+		if err != nil {
+			return schema.Service{}, err
+		}
+	}
+	if len(svc.Methods) == 0 {
+		// Without methods to name their own, the service needs a backend.
+		if err := l.require(n, "backend"); err != nil {
+			return schema.Service{}, err
+		}
+	}
+	return svc, nil
+}
+
+// backend reads a backend name, which must be one of Options.Backends.
+func (l *loader) backend(n *yaml.Node, dst *string) error {
+	if err := l.str(n, dst); err != nil {
+		return err
+	}
+	if _, ok := l.opts.Backends[*dst]; !ok {
+		return l.errorf(n, "unknown backend %q", *dst)
+	}
+	return nil
+}
+
+func (l *loader) method(n *yaml.Node) (schema.Method, error) {
+	if err := l.require(n, "name", "input", "output"); err != nil {
+		return schema.Method{}, err
+	}
+	// The name is needed first to name inline input and output messages.
+	var m schema.Method
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == "name" {
+			if err := l.str(n.Content[i+1], &m.Name); err != nil {
+				return schema.Method{}, err
+			}
+		}
+	}
+	if m.Name == "" {
+		return schema.Method{}, l.errorf(n, "method name must not be empty")
+	}
+
+	err := l.object(n, map[string]func(*yaml.Node) error{
+		"name":        func(v *yaml.Node) error { return nil },
+		"description": func(v *yaml.Node) error { return l.str(v, &m.Description) },
+		"backend":     func(v *yaml.Node) error { return l.backend(v, &m.Backend) },
+		"type": func(v *yaml.Node) error {
+			var s string
+			if err := l.str(v, &s); err != nil {
+				return err
+			}
+			t, ok := methodTypes[s]
+			if !ok {
+				return l.errorf(v, "unknown method type %q, expected one of %s", s, keyList(methodTypes))
+			}
+			m.Type = t
+			return nil
+		},
+		"input": func(v *yaml.Node) error {
+			var err error
+			m.Input, err = l.typeSpec(v, m.Name+"Request")
+			if err == nil && m.Input.Kind != schema.KindMessage {
+				err = l.errorf(v, "input of %s must be a message type", m.Name)
+			}
+			return err
+		},
+		"output": func(v *yaml.Node) error {
+			var err error
+			m.Output, err = l.typeSpec(v, m.Name+"Response")
+			if err == nil && m.Output.Kind != schema.KindMessage {
+				err = l.errorf(v, "output of %s must be a message type", m.Name)
+			}
+			return err
+		},
+		"http": func(v *yaml.Node) error {
+			var s string
+			if err := l.str(v, &s); err != nil {
+				return err
+			}
+			method, path, ok := strings.Cut(strings.TrimSpace(s), " ")
+			path = strings.TrimSpace(path)
+			if !ok || !schema.IsToken(method) || !strings.HasPrefix(path, "/") {
+				return l.errorf(v, "invalid http rule %q, expected e.g. \"GET /users/{id}\"", s)
+			}
+			m.HTTPMethod, m.HTTPPath = method, path
+			return nil
+		},
+		"options": func(v *yaml.Node) error {
+			return l.mapping(v, func(key, value *yaml.Node) error {
+				var opt any
+				if err := l.value(value, &opt); err != nil {
+					return err
+				}
+				if m.Options == nil {
+					m.Options = make(map[string]any)
+				}
+				m.Options[key.Value] = opt
+				return nil
+			})
+		},
+	})
+	return m, err
+}
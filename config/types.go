@@ -0,0 +1,302 @@
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// scalarTypes maps the type names usable in type expressions to schema
+// types.
+var scalarTypes = map[string]schema.Type{
+	"bool":    schema.Bool,
+	"int32":   schema.Int32,
+	"int64":   schema.Int64,
+	"float32": schema.Float32,
+	"float64": schema.Float64,
+	"string":  schema.String,
+	"bytes":   schema.Bytes,
+}
+
+// typeResolver resolves named types from the types section on first use, so
+// definitions may appear in any order.
+type typeResolver struct {
+	defs      map[string]*yaml.Node
+	resolved  map[string]schema.Type
+	resolving map[string]bool
+}
+
+// types registers every entry of the types section with the schema.
+func (l *loader) types(n *yaml.Node) error {
+	l.defs = make(map[string]*yaml.Node)
+	l.resolved = make(map[string]schema.Type)
+	l.resolving = make(map[string]bool)
+
+	var names []*yaml.Node
+	err := l.mapping(n, func(key, value *yaml.Node) error {
+		if _, ok := scalarTypes[key.Value]; ok {
+			return l.errorf(key, "type name %q is reserved", key.Value)
+		}
+		if !validTypeName(key.Value) {
+			return l.errorf(key, "invalid type name %q", key.Value)
+		}
+		l.defs[key.Value] = value
+		names = append(names, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range names {
+		t, err := l.named(key.Value, key)
+		if err != nil {
+			return err
+		}
+		l.p.Schema().RegisterType(key.Value, t)
+	}
+	return nil
+}
+
+// named returns the type defined as name, reporting unknown names at ref.
+func (l *loader) named(name string, ref *yaml.Node) (schema.Type, error) {
+	if t, ok := l.resolved[name]; ok {
+		return t, nil
+	}
+	def, ok := l.defs[name]
+	if !ok {
+		return schema.Type{}, l.errorf(ref, "unknown type %q", name)
+	}
+	if l.resolving[name] {
+		// A message that contains itself refers to its registered
+		// definition at the recursive field.
+		return schema.Ref(name), nil
+	}
+	l.resolving[name] = true
+	defer delete(l.resolving, name)
+
+	t, err := l.typeDef(name, def)
+	if err != nil {
+		return schema.Type{}, err
+	}
+	l.resolved[name] = t
+	return t, nil
+}
+
+// typeSpec reads a type given either as a type expression or as an inline
+// definition, which is named fallback.
+func (l *loader) typeSpec(n *yaml.Node, fallback string) (schema.Type, error) {
+	if resolve(n).Kind == yaml.MappingNode {
+		return l.typeDef(fallback, n)
+	}
+	return l.typeRef(n)
+}
+
+// typeDef reads a message definition with "fields", an enum definition with
+// "enum", or a type expression aliasing another type.
+func (l *loader) typeDef(name string, n *yaml.Node) (schema.Type, error) {
+	n = resolve(n)
+	if n.Kind == yaml.ScalarNode {
+		return l.typeRef(n)
+	}
+
+	var t schema.Type
+	err := l.object(n, map[string]func(*yaml.Node) error{
+		"fields": func(v *yaml.Node) error {
+			if t.Kind == schema.KindEnum {
+				return l.errorf(v, "a type cannot have both fields and enum values")
+			}
+			t = schema.Type{Kind: schema.KindMessage, Name: name}
+			return l.fields(&t, v)
+		},
+		"enum": func(v *yaml.Node) error {
+			if t.Kind == schema.KindMessage {
+				return l.errorf(v, "a type cannot have both fields and enum values")
+			}
+			t = schema.Type{Kind: schema.KindEnum, Name: name}
+			return l.enumValues(&t, v)
+		},
+	})
+	if err != nil {
+		return schema.Type{}, err
+	}
+	if t.Kind == schema.KindInvalid {
+		return schema.Type{}, l.errorf(n, "type %s needs fields or enum values", name)
+	}
+	return t, nil
+}
+
+// fields reads a mapping of field names to type expressions or to field
+// definitions with type, number, required, default and description keys.
+func (l *loader) fields(t *schema.Type, n *yaml.Node) error {
+	numbers := make(map[int]string)
+	return l.mapping(n, func(key, value *yaml.Node) error {
+		f := schema.Field{Name: key.Value, Number: len(t.Fields) + 1}
+		numberNode := key
+
+		if value.Kind == yaml.MappingNode {
+			if err := l.require(value, "type"); err != nil {
+				return err
+			}
+			err := l.object(value, map[string]func(*yaml.Node) error{
+				"type": func(v *yaml.Node) error {
+					var err error
+					f.Type, err = l.typeRef(v)
+					return err
+				},
+				"number": func(v *yaml.Node) error {
+					numberNode = v
+					if err := l.integer(v, &f.Number); err != nil {
+						return err
+					}
+					if f.Number <= 0 {
+						return l.errorf(v, "field number must be positive")
+					}
+					return nil
+				},
+				"required":    func(v *yaml.Node) error { return l.boolean(v, &f.Required) },
+				"default":     func(v *yaml.Node) error { return l.value(v, &f.Default) },
+				"description": func(v *yaml.Node) error { return l.str(v, &f.Description) },
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			var err error
+			if f.Type, err = l.typeRef(value); err != nil {
+				return err
+			}
+		}
+
+		if other, ok := numbers[f.Number]; ok {
+			return l.errorf(numberNode, "field %s reuses number %d of field %s", f.Name, f.Number, other)
+		}
+		numbers[f.Number] = f.Name
+		t.Fields = append(t.Fields, f)
+		return nil
+	})
+}
+
+// enumValues reads a list of value names, numbered from zero, or a mapping
+// of value names to numbers.
+func (l *loader) enumValues(t *schema.Type, n *yaml.Node) error {
+	names := make(map[string]bool)
+	numbers := make(map[int]string)
+	add := func(node *yaml.Node, name string, number int) error {
+		if names[name] {
+			return l.errorf(node, "duplicate enum value %q", name)
+		}
+		if other, ok := numbers[number]; ok {
+			return l.errorf(node, "enum value %s reuses number %d of %s", name, number, other)
+		}
+		names[name] = true
+		numbers[number] = name
+		t.Values = append(t.Values, schema.EnumValue{Name: name, Number: number})
+		return nil
+	}
+
+	if resolve(n).Kind == yaml.MappingNode {
+		return l.mapping(n, func(key, value *yaml.Node) error {
+			var number int
+			if err := l.integer(value, &number); err != nil {
+				return err
+			}
+			return add(key, key.Value, number)
+		})
+	}
+	return l.sequence(n, func(item *yaml.Node) error {
+		var name string
+		if err := l.str(item, &name); err != nil {
+			return err
+		}
+		return add(item, name, len(t.Values))
+	})
+}
+
+// typeRef parses a type expression: a scalar type name, a name from the
+// types section, "[]T" for repeated types, or "map<K, V>".
+func (l *loader) typeRef(n *yaml.Node) (schema.Type, error) {
+	var expr string
+	if err := l.str(n, &expr); err != nil {
+		return schema.Type{}, err
+	}
+	return l.parseType(n, strings.TrimSpace(expr))
+}
+
+func (l *loader) parseType(n *yaml.Node, expr string) (schema.Type, error) {
+	if elem, ok := strings.CutPrefix(expr, "[]"); ok {
+		t, err := l.parseType(n, strings.TrimSpace(elem))
+		if err != nil {
+			return schema.Type{}, err
+		}
+		return schema.Repeated(t), nil
+	}
+
+	if args, ok := strings.CutPrefix(expr, "map<"); ok {
+		args, ok = strings.CutSuffix(args, ">")
+		if !ok {
+			return schema.Type{}, l.errorf(n, "invalid map type %q, expected map<K, V>", expr)
+		}
+		keyExpr, valueExpr, ok := splitMapArgs(args)
+		if !ok {
+			return schema.Type{}, l.errorf(n, "invalid map type %q, expected map<K, V>", expr)
+		}
+		key, ok := scalarTypes[keyExpr]
+		if !ok || key.Kind == schema.KindFloat32 || key.Kind == schema.KindFloat64 || key.Kind == schema.KindBytes {
+			return schema.Type{}, l.errorf(n, "invalid map key type %q, expected string, bool or an integer type", keyExpr)
+		}
+		value, err := l.parseType(n, valueExpr)
+		if err != nil {
+			return schema.Type{}, err
+		}
+		return schema.Map(key, value), nil
+	}
+
+	if t, ok := scalarTypes[expr]; ok {
+		return t, nil
+	}
+	if !validTypeName(expr) {
+		return schema.Type{}, l.errorf(n, "invalid type %q", expr)
+	}
+	return l.named(expr, n)
+}
+
+// splitMapArgs splits "K, V" at the first comma outside angle brackets.
+func splitMapArgs(s string) (key, value string, ok bool) {
+	depth := 0
+	for i, r := range s {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				key, value = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+				return key, value, key != "" && value != ""
+			}
+		}
+	}
+	return "", "", false
+}
+
+// validTypeName accepts identifiers, optionally qualified with dots.
+func validTypeName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "" {
+			return false
+		}
+		for i, r := range part {
+			letter := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+			if !letter && (i == 0 || r < '0' || r > '9') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -1,17 +1,236 @@
 package protokol
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
 
-// Sentinel errors returned by protokol operations.
+// Sentinel errors returned by protokol operations. Each is an *Error, so
+// ErrorCode reports its code.
 var (
 	// ErrAlreadyRunning is returned when Run is called on an already running instance.
-	ErrAlreadyRunning = errors.New("protokol: already running")
+	ErrAlreadyRunning error = NewError(CodeFailedPrecondition, "protokol: already running")
 	// ErrServiceNotFound is returned when a requested service does not exist.
-	ErrServiceNotFound = errors.New("protokol: service not found")
+	ErrServiceNotFound error = NewError(CodeUnimplemented, "protokol: service not found")
 	// ErrMethodNotFound is returned when a requested method does not exist.
-	ErrMethodNotFound = errors.New("protokol: method not found")
+	ErrMethodNotFound error = NewError(CodeUnimplemented, "protokol: method not found")
 	// ErrBackendNotFound is returned when a backend is not registered.
-	ErrBackendNotFound = errors.New("protokol: backend not found")
+	ErrBackendNotFound error = NewError(CodeInternal, "protokol: backend not found")
 	// ErrStreamingNotSupported is returned when streaming is not supported by a backend.
-	ErrStreamingNotSupported = errors.New("protokol: streaming not supported")
+	ErrStreamingNotSupported error = NewError(CodeUnimplemented, "protokol: streaming not supported")
+	// ErrInvalidArgument is returned when request input does not match the schema.
+	ErrInvalidArgument error = NewError(CodeInvalidArgument, "protokol: invalid argument")
 )
+
+// sentinels are checked with errors.Is by ErrorCode, for errors that match
+// them through an Is method rather than by wrapping.
+var sentinels = []error{
+	ErrInvalidArgument,
+	ErrServiceNotFound,
+	ErrMethodNotFound,
+	ErrStreamingNotSupported,
+	ErrBackendNotFound,
+}
+
+// Error is an error with a canonical code, which adapters translate into
+// their protocol's status: an HTTP status, a gRPC status, a GraphQL
+// extensions code or a JSON-RPC error code.
+type Error struct {
+	Code    Code
+	Message string
+	// Details carry structured information for the client, such as
+	// *BadRequest or *RetryInfo. Adapters encode them with encoding/json;
+	// the gRPC adapter sends the detail types of this package as their
+	// google.rpc equivalents and proto messages as they are.
+	Details []any
+	// Err is the underlying cause, returned by Unwrap. It is not sent to
+	// the client.
+	Err error
+
+	// text is the message including the causes given to Errorf or Wrap,
+	// returned by Error for logging.
+	text string
+}
+
+// NewError returns an error with the given code and message.
+func NewError(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Errorf returns an error with the given code. Message, which is sent to
+// clients, is formatted by fmt.Sprintf from format and the arguments that are
+// not errors. The error arguments become the cause, in order, so they are
+// matched by errors.Is and errors.As but never sent:
+//
+//	protokol.Errorf(protokol.CodeNotFound, "user %s", id, sql.ErrNoRows)
+//
+// has the message "user 42", while Error returns the message followed by the
+// causes, for logging.
+func Errorf(code Code, format string, args ...any) *Error {
+	var values []any
+	var errs []error
+	var text []string
+	for _, a := range args {
+		if err, ok := a.(error); ok && err != nil {
+			errs = append(errs, err)
+			text = append(text, err.Error())
+		} else {
+			values = append(values, a)
+		}
+	}
+	e := NewError(code, fmt.Sprintf(format, values...))
+	switch len(errs) {
+	case 0:
+		return e
+	case 1:
+		e.Err = errs[0]
+	default:
+		e.Err = errors.Join(errs...)
+	}
+	e.text = e.Error() + ": " + strings.Join(text, ": ")
+	return e
+}
+
+// Wrap returns a copy of e with err as its cause. Message is unchanged,
+// while Error appends the text of err:
+//
+//	protokol.NewError(protokol.CodeUnavailable, "inventory unavailable").Wrap(err)
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	c.text = ""
+	if err != nil {
+		c.text = c.Error() + ": " + err.Error()
+	}
+	return &c
+}
+
+// Error returns the message, followed by the causes of errors created by
+// Errorf or Wrap, or the code name if there is no message.
+func (e *Error) Error() string {
+	if e.text != "" {
+		return e.text
+	}
+	if e.Message == "" {
+		return e.Code.String()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns a copy of e with details appended.
+func (e *Error) WithDetails(details ...any) *Error {
+	c := *e
+	c.Details = append(append([]any(nil), e.Details...), details...)
+	return &c
+}
+
+// ErrorCode returns the code of err: the code of the first *Error in its
+// chain, the code of a sentinel error it matches with errors.Is,
+// CodeCanceled or CodeDeadlineExceeded for context errors, and CodeInternal
+// for any other error. A nil error is CodeOK.
+func ErrorCode(err error) Code {
+	if err == nil {
+		return CodeOK
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	for _, s := range sentinels {
+		if errors.Is(err, s) {
+			return s.(*Error).Code
+		}
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	default:
+		return CodeInternal
+	}
+}
+
+// AsError converts err for reporting to a client. The result has the code
+// returned by ErrorCode, the details of the first *Error in the chain, and
+// err's message, so context added by wrapping is kept. It returns nil for a
+// nil error.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	out := &Error{Code: ErrorCode(err), Message: err.Error(), Err: err}
+	var e *Error
+	if errors.As(err, &e) {
+		out.Details = e.Details
+	}
+	return out
+}
+
+// ErrorDetail returns the first detail of type T in the chain of err.
+//
+//	if info, ok := protokol.ErrorDetail[*protokol.RetryInfo](err); ok {
+//	    time.Sleep(info.RetryDelay)
+//	}
+func ErrorDetail[T any](err error) (T, bool) {
+	for err != nil {
+		if e, ok := err.(*Error); ok {
+			for _, d := range e.Details {
+				if v, ok := d.(T); ok {
+					return v, true
+				}
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	var zero T
+	return zero, false
+}
+
+// BadRequest is an error detail listing invalid request fields.
+type BadRequest struct {
+	FieldViolations []FieldViolation `json:"fieldViolations"`
+}
+
+// FieldViolation describes one invalid field. Field is a path such as
+// "address.zip" or "tags[2]".
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// ErrorInfo is an error detail with a machine-readable reason, e.g.
+// "USER_SUSPENDED", and the domain defining it.
+type ErrorInfo struct {
+	Reason   string            `json:"reason"`
+	Domain   string            `json:"domain,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// RequestInfo is an error detail identifying the request, e.g. by the
+// correlation id under which an internal error was logged.
+type RequestInfo struct {
+	RequestID string `json:"requestId"`
+}
+
+// RetryInfo is an error detail telling the client how long to wait before
+// retrying.
+type RetryInfo struct {
+	RetryDelay time.Duration `json:"-"`
+}
+
+// MarshalJSON encodes the delay as a duration string, e.g. {"retryDelay":"1.5s"}.
+func (r RetryInfo) MarshalJSON() ([]byte, error) {
+	return fmt.Appendf(nil, `{"retryDelay":%q}`, r.RetryDelay.String()), nil
+}
@@ -0,0 +1,174 @@
+package protokol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+func TestErrorf(t *testing.T) {
+	errNoRows := errors.New("sql: no rows in result set")
+	notFound := NewError(CodeNotFound, "user not found")
+
+	tests := []struct {
+		name    string
+		err     *Error
+		message string
+		text    string
+		causes  []error
+	}{
+		{
+			name:    "no causes",
+			err:     Errorf(CodeNotFound, "user %s", "42"),
+			message: "user 42",
+			text:    "user 42",
+		},
+		{
+			name:    "cause left out of message",
+			err:     Errorf(CodeNotFound, "user %s", "42", errNoRows),
+			message: "user 42",
+			text:    "user 42: sql: no rows in result set",
+			causes:  []error{errNoRows},
+		},
+		{
+			name:    "cause before values",
+			err:     Errorf(CodeNotFound, "lookup (%s)", errNoRows, "42"),
+			message: "lookup (42)",
+			text:    "lookup (42): sql: no rows in result set",
+			causes:  []error{errNoRows},
+		},
+		{
+			name:    "several causes",
+			err:     Errorf(CodeInvalidArgument, "invalid input", ErrInvalidArgument, errNoRows),
+			message: "invalid input",
+			text:    "invalid input: protokol: invalid argument: sql: no rows in result set",
+			causes:  []error{ErrInvalidArgument, errNoRows},
+		},
+		{
+			name:    "percent sign",
+			err:     Errorf(CodeOutOfRange, "over %d%%", 100),
+			message: "over 100%",
+			text:    "over 100%",
+		},
+		{
+			name:    "wrap",
+			err:     notFound.Wrap(errNoRows),
+			message: "user not found",
+			text:    "user not found: sql: no rows in result set",
+			causes:  []error{errNoRows},
+		},
+		{
+			name:    "wrap nil",
+			err:     Errorf(CodeNotFound, "user", errNoRows).Wrap(nil),
+			message: "user",
+			text:    "user",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err.Message != tt.message {
+				t.Errorf("Message = %q, want %q", tt.err.Message, tt.message)
+			}
+			if got := tt.err.Error(); got != tt.text {
+				t.Errorf("Error() = %q, want %q", got, tt.text)
+			}
+			for _, c := range tt.causes {
+				if !errors.Is(tt.err, c) {
+					t.Errorf("errors.Is(err, %v) = false", c)
+				}
+			}
+			if len(tt.causes) == 0 && tt.err.Err != nil {
+				t.Errorf("Err = %v, want nil", tt.err.Err)
+			}
+		})
+	}
+
+	if notFound.Err != nil {
+		t.Error("Wrap modified its receiver")
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want Code
+	}{
+		{nil, CodeOK},
+		{NewError(CodeNotFound, "x"), CodeNotFound},
+		{fmt.Errorf("get: %w", NewError(CodePermissionDenied, "x")), CodePermissionDenied},
+		{fmt.Errorf("call: %w", ErrMethodNotFound), CodeUnimplemented},
+		{Errorf(CodeAborted, "retry", ErrInvalidArgument), CodeAborted},
+		{context.Canceled, CodeCanceled},
+		{fmt.Errorf("wait: %w", context.DeadlineExceeded), CodeDeadlineExceeded},
+		{io.EOF, CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.err), func(t *testing.T) {
+			if got := ErrorCode(tt.err); got != tt.want {
+				t.Errorf("ErrorCode = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsError(t *testing.T) {
+	detail := &ErrorInfo{Reason: "SUSPENDED"}
+	inner := NewError(CodeFailedPrecondition, "suspended").WithDetails(detail)
+	e := AsError(fmt.Errorf("login: %w", inner))
+	if e.Code != CodeFailedPrecondition || e.Message != "login: suspended" {
+		t.Errorf("AsError = %v %q", e.Code, e.Message)
+	}
+	if got, ok := ErrorDetail[*ErrorInfo](e); !ok || got != detail {
+		t.Errorf("ErrorDetail = %v, %v", got, ok)
+	}
+	if AsError(inner) != inner {
+		t.Error("AsError of an *Error returns a copy")
+	}
+	if AsError(nil) != nil {
+		t.Error("AsError(nil) != nil")
+	}
+}
+
+func TestCodeHTTPStatus(t *testing.T) {
+	tests := []struct {
+		code   Code
+		status int
+		back   Code
+	}{
+		{CodeInvalidArgument, http.StatusBadRequest, CodeInvalidArgument},
+		{CodeUnauthenticated, http.StatusUnauthorized, CodeUnauthenticated},
+		{CodePermissionDenied, http.StatusForbidden, CodePermissionDenied},
+		{CodeNotFound, http.StatusNotFound, CodeNotFound},
+		{CodeAlreadyExists, http.StatusConflict, CodeAlreadyExists},
+		{CodeResourceExhausted, http.StatusTooManyRequests, CodeResourceExhausted},
+		{CodeCanceled, 499, CodeCanceled},
+		{CodeUnimplemented, http.StatusNotImplemented, CodeUnimplemented},
+		{CodeUnavailable, http.StatusServiceUnavailable, CodeUnavailable},
+		{CodeDeadlineExceeded, http.StatusGatewayTimeout, CodeDeadlineExceeded},
+		{CodeInternal, http.StatusInternalServerError, CodeInternal},
+		{CodeDataLoss, http.StatusInternalServerError, CodeInternal},
+		{CodeAborted, http.StatusConflict, CodeAlreadyExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			if got := tt.code.HTTPStatus(); got != tt.status {
+				t.Errorf("HTTPStatus = %d, want %d", got, tt.status)
+			}
+			if got := CodeFromHTTPStatus(tt.status); got != tt.back {
+				t.Errorf("CodeFromHTTPStatus(%d) = %v, want %v", tt.status, got, tt.back)
+			}
+		})
+	}
+
+	for status, want := range map[int]Code{418: CodeFailedPrecondition, 507: CodeInternal, 302: CodeUnknown} {
+		if got := CodeFromHTTPStatus(status); got != want {
+			t.Errorf("CodeFromHTTPStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+	if got := Code(99).String(); got != "CODE(99)" {
+		t.Errorf("String = %q", got)
+	}
+}
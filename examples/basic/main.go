@@ -18,23 +18,31 @@ import (
 	"github.com/jekabolt/protokol/schema"
 )
 
+// User is the output of several methods. Its schema is derived from the
+// struct.
+type User struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+type GetUserRequest struct {
+	ID string `json:"id" protokol:"required"`
+}
+
 func main() {
 	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
 
 	p := protokol.New()
 
+	// User is shared by several methods, so it is registered once and
+	// referenced by name.
+	p.Schema().RegisterType("User", schema.MustFromStruct[User]())
+
 	userService := schema.NewService("UserService").
 		Backend("users").
 		Description("User management service").
-		Method(schema.Unary("GetUser").
-			Input(schema.Message("GetUserRequest").
-				RequiredField("id", schema.String).
-				Build()).
-			Output(schema.Message("User").
-				Field("id", schema.String).
-				Field("name", schema.String).
-				Field("email", schema.String).
-				Build()).
+		Method(schema.UnaryFor[GetUserRequest, User]("GetUser").
 			HTTP("GET", "/users/{id}").
 			Build()).
 		Method(schema.Unary("CreateUser").
@@ -42,11 +50,7 @@ func main() {
 				RequiredField("name", schema.String).
 				RequiredField("email", schema.String).
 				Build()).
-			Output(schema.Message("User").
-				Field("id", schema.String).
-				Field("name", schema.String).
-				Field("email", schema.String).
-				Build()).
+			Output(schema.Ref("User")).
 			HTTP("POST", "/users").
 			Build()).
 		Method(schema.Unary("ListUsers").
@@ -55,11 +59,7 @@ func main() {
 				Field("offset", schema.Int32).
 				Build()).
 			Output(schema.Message("ListUsersResponse").
-				Field("users", schema.Repeated(schema.Message("User").
-					Field("id", schema.String).
-					Field("name", schema.String).
-					Field("email", schema.String).
-					Build())).
+				Field("users", schema.Repeated(schema.Ref("User"))).
 				Field("total", schema.Int32).
 				Build()).
 			HTTP("GET", "/users").
@@ -70,13 +70,8 @@ func main() {
 
 	userBackend := backend.NewHandler()
 
-	userBackend.Register("UserService", "GetUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
-		id, _ := input["id"].(string)
-		return map[string]any{
-			"id":    id,
-			"name":  "John Doe",
-			"email": "john@example.com",
-		}, nil
+	backend.RegisterTyped(userBackend, "UserService", "GetUser", func(ctx context.Context, req GetUserRequest) (User, error) {
+		return User{ID: req.ID, Name: "John Doe", Email: "john@example.com"}, nil
 	})
 
 	userBackend.Register("UserService", "CreateUser", func(ctx context.Context, input map[string]any) (map[string]any, error) {
@@ -0,0 +1,109 @@
+// Package grpcstatus converts between protokol.Error and gRPC status,
+// translating the error details of the protokol package into their
+// google.rpc equivalents.
+package grpcstatus
+
+import (
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/jekabolt/protokol"
+)
+
+// ToStatus converts e to a gRPC status. Details of the protokol detail types
+// become google.rpc messages and proto messages are sent as they are; other
+// details are dropped.
+func ToStatus(e *protokol.Error) *status.Status {
+	p := &spb.Status{Code: int32(e.Code), Message: e.Error()}
+	for _, d := range e.Details {
+		m := toProto(d)
+		if m == nil {
+			continue
+		}
+		a, err := anypb.New(m)
+		if err != nil {
+			continue
+		}
+		p.Details = append(p.Details, a)
+	}
+	return status.FromProto(p)
+}
+
+// FromStatus converts st to a *protokol.Error with the same code and
+// message. google.rpc details become their protokol equivalents and other
+// details known to the proto registry are kept as proto messages.
+func FromStatus(st *status.Status) *protokol.Error {
+	e := protokol.NewError(protokol.Code(st.Code()), st.Message())
+	for _, a := range st.Proto().GetDetails() {
+		m, err := a.UnmarshalNew()
+		if err != nil {
+			continue
+		}
+		e.Details = append(e.Details, fromProto(m))
+	}
+	return e
+}
+
+func toProto(d any) proto.Message {
+	switch d := d.(type) {
+	case protokol.BadRequest:
+		return badRequest(&d)
+	case *protokol.BadRequest:
+		return badRequest(d)
+	case protokol.ErrorInfo:
+		return errorInfo(&d)
+	case *protokol.ErrorInfo:
+		return errorInfo(d)
+	case protokol.RetryInfo:
+		return &errdetails.RetryInfo{RetryDelay: durationpb.New(d.RetryDelay)}
+	case *protokol.RetryInfo:
+		return &errdetails.RetryInfo{RetryDelay: durationpb.New(d.RetryDelay)}
+	case protokol.RequestInfo:
+		return &errdetails.RequestInfo{RequestId: d.RequestID}
+	case *protokol.RequestInfo:
+		return &errdetails.RequestInfo{RequestId: d.RequestID}
+	case proto.Message:
+		return d
+	}
+	return nil
+}
+
+func badRequest(d *protokol.BadRequest) *errdetails.BadRequest {
+	br := &errdetails.BadRequest{}
+	for _, v := range d.FieldViolations {
+		br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       v.Field,
+			Description: v.Description,
+		})
+	}
+	return br
+}
+
+func errorInfo(d *protokol.ErrorInfo) *errdetails.ErrorInfo {
+	return &errdetails.ErrorInfo{Reason: d.Reason, Domain: d.Domain, Metadata: d.Metadata}
+}
+
+func fromProto(m proto.Message) any {
+	switch m := m.(type) {
+	case *errdetails.BadRequest:
+		br := &protokol.BadRequest{}
+		for _, v := range m.GetFieldViolations() {
+			br.FieldViolations = append(br.FieldViolations, protokol.FieldViolation{
+				Field:       v.GetField(),
+				Description: v.GetDescription(),
+			})
+		}
+		return br
+	case *errdetails.ErrorInfo:
+		return &protokol.ErrorInfo{Reason: m.GetReason(), Domain: m.GetDomain(), Metadata: m.GetMetadata()}
+	case *errdetails.RetryInfo:
+		return &protokol.RetryInfo{RetryDelay: m.GetRetryDelay().AsDuration()}
+	case *errdetails.RequestInfo:
+		return &protokol.RequestInfo{RequestID: m.GetRequestId()}
+	}
+	return m
+}
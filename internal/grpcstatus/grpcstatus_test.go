@@ -0,0 +1,91 @@
+package grpcstatus
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/jekabolt/protokol"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		detail  any
+		want    string
+		dropped bool
+	}{
+		{
+			name:   "bad request",
+			detail: protokol.BadRequest{FieldViolations: []protokol.FieldViolation{{Field: "id", Description: "required"}}},
+			want:   "&{FieldViolations:[{Field:id Description:required}]}",
+		},
+		{
+			name:   "error info",
+			detail: &protokol.ErrorInfo{Reason: "QUOTA", Domain: "acme.com", Metadata: map[string]string{"k": "v"}},
+			want:   "&{Reason:QUOTA Domain:acme.com Metadata:map[k:v]}",
+		},
+		{
+			name:   "retry info",
+			detail: protokol.RetryInfo{RetryDelay: 3 * time.Second},
+			want:   "&{RetryDelay:3s}",
+		},
+		{
+			name:   "request info",
+			detail: &protokol.RequestInfo{RequestID: "r1"},
+			want:   "&{RequestID:r1}",
+		},
+		{
+			name:   "proto message",
+			detail: wrapperspb.String("x"),
+			want:   "x",
+		},
+		{
+			name:    "other details are dropped",
+			detail:  map[string]any{"k": "v"},
+			dropped: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := protokol.NewError(protokol.CodeFailedPrecondition, "precondition failed").WithDetails(tt.detail)
+			st := ToStatus(in)
+			if st.Code() != codes.FailedPrecondition || st.Message() != "precondition failed" {
+				t.Errorf("status = %v %q", st.Code(), st.Message())
+			}
+
+			out := FromStatus(st)
+			if out.Code != protokol.CodeFailedPrecondition || out.Message != "precondition failed" {
+				t.Errorf("error = %v %q", out.Code, out.Message)
+			}
+			if tt.dropped {
+				if len(out.Details) != 0 {
+					t.Errorf("details = %v, want none", out.Details)
+				}
+				return
+			}
+			if len(out.Details) != 1 {
+				t.Fatalf("details = %v, want one", out.Details)
+			}
+			got := fmt.Sprintf("%+v", out.Details[0])
+			// Proto text output is not stable, so compare the value.
+			if w, ok := out.Details[0].(*wrapperspb.StringValue); ok {
+				got = w.GetValue()
+			}
+			if got != tt.want {
+				t.Errorf("detail = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCodes(t *testing.T) {
+	for code := protokol.CodeOK; code <= protokol.CodeUnauthenticated; code++ {
+		if got := ToStatus(protokol.NewError(code, "x")).Code(); uint32(got) != uint32(code) {
+			t.Errorf("ToStatus(%v) = %v", code, got)
+		}
+	}
+}
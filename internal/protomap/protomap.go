@@ -0,0 +1,359 @@
+// Package protomap converts between protobuf messages and the map form used
+// by protokol.Request and protokol.Response.
+package protomap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ToMap converts a protobuf message into the map form used by
+// protokol.Request and protokol.Response. Enums are rendered by name. Only
+// populated fields are included, so proto3 scalars holding their zero value
+// are omitted.
+func ToMap(m protoreflect.Message) map[string]any {
+	return converter{}.toMap(m)
+}
+
+// FromMap populates a protobuf message from a map. Keys that do not match a
+// field by proto or JSON name are ignored.
+func FromMap(in map[string]any, m protoreflect.Message) error {
+	return converter{}.fromMap(in, m)
+}
+
+// EnumNames maps the full names of enum values to the names used in the map
+// form, for descriptors whose value names differ from the schema's. Values
+// not in the map go by their descriptor names.
+type EnumNames map[protoreflect.FullName]string
+
+// ToMap is like the package's ToMap, naming enum values after n.
+func (n EnumNames) ToMap(m protoreflect.Message) map[string]any {
+	return converter{names: n}.toMap(m)
+}
+
+// FromMap is like the package's FromMap, accepting enum values named after
+// n as well as by their descriptor names.
+func (n EnumNames) FromMap(in map[string]any, m protoreflect.Message) error {
+	return converter{names: n}.fromMap(in, m)
+}
+
+// converter holds the options of a conversion.
+type converter struct {
+	names EnumNames
+}
+
+func (c converter) toMap(m protoreflect.Message) map[string]any {
+	out := make(map[string]any)
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		out[string(fd.Name())] = c.fieldToAny(fd, v)
+		return true
+	})
+	return out
+}
+
+func (c converter) fieldToAny(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]any, list.Len())
+		for i := range out {
+			out[i] = c.singularToAny(fd, list.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := make(map[string]any, v.Map().Len())
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			out[k.String()] = c.singularToAny(fd.MapValue(), mv)
+			return true
+		})
+		return out
+	default:
+		return c.singularToAny(fd, v)
+	}
+}
+
+func (c converter) singularToAny(fd protoreflect.FieldDescriptor, v protoreflect.Value) any {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			if name, ok := c.names[ev.FullName()]; ok {
+				return name
+			}
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return c.toMap(v.Message())
+	default:
+		return v.Interface()
+	}
+}
+
+func (c converter) fromMap(in map[string]any, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	for k, v := range in {
+		if v == nil {
+			continue
+		}
+		fd := fields.ByName(protoreflect.Name(k))
+		if fd == nil {
+			fd = fields.ByJSONName(k)
+		}
+		if fd == nil {
+			continue
+		}
+		if err := c.setField(m, fd, v); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+func (c converter) setField(m protoreflect.Message, fd protoreflect.FieldDescriptor, v any) error {
+	rv := reflect.ValueOf(v)
+	switch {
+	case fd.IsList():
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return fmt.Errorf("expected list, got %T", v)
+		}
+		list := m.Mutable(fd).List()
+		for i := range rv.Len() {
+			ev, err := c.toValue(fd, rv.Index(i).Interface(), list.NewElement)
+			if err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+			list.Append(ev)
+		}
+		return nil
+
+	case fd.IsMap():
+		if rv.Kind() != reflect.Map {
+			return fmt.Errorf("expected map, got %T", v)
+		}
+		mp := m.Mutable(fd).Map()
+		iter := rv.MapRange()
+		for iter.Next() {
+			kv, err := c.toValue(fd.MapKey(), iter.Key().Interface(), nil)
+			if err != nil {
+				return fmt.Errorf("key %v: %w", iter.Key(), err)
+			}
+			vv, err := c.toValue(fd.MapValue(), iter.Value().Interface(), mp.NewValue)
+			if err != nil {
+				return fmt.Errorf("[%v]: %w", iter.Key(), err)
+			}
+			mp.Set(kv.MapKey(), vv)
+		}
+		return nil
+
+	default:
+		val, err := c.toValue(fd, v, func() protoreflect.Value { return m.NewField(fd) })
+		if err != nil {
+			return err
+		}
+		m.Set(fd, val)
+		return nil
+	}
+}
+
+// toValue converts a Go value into a protoreflect.Value for the given field
+// kind. newMsg allocates the message for message-typed values.
+func (c converter) toValue(fd protoreflect.FieldDescriptor, v any, newMsg func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		switch b := v.(type) {
+		case bool:
+			return protoreflect.ValueOfBool(b), nil
+		case string:
+			parsed, err := strconv.ParseBool(b)
+			if err != nil {
+				return protoreflect.Value{}, err
+			}
+			return protoreflect.ValueOfBool(parsed), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("expected bool, got %T", v)
+
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		n, err := toInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return protoreflect.Value{}, fmt.Errorf("value %d overflows int32", n)
+		}
+		return protoreflect.ValueOfInt32(int32(n)), nil
+
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		n, err := toInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfInt64(n), nil
+
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		n, err := toInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if n < 0 || n > math.MaxUint32 {
+			return protoreflect.Value{}, fmt.Errorf("value %d overflows uint32", n)
+		}
+		return protoreflect.ValueOfUint32(uint32(n)), nil
+
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		n, err := toInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if n < 0 {
+			return protoreflect.Value{}, fmt.Errorf("value %d overflows uint64", n)
+		}
+		return protoreflect.ValueOfUint64(uint64(n)), nil
+
+	case protoreflect.FloatKind:
+		f, err := toFloat64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfFloat32(float32(f)), nil
+
+	case protoreflect.DoubleKind:
+		f, err := toFloat64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfFloat64(f), nil
+
+	case protoreflect.StringKind:
+		switch s := v.(type) {
+		case string:
+			return protoreflect.ValueOfString(s), nil
+		case fmt.Stringer:
+			return protoreflect.ValueOfString(s.String()), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("expected string, got %T", v)
+
+	case protoreflect.BytesKind:
+		switch b := v.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		case string:
+			// Bytes arriving through JSON are base64 encoded.
+			decoded, err := base64.StdEncoding.DecodeString(b)
+			if err != nil {
+				return protoreflect.ValueOfBytes([]byte(b)), nil
+			}
+			return protoreflect.ValueOfBytes(decoded), nil
+		}
+		return protoreflect.Value{}, fmt.Errorf("expected bytes, got %T", v)
+
+	case protoreflect.EnumKind:
+		if s, ok := v.(string); ok {
+			ev := c.enumValue(fd.Enum(), s)
+			if ev == nil {
+				return protoreflect.Value{}, fmt.Errorf("unknown enum value %q", s)
+			}
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		n, err := toInt64(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(n)), nil
+
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		msg := newMsg()
+		fields, err := toStringMap(v)
+		if err != nil {
+			return protoreflect.Value{}, err
+		}
+		if err := c.fromMap(fields, msg.Message()); err != nil {
+			return protoreflect.Value{}, err
+		}
+		return msg, nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", fd.Kind())
+}
+
+// enumValue returns the value of ed named name in the map form, or nil.
+func (c converter) enumValue(ed protoreflect.EnumDescriptor, name string) protoreflect.EnumValueDescriptor {
+	values := ed.Values()
+	if len(c.names) > 0 {
+		for i := range values.Len() {
+			ev := values.Get(i)
+			if n, ok := c.names[ev.FullName()]; ok && n == name {
+				return ev
+			}
+		}
+	}
+	return values.ByName(protoreflect.Name(name))
+}
+
+func toInt64(v any) (int64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows int64", u)
+		}
+		return int64(u), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v is not an integer", f)
+		}
+		return int64(f), nil
+	}
+	return 0, fmt.Errorf("expected integer, got %T", v)
+}
+
+func toFloat64(v any) (float64, error) {
+	switch n := v.(type) {
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("expected number, got %T", v)
+}
+
+// toStringMap accepts map[string]any as well as other string-keyed maps such
+// as map[string]string that handlers commonly return.
+func toStringMap(v any) (map[string]any, error) {
+	if m, ok := v.(map[string]any); ok {
+		return m, nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("expected object, got %T", v)
+	}
+	out := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		out[iter.Key().String()] = iter.Value().Interface()
+	}
+	return out, nil
+}
@@ -0,0 +1,146 @@
+package protomap
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const userProto = `syntax = "proto3";
+package acme.v1;
+
+message User {
+  enum Role {
+    ROLE_USER = 0;
+    ROLE_ADMIN = 1;
+  }
+  int64 id = 1;
+  string name = 2;
+  optional int32 age = 3;
+  bool active = 4;
+  bytes avatar = 5;
+  Role role = 6;
+  repeated string tags = 7;
+  map<string, int64> counts = 8;
+  User manager = 9;
+  uint32 level = 10;
+}
+`
+
+func userDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	compiler := protocompile.Compiler{
+		Resolver: &protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{"user.proto": userProto}),
+		},
+	}
+	files, err := compiler.Compile(context.Background(), "user.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files[0].Messages().ByName("User")
+}
+
+func TestRoundTrip(t *testing.T) {
+	md := userDescriptor(t)
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		want  string
+	}{
+		{
+			name:  "scalars from JSON",
+			input: map[string]any{"id": float64(7), "name": "ada", "active": true, "level": "3"},
+			want:  "map[active:true id:7 level:3 name:ada]",
+		},
+		{
+			name:  "zero scalars are omitted",
+			input: map[string]any{"id": 0, "name": "", "active": false},
+			want:  "map[]",
+		},
+		{
+			name:  "zero optional scalar is kept",
+			input: map[string]any{"age": 0},
+			want:  "map[age:0]",
+		},
+		{
+			name:  "enum by name and number",
+			input: map[string]any{"role": "ROLE_ADMIN", "manager": map[string]any{"role": 1}},
+			want:  "map[manager:map[role:ROLE_ADMIN] role:ROLE_ADMIN]",
+		},
+		{
+			name:  "base64 bytes",
+			input: map[string]any{"avatar": "aGk="},
+			want:  "map[avatar:[104 105]]",
+		},
+		{
+			name:  "repeated and map fields",
+			input: map[string]any{"tags": []string{"a", "b"}, "counts": map[string]int{"x": 1}},
+			want:  "map[counts:map[x:1] tags:[a b]]",
+		},
+		{
+			name:  "unknown keys and nulls are ignored",
+			input: map[string]any{"nickname": "x", "name": nil},
+			want:  "map[]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := dynamicpb.NewMessage(md)
+			if err := FromMap(tt.input, m); err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprint(ToMap(m)); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFromMapErrors(t *testing.T) {
+	md := userDescriptor(t)
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		want  string
+	}{
+		{"wrong kind", map[string]any{"name": 1}, "name: expected string, got int"},
+		{"int32 overflow", map[string]any{"age": int64(1) << 40}, "age: value 1099511627776 overflows int32"},
+		{"negative unsigned", map[string]any{"level": -1}, "level: value -1 overflows uint32"},
+		{"fractional integer", map[string]any{"id": 1.5}, "id: value 1.5 is not an integer"},
+		{"unknown enum value", map[string]any{"role": "OWNER"}, `role: unknown enum value "OWNER"`},
+		{"list element", map[string]any{"tags": []any{"a", 1}}, "tags: [1]: expected string, got int"},
+		{"nested message", map[string]any{"manager": "ada"}, "manager: expected object, got string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := FromMap(tt.input, dynamicpb.NewMessage(md))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnumNames(t *testing.T) {
+	md := userDescriptor(t)
+	names := EnumNames{"acme.v1.User.ROLE_ADMIN": "ADMIN"}
+
+	m := dynamicpb.NewMessage(md)
+	if err := names.FromMap(map[string]any{"role": "ADMIN"}, m); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(names.ToMap(m)); got != "map[role:ADMIN]" {
+		t.Errorf("got %s, want map[role:ADMIN]", got)
+	}
+	if got := fmt.Sprint(ToMap(m)); got != "map[role:ROLE_ADMIN]" {
+		t.Errorf("without names got %s, want map[role:ROLE_ADMIN]", got)
+	}
+}
@@ -2,7 +2,6 @@ package auth
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strings"
 
@@ -10,10 +9,11 @@ import (
 	"github.com/jekabolt/protokol/adapters"
 )
 
+// Errors returned by the middleware, with code CodeUnauthenticated.
 var (
-	ErrUnauthorized   = errors.New("unauthorized")
-	ErrInvalidToken   = errors.New("invalid token")
-	ErrMissingToken   = errors.New("missing authorization token")
+	ErrUnauthorized error = protokol.NewError(protokol.CodeUnauthenticated, "unauthorized")
+	ErrInvalidToken error = protokol.NewError(protokol.CodeUnauthenticated, "invalid token")
+	ErrMissingToken error = protokol.NewError(protokol.CodeUnauthenticated, "missing authorization token")
 )
 
 type contextKey struct{}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+)
+
+func TestMiddleware(t *testing.T) {
+	next := adapters.HandlerFunc(func(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+		user, _ := UserFromContext(ctx)
+		return &protokol.Response{Output: map[string]any{"user": user}}, nil
+	})
+	h := New(APIKey("k1")).Wrap(next)
+
+	tests := []struct {
+		name   string
+		header []string
+		err    error
+	}{
+		{name: "valid key", header: []string{"Bearer k1"}},
+		{name: "missing header", err: ErrMissingToken},
+		{name: "wrong scheme", header: []string{"Basic k1"}, err: ErrInvalidToken},
+		{name: "unknown key", header: []string{"Bearer k2"}, err: ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &protokol.Request{Service: "Users", Method: "GetUser", Metadata: map[string][]string{}}
+			if tt.header != nil {
+				req.Metadata["Authorization"] = tt.header
+			}
+			resp, err := h.Handle(context.Background(), req)
+			if tt.err == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.Output["user"] != "k1" {
+					t.Errorf("user = %v, want k1", resp.Output["user"])
+				}
+				return
+			}
+			if !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if code := protokol.ErrorCode(err); code != protokol.CodeUnauthenticated {
+				t.Errorf("code = %v, want %v", code, protokol.CodeUnauthenticated)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package ratelimit
 
 import (
 	"context"
-	"errors"
 	"hash/fnv"
 	"net"
 	"strings"
@@ -13,7 +12,9 @@ import (
 	"github.com/jekabolt/protokol/adapters"
 )
 
-var ErrRateLimited = errors.New("rate limit exceeded")
+// ErrRateLimited is returned when a client has no tokens left. Its code is
+// CodeResourceExhausted.
+var ErrRateLimited error = protokol.NewError(protokol.CodeResourceExhausted, "rate limit exceeded")
 
 const (
 	// defaultShards is the number of shards for the bucket map.
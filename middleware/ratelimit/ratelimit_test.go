@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+)
+
+func TestMiddleware(t *testing.T) {
+	next := adapters.HandlerFunc(func(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+		return &protokol.Response{}, nil
+	})
+	m := New(0.001, 2, ByIP)
+	defer m.Stop()
+	h := m.Wrap(next)
+
+	tests := []struct {
+		name string
+		addr string
+		err  error
+	}{
+		{name: "first request", addr: "10.0.0.1:5000"},
+		{name: "within the burst", addr: "10.0.0.1:5001"},
+		{name: "over the burst", addr: "10.0.0.1:5002", err: ErrRateLimited},
+		{name: "another client", addr: "10.0.0.2:5000"},
+		{name: "no key bypasses the limit"},
+		{name: "no key again"},
+		{name: "no key once more"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Handle(context.Background(), &protokol.Request{Service: "Users", Method: "GetUser", RemoteAddr: tt.addr})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want %v", err, tt.err)
+			}
+			if tt.err != nil && protokol.ErrorCode(err) != protokol.CodeResourceExhausted {
+				t.Errorf("code = %v, want %v", protokol.ErrorCode(err), protokol.CodeResourceExhausted)
+			}
+		})
+	}
+}
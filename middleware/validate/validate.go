@@ -0,0 +1,45 @@
+// Package validate provides schema-driven input validation middleware.
+package validate
+
+import (
+	"context"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/transform"
+)
+
+// Middleware checks request input against the method's input type and
+// rejects invalid requests with a *transform.ValidationError.
+//
+// Adapters already validate input right before the backend is called. Use
+// this middleware with adapters.Config.SkipValidation to validate at a
+// different point in the chain, for example before an expensive middleware.
+type Middleware struct {
+	schema *schema.Schema
+}
+
+// New creates a validation middleware for s.
+func New(s *schema.Schema) *Middleware {
+	return &Middleware{schema: s}
+}
+
+// Wrap returns a handler that validates the input of requests for known
+// methods. Requests for unknown methods are passed through unchanged.
+func (m *Middleware) Wrap(next adapters.Handler) adapters.Handler {
+	return adapters.HandlerFunc(func(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+		svc, ok := m.schema.ServiceByName(req.Service)
+		if !ok {
+			return next.Handle(ctx, req)
+		}
+		method, ok := svc.MethodByName(req.Method)
+		if !ok || method.IsClientStreaming() {
+			return next.Handle(ctx, req)
+		}
+		if err := transform.Validate(m.schema, method.Input, req.Input); err != nil {
+			return nil, err
+		}
+		return next.Handle(ctx, req)
+	})
+}
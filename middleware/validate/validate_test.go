@@ -0,0 +1,56 @@
+package validate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/adapters"
+	"github.com/jekabolt/protokol/schema"
+	"github.com/jekabolt/protokol/transform"
+)
+
+func TestMiddleware(t *testing.T) {
+	user := schema.Message("User").RequiredField("id", schema.Int64).Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Backend("test").
+		Method(schema.Unary("GetUser").Input(user).Output(user).Build()).
+		Method(schema.ClientStream("Upload").Input(user).Output(user).Build()).
+		MustBuild())
+
+	tests := []struct {
+		name    string
+		service string
+		method  string
+		input   map[string]any
+		invalid bool
+	}{
+		{name: "valid", service: "Users", method: "GetUser", input: map[string]any{"id": 1}},
+		{name: "invalid", service: "Users", method: "GetUser", input: map[string]any{}, invalid: true},
+		{name: "client stream", service: "Users", method: "Upload", input: map[string]any{}},
+		{name: "unknown method", service: "Users", method: "DeleteUser", input: map[string]any{}},
+		{name: "unknown service", service: "Orders", method: "GetOrder", input: map[string]any{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			next := adapters.HandlerFunc(func(ctx context.Context, req *protokol.Request) (*protokol.Response, error) {
+				called = true
+				return &protokol.Response{}, nil
+			})
+			_, err := New(s).Wrap(next).Handle(context.Background(), &protokol.Request{
+				Service: tt.service,
+				Method:  tt.method,
+				Input:   tt.input,
+			})
+			var verr *transform.ValidationError
+			if got := errors.As(err, &verr); got != tt.invalid {
+				t.Errorf("err = %v, want validation error %v", err, tt.invalid)
+			}
+			if called == tt.invalid {
+				t.Errorf("next called = %v", called)
+			}
+		})
+	}
+}
@@ -55,14 +55,20 @@ func (p *Protokol) AddAdapter(a Adapter) {
 	p.adapters = append(p.adapters, a)
 }
 
-// Run starts all registered adapters and blocks until the context is cancelled
-// or an adapter returns an error. Returns ErrAlreadyRunning if already running.
+// Run validates the schema, starts all registered adapters and blocks until
+// the context is cancelled or an adapter returns an error. Returns
+// ErrAlreadyRunning if already running, or the *schema.ValidationError if the
+// schema is inconsistent.
 func (p *Protokol) Run(ctx context.Context) error {
 	p.mu.Lock()
 	if p.running {
 		p.mu.Unlock()
 		return ErrAlreadyRunning
 	}
+	if err := p.schema.Validate(); err != nil {
+		p.mu.Unlock()
+		return err
+	}
 	p.running = true
 	p.mu.Unlock()
 
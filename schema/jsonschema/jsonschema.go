@@ -0,0 +1,250 @@
+// Package jsonschema converts schema types to JSON Schema (draft 2020-12),
+// so that payloads can be described and validated outside of Go.
+package jsonschema
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// Draft is the JSON Schema dialect of generated documents.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// integerPattern matches the decimal string form of 64-bit integers, which
+// are accepted as strings because JSON numbers lose precision above 2^53.
+const integerPattern = `^-?[0-9]+$`
+
+// Generate returns a JSON Schema document for t. Named messages and enums
+// are placed in "$defs" and referenced by name, so recursive types are
+// supported; a named t is itself a "$ref" to its definition. Type references
+// are resolved against s, which may be nil if t contains none.
+func Generate(s *schema.Schema, t schema.Type) map[string]any {
+	g := newGenerator(s)
+	doc := g.schema(t)
+	doc["$schema"] = Draft
+	if len(g.defs) > 0 {
+		doc["$defs"] = g.defs
+	}
+	return doc
+}
+
+// Marshal returns the indented JSON encoding of the document for t.
+func Marshal(s *schema.Schema, t schema.Type) ([]byte, error) {
+	return json.MarshalIndent(Generate(s, t), "", "  ")
+}
+
+// Definitions returns a document whose "$defs" hold every named message and
+// enum in s: the registered types and the inputs and outputs of all methods,
+// including the types they reference. Unnamed method messages are defined as
+// {Method}Request and {Method}Response.
+func Definitions(s *schema.Schema) map[string]any {
+	g := newGenerator(s)
+	for name, t := range s.Types {
+		if t.Name == "" {
+			t.Name = name
+		}
+		g.define(t)
+	}
+	for _, svc := range s.Services {
+		for _, m := range svc.Methods {
+			g.define(named(g.resolve(m.Input), m.Name+"Request"))
+			g.define(named(g.resolve(m.Output), m.Name+"Response"))
+		}
+	}
+	return map[string]any{"$schema": Draft, "$defs": g.defs}
+}
+
+// WriteFiles writes one self-contained document per named type in
+// Definitions(s) to dir, named {Type}.json.
+func WriteFiles(s *schema.Schema, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	defs := Definitions(s)["$defs"].(map[string]any)
+	for name := range defs {
+		doc := map[string]any{"$schema": Draft, "$ref": "#/$defs/" + name, "$defs": reachable(defs, name)}
+		data, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name+".json"), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generator converts schema types, collecting named messages and enums as
+// definitions.
+type generator struct {
+	types *schema.Schema
+	defs  map[string]any
+}
+
+func newGenerator(s *schema.Schema) *generator {
+	return &generator{types: s, defs: make(map[string]any)}
+}
+
+// resolve follows type references. Unknown references resolve to the
+// invalid type, which accepts any value.
+func (g *generator) resolve(t schema.Type) schema.Type {
+	t, ok := g.types.Resolve(t)
+	if !ok {
+		return schema.Type{}
+	}
+	return t
+}
+
+// schema returns the JSON Schema for t, referencing a definition for named
+// messages and enums.
+func (g *generator) schema(t schema.Type) map[string]any {
+	switch t.Kind {
+	case schema.KindBool:
+		return map[string]any{"type": "boolean"}
+	case schema.KindInt32:
+		return map[string]any{"type": "integer", "minimum": math.MinInt32, "maximum": math.MaxInt32}
+	case schema.KindInt64:
+		return map[string]any{"type": []any{"integer", "string"}, "pattern": integerPattern}
+	case schema.KindFloat32, schema.KindFloat64:
+		return map[string]any{"type": "number"}
+	case schema.KindString:
+		return map[string]any{"type": "string"}
+	case schema.KindBytes:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case schema.KindRepeated:
+		s := map[string]any{"type": "array"}
+		if t.Elem != nil {
+			s["items"] = g.schema(*t.Elem)
+		}
+		return s
+	case schema.KindMap:
+		s := map[string]any{"type": "object"}
+		if t.Elem != nil {
+			s["additionalProperties"] = g.schema(*t.Elem)
+		}
+		if t.Key != nil {
+			if names := keySchema(*t.Key); names != nil {
+				s["propertyNames"] = names
+			}
+		}
+		return s
+	case schema.KindMessage, schema.KindEnum:
+		if t.Name == "" {
+			return g.inline(t)
+		}
+		g.define(t)
+		return map[string]any{"$ref": "#/$defs/" + t.Name}
+	case schema.KindRef:
+		if r := g.resolve(t); r.Kind != schema.KindInvalid {
+			return g.schema(r)
+		}
+		return map[string]any{}
+	default:
+		return map[string]any{}
+	}
+}
+
+// define registers a named message or enum. The first definition of a name
+// wins.
+func (g *generator) define(t schema.Type) {
+	if t.Name == "" || (t.Kind != schema.KindMessage && t.Kind != schema.KindEnum) {
+		return
+	}
+	if _, ok := g.defs[t.Name]; ok {
+		return
+	}
+	// Reserve the name before descending so recursive types terminate.
+	g.defs[t.Name] = map[string]any{}
+	s := g.inline(t)
+	s["title"] = t.Name
+	g.defs[t.Name] = s
+}
+
+func (g *generator) inline(t schema.Type) map[string]any {
+	if t.Kind == schema.KindEnum {
+		names := make([]any, len(t.Values))
+		for i, v := range t.Values {
+			names[i] = v.Name
+		}
+		return map[string]any{"type": "string", "enum": names}
+	}
+
+	props := make(map[string]any, len(t.Fields))
+	required := []string{}
+	for _, f := range t.Fields {
+		props[f.Name] = g.field(f)
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func (g *generator) field(f schema.Field) map[string]any {
+	s := g.schema(f.Type)
+	if f.Default != nil {
+		// Keywords next to $ref apply alongside it in draft 2020-12.
+		s["default"] = f.Default
+	}
+	if f.Description != "" {
+		s["description"] = f.Description
+	}
+	return s
+}
+
+// keySchema constrains map keys, which are always strings in JSON, to the
+// textual form of the key type.
+func keySchema(t schema.Type) map[string]any {
+	switch t.Kind {
+	case schema.KindInt32, schema.KindInt64:
+		return map[string]any{"pattern": integerPattern}
+	case schema.KindBool:
+		return map[string]any{"enum": []any{"true", "false"}}
+	}
+	return nil
+}
+
+// named gives an unnamed message type the fallback name.
+func named(t schema.Type, fallback string) schema.Type {
+	if t.Kind == schema.KindMessage && t.Name == "" {
+		t.Name = fallback
+	}
+	return t
+}
+
+// reachable returns defs[name] and every definition it references,
+// directly or indirectly.
+func reachable(defs map[string]any, name string) map[string]any {
+	out := make(map[string]any)
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/$defs/")
+				if _, seen := out[name]; !seen {
+					out[name] = defs[name]
+					walk(defs[name])
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(map[string]any{"$ref": "#/$defs/" + name})
+	return out
+}
@@ -0,0 +1,137 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+func encode(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestGenerate(t *testing.T) {
+	role := schema.Enum("Role").Value("USER", 0).Value("ADMIN", 1).Build()
+
+	tests := []struct {
+		name string
+		typ  schema.Type
+		want string
+	}{
+		{"bool", schema.Bool, `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"boolean"}`},
+		{"int32", schema.Int32, `{"$schema":"https://json-schema.org/draft/2020-12/schema","maximum":2147483647,"minimum":-2147483648,"type":"integer"}`},
+		{"int64", schema.Int64, `{"$schema":"https://json-schema.org/draft/2020-12/schema","pattern":"^-?[0-9]+$","type":["integer","string"]}`},
+		{"bytes", schema.Bytes, `{"$schema":"https://json-schema.org/draft/2020-12/schema","contentEncoding":"base64","type":"string"}`},
+		{"repeated", schema.Repeated(schema.String), `{"$schema":"https://json-schema.org/draft/2020-12/schema","items":{"type":"string"},"type":"array"}`},
+		{"map with integer keys", schema.Map(schema.Int64, schema.Bool), `{"$schema":"https://json-schema.org/draft/2020-12/schema","additionalProperties":{"type":"boolean"},"propertyNames":{"pattern":"^-?[0-9]+$"},"type":"object"}`},
+		{"named enum", role, `{"$defs":{"Role":{"enum":["USER","ADMIN"],"title":"Role","type":"string"}},"$ref":"#/$defs/Role","$schema":"https://json-schema.org/draft/2020-12/schema"}`},
+		{
+			"unnamed message",
+			schema.Message("").
+				RequiredField("id", schema.String).
+				FieldWithDefault("role", role, "USER").
+				Build(),
+			`{"$defs":{"Role":{"enum":["USER","ADMIN"],"title":"Role","type":"string"}},"$schema":"https://json-schema.org/draft/2020-12/schema","properties":{"id":{"type":"string"},"role":{"$ref":"#/$defs/Role","default":"USER"}},"required":["id"],"type":"object"}`,
+		},
+		{"unresolved reference", schema.Ref("Missing"), `{"$schema":"https://json-schema.org/draft/2020-12/schema"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encode(t, Generate(schema.NewSchema(), tt.typ)); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateRecursive(t *testing.T) {
+	s := schema.NewSchema()
+	s.RegisterType("Node", schema.Message("Node").
+		Field("children", schema.Repeated(schema.Ref("Node"))).
+		Build())
+	got := encode(t, Generate(s, schema.Ref("Node")))
+	want := `{"$defs":{"Node":{"properties":{"children":{"items":{"$ref":"#/$defs/Node"},"type":"array"}},"title":"Node","type":"object"}},"$ref":"#/$defs/Node","$schema":"https://json-schema.org/draft/2020-12/schema"}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func usersSchema() *schema.Schema {
+	s := schema.NewSchema()
+	s.RegisterType("Address", schema.Message("Address").Field("city", schema.String).Build())
+	user := schema.Message("User").
+		Field("id", schema.String).
+		Field("address", schema.Ref("Address")).
+		Build()
+	s.AddService(schema.NewService("Users").Backend("b").
+		Method(schema.Unary("GetUser").
+			Input(schema.Message("").Field("id", schema.String).Build()).
+			Output(user).
+			Build()).
+		MustBuild())
+	return s
+}
+
+func TestDefinitions(t *testing.T) {
+	defs := Definitions(usersSchema())["$defs"].(map[string]any)
+	var names []string
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if got := encode(t, names); got != `["Address","GetUserRequest","User"]` {
+		t.Errorf("definitions = %s", got)
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteFiles(usersSchema(), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		file string
+		defs string
+	}{
+		{"Address.json", `["Address"]`},
+		{"GetUserRequest.json", `["GetUserRequest"]`},
+		{"User.json", `["Address","User"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Ref  string                     `json:"$ref"`
+				Defs map[string]json.RawMessage `json:"$defs"`
+			}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for name := range doc.Defs {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if got := encode(t, names); got != tt.defs {
+				t.Errorf("$defs = %s, want %s", got, tt.defs)
+			}
+			if want := "#/$defs/" + strings.TrimSuffix(tt.file, ".json"); doc.Ref != want {
+				t.Errorf("$ref = %s, want %s", doc.Ref, want)
+			}
+		})
+	}
+}
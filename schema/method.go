@@ -20,7 +20,9 @@ type Method struct {
 	Description string
 	HTTPMethod  string
 	HTTPPath    string
-	Options     map[string]any
+	// Backend overrides Service.Backend for this method when set.
+	Backend string
+	Options map[string]any
 }
 
 // IsStreaming returns true if the method uses any form of streaming.
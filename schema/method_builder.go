@@ -52,6 +52,12 @@ func (b *MethodBuilder) HTTP(method, path string) *MethodBuilder {
 	return b
 }
 
+// Backend routes the method to the named backend instead of the service's.
+func (b *MethodBuilder) Backend(name string) *MethodBuilder {
+	b.method.Backend = name
+	return b
+}
+
 // Description sets a human-readable description for the method.
 func (b *MethodBuilder) Description(desc string) *MethodBuilder {
 	b.method.Description = desc
@@ -0,0 +1,509 @@
+package protofile
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// fileBuilder accumulates the descriptor for a single proto package.
+type fileBuilder struct {
+	schema   *schema.Schema
+	pkg      string
+	file     *descriptorpb.FileDescriptorProto
+	messages map[string]*descriptorpb.DescriptorProto
+	enums    map[string]*descriptorpb.EnumDescriptorProto
+	// types holds the definition of each message and enum by name.
+	types map[string]schema.Type
+	// values holds the enum value names used in the package scope.
+	values map[string]bool
+	// enumNames maps renamed enum values to their schema names.
+	enumNames map[protoreflect.FullName]string
+	// comments holds service, method and field descriptions by full name.
+	comments map[string]string
+}
+
+// Files converts the schema into one file descriptor per service package.
+// Message and enum types are emitted once per package by name; different
+// definitions of a name are an error. Enum values share the package scope,
+// so the values of an enum that would collide with another enum's are
+// prefixed with its name, e.g. STATUS_ACTIVE; see EnumValueNames. Methods
+// with an explicit HTTP mapping carry a google.api.http option, and the
+// files it depends on are registered too.
+func Files(s *schema.Schema) (*protoregistry.Files, error) {
+	builders, err := build(s)
+	if err != nil {
+		return nil, err
+	}
+
+	files := new(protoregistry.Files)
+	for _, b := range builders {
+		for _, dep := range b.file.Dependency {
+			if err := registerDependency(files, dep); err != nil {
+				return nil, fmt.Errorf("protofile: %w", err)
+			}
+		}
+		fd, err := protodesc.NewFile(b.file, files)
+		if err != nil {
+			return nil, fmt.Errorf("protofile: package %q: %w", b.pkg, err)
+		}
+		if err := files.RegisterFile(fd); err != nil {
+			return nil, fmt.Errorf("protofile: package %q: %w", b.pkg, err)
+		}
+	}
+	return files, nil
+}
+
+// EnumValueNames returns the schema names of the enum values that Files
+// prefixes, by their full names in the generated files.
+func EnumValueNames(s *schema.Schema) (map[protoreflect.FullName]string, error) {
+	builders, err := build(s)
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[protoreflect.FullName]string)
+	for _, b := range builders {
+		maps.Copy(names, b.enumNames)
+	}
+	return names, nil
+}
+
+// build returns a file builder per service package in schema order.
+func build(s *schema.Schema) ([]*fileBuilder, error) {
+	byPkg := make(map[string]*fileBuilder)
+	var builders []*fileBuilder
+
+	for _, svc := range s.Services {
+		b, ok := byPkg[svc.Package]
+		if !ok {
+			b = newFileBuilder(s, svc.Package)
+			byPkg[svc.Package] = b
+			builders = append(builders, b)
+		}
+		if err := b.addService(svc); err != nil {
+			return nil, fmt.Errorf("protofile: service %s: %w", svc.Name, err)
+		}
+	}
+	return builders, nil
+}
+
+// resolve returns the type t refers to.
+func (b *fileBuilder) resolve(t schema.Type) (schema.Type, error) {
+	resolved, ok := b.schema.Resolve(t)
+	if !ok {
+		return t, fmt.Errorf("reference to unregistered type %s", t.Name)
+	}
+	return resolved, nil
+}
+
+// registerDependency adds a generated file such as google/api/annotations.proto
+// and its own imports to files.
+func registerDependency(files *protoregistry.Files, path string) error {
+	if _, err := files.FindFileByPath(path); err == nil {
+		return nil
+	}
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+	if err != nil {
+		return err
+	}
+	imports := fd.Imports()
+	for i := range imports.Len() {
+		if err := registerDependency(files, imports.Get(i).Path()); err != nil {
+			return err
+		}
+	}
+	return files.RegisterFile(fd)
+}
+
+func newFileBuilder(s *schema.Schema, pkg string) *fileBuilder {
+	name := pkg
+	if name == "" {
+		name = "default"
+	}
+	return &fileBuilder{
+		schema: s,
+		pkg:    pkg,
+		file: &descriptorpb.FileDescriptorProto{
+			Name:    proto.String("protokol/" + strings.ReplaceAll(name, ".", "/") + ".proto"),
+			Package: proto.String(pkg),
+			Syntax:  proto.String("proto3"),
+		},
+		messages:  make(map[string]*descriptorpb.DescriptorProto),
+		enums:     make(map[string]*descriptorpb.EnumDescriptorProto),
+		types:     make(map[string]schema.Type),
+		values:    make(map[string]bool),
+		enumNames: make(map[protoreflect.FullName]string),
+		comments:  make(map[string]string),
+	}
+}
+
+func (b *fileBuilder) addService(svc schema.Service) error {
+	sd := &descriptorpb.ServiceDescriptorProto{Name: proto.String(svc.Name)}
+	svcName := strings.TrimPrefix(b.qualify(svc.Name), ".")
+	b.comments[svcName] = svc.Description
+	for _, m := range svc.Methods {
+		in, err := b.addRoot(m.Input, m.Name+"Request")
+		if err != nil {
+			return fmt.Errorf("method %s input: %w", m.Name, err)
+		}
+		out, err := b.addRoot(m.Output, m.Name+"Response")
+		if err != nil {
+			return fmt.Errorf("method %s output: %w", m.Name, err)
+		}
+		md := &descriptorpb.MethodDescriptorProto{
+			Name:            proto.String(m.Name),
+			InputType:       proto.String(in),
+			OutputType:      proto.String(out),
+			ClientStreaming: proto.Bool(m.IsClientStreaming()),
+			ServerStreaming: proto.Bool(m.IsServerStreaming()),
+		}
+		if rule := newHTTPRule(m); rule != nil {
+			md.Options = &descriptorpb.MethodOptions{}
+			proto.SetExtension(md.Options, annotations.E_Http, rule)
+			b.addDependency(annotationsFile)
+		}
+		b.comments[svcName+"."+m.Name] = m.Description
+		sd.Method = append(sd.Method, md)
+	}
+	b.file.Service = append(b.file.Service, sd)
+	return nil
+}
+
+// addRoot registers a method input or output type. Methods without a type
+// get an empty message so every RPC has a concrete request and response.
+func (b *fileBuilder) addRoot(t schema.Type, fallback string) (string, error) {
+	t, err := b.resolve(t)
+	if err != nil {
+		return "", err
+	}
+	switch t.Kind {
+	case schema.KindInvalid:
+		t = schema.Type{Kind: schema.KindMessage, Name: fallback}
+	case schema.KindMessage:
+	default:
+		return "", errors.New("must be a message type")
+	}
+	return b.addMessage(t, fallback)
+}
+
+// addMessage registers a message type and returns its fully-qualified name.
+// Empty messages stand in for recursive references, as in schema.Validate,
+// so they match any definition of their name and are completed by it.
+func (b *fileBuilder) addMessage(t schema.Type, fallback string) (string, error) {
+	name := t.Name
+	if name == "" {
+		name = fallback
+	}
+	t.Name = name
+	md, ok := b.messages[name]
+	if def, defined := b.types[name]; defined {
+		switch {
+		case isPlaceholder(t) || reflect.DeepEqual(def, t):
+			return b.qualify(name), nil
+		case !ok || !isPlaceholder(def):
+			return "", fmt.Errorf("conflicting definitions of type %s", name)
+		}
+	} else {
+		md = &descriptorpb.DescriptorProto{Name: proto.String(name)}
+		b.messages[name] = md
+		b.file.MessageType = append(b.file.MessageType, md)
+	}
+	b.types[name] = t
+
+	for i, f := range t.Fields {
+		number := f.Number
+		if number == 0 {
+			number = i + 1
+		}
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(f.Name),
+			Number:   proto.Int32(int32(number)),
+			JsonName: proto.String(f.Name),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if err := b.setFieldType(md, fd, f.Type, name+exportName(f.Name)); err != nil {
+			return "", fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		// Singular scalars and enums are proto3 optional so that a field
+		// set to its zero value can be told from one left out, for
+		// required and default checks.
+		if fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL && fd.GetType() != descriptorpb.FieldDescriptorProto_TYPE_MESSAGE {
+			fd.Proto3Optional = proto.Bool(true)
+			fd.OneofIndex = proto.Int32(int32(len(md.OneofDecl)))
+			md.OneofDecl = append(md.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + f.Name)})
+		}
+		b.comments[strings.TrimPrefix(b.qualify(name+"."+f.Name), ".")] = f.Description
+		if f.Required {
+			fd.Options = &descriptorpb.FieldOptions{}
+			proto.SetExtension(fd.Options, annotations.E_FieldBehavior, []annotations.FieldBehavior{annotations.FieldBehavior_REQUIRED})
+			b.addDependency(fieldBehaviorFile)
+		}
+		md.Field = append(md.Field, fd)
+	}
+	return b.qualify(name), nil
+}
+
+func (b *fileBuilder) setFieldType(parent *descriptorpb.DescriptorProto, fd *descriptorpb.FieldDescriptorProto, t schema.Type, fallback string) error {
+	t, err := b.resolve(t)
+	if err != nil {
+		return err
+	}
+	switch t.Kind {
+	case schema.KindRepeated:
+		if t.Elem == nil {
+			return errors.New("repeated type without element type")
+		}
+		elem, err := b.resolve(*t.Elem)
+		if err != nil {
+			return err
+		}
+		if elem.Kind == schema.KindRepeated || elem.Kind == schema.KindMap {
+			return errors.New("repeated element cannot be repeated or map")
+		}
+		fd.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return b.setScalarType(fd, elem, fallback)
+
+	case schema.KindMap:
+		if t.Key == nil || t.Elem == nil {
+			return errors.New("map type without key or value type")
+		}
+		switch t.Key.Kind {
+		case schema.KindString, schema.KindInt32, schema.KindInt64, schema.KindBool:
+		default:
+			return errors.New("map key must be string, integer or bool")
+		}
+		elem, err := b.resolve(*t.Elem)
+		if err != nil {
+			return err
+		}
+		if elem.Kind == schema.KindRepeated || elem.Kind == schema.KindMap {
+			return errors.New("map value cannot be repeated or map")
+		}
+
+		entryName := exportName(fd.GetName()) + "Entry"
+		entry := &descriptorpb.DescriptorProto{
+			Name:    proto.String(entryName),
+			Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+		}
+		key := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String("key"),
+			Number:   proto.Int32(1),
+			JsonName: proto.String("key"),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		value := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String("value"),
+			Number:   proto.Int32(2),
+			JsonName: proto.String("value"),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+		if err := b.setScalarType(key, *t.Key, fallback); err != nil {
+			return err
+		}
+		if err := b.setScalarType(value, elem, fallback); err != nil {
+			return err
+		}
+		entry.Field = []*descriptorpb.FieldDescriptorProto{key, value}
+		parent.NestedType = append(parent.NestedType, entry)
+
+		fd.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		fd.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		fd.TypeName = proto.String(b.qualify(parent.GetName() + "." + entryName))
+		return nil
+
+	default:
+		return b.setScalarType(fd, t, fallback)
+	}
+}
+
+// setScalarType sets the type of a singular field or a repeated/map element.
+func (b *fileBuilder) setScalarType(fd *descriptorpb.FieldDescriptorProto, t schema.Type, fallback string) error {
+	t, err := b.resolve(t)
+	if err != nil {
+		return err
+	}
+	var typ descriptorpb.FieldDescriptorProto_Type
+	switch t.Kind {
+	case schema.KindBool:
+		typ = descriptorpb.FieldDescriptorProto_TYPE_BOOL
+	case schema.KindInt32:
+		typ = descriptorpb.FieldDescriptorProto_TYPE_INT32
+	case schema.KindInt64:
+		typ = descriptorpb.FieldDescriptorProto_TYPE_INT64
+	case schema.KindFloat32:
+		typ = descriptorpb.FieldDescriptorProto_TYPE_FLOAT
+	case schema.KindFloat64:
+		typ = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
+	case schema.KindString:
+		typ = descriptorpb.FieldDescriptorProto_TYPE_STRING
+	case schema.KindBytes:
+		typ = descriptorpb.FieldDescriptorProto_TYPE_BYTES
+	case schema.KindMessage:
+		name, err := b.addMessage(t, fallback)
+		if err != nil {
+			return err
+		}
+		typ = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+		fd.TypeName = proto.String(name)
+	case schema.KindEnum:
+		name, err := b.addEnum(t, fallback)
+		if err != nil {
+			return err
+		}
+		typ = descriptorpb.FieldDescriptorProto_TYPE_ENUM
+		fd.TypeName = proto.String(name)
+	default:
+		return fmt.Errorf("unsupported kind %d", t.Kind)
+	}
+	fd.Type = typ.Enum()
+	return nil
+}
+
+// addEnum registers an enum type. Proto3 requires the first value to be zero,
+// so values are ordered by number and an UNSPECIFIED value is added if the
+// schema does not define one. If a value name is already used by another
+// enum in the package, all values not yet carrying the enum's prefix get it.
+func (b *fileBuilder) addEnum(t schema.Type, fallback string) (string, error) {
+	name := t.Name
+	if name == "" {
+		name = fallback
+	}
+	t.Name = name
+	if def, ok := b.types[name]; ok {
+		if !reflect.DeepEqual(def, t) {
+			return "", fmt.Errorf("conflicting definitions of type %s", name)
+		}
+		return b.qualify(name), nil
+	}
+	b.types[name] = t
+
+	values := slices.Clone(t.Values)
+	slices.SortStableFunc(values, func(x, y schema.EnumValue) int {
+		return x.Number - y.Number
+	})
+	prefix := enumPrefix(name) + "_"
+	if len(values) == 0 || values[0].Number != 0 {
+		values = append([]schema.EnumValue{{Name: prefix + "UNSPECIFIED"}}, values...)
+	}
+	collides := slices.ContainsFunc(values, func(v schema.EnumValue) bool {
+		return b.values[v.Name]
+	})
+
+	ed := &descriptorpb.EnumDescriptorProto{Name: proto.String(name)}
+	for _, v := range values {
+		valueName := v.Name
+		if collides && !strings.HasPrefix(valueName, prefix) {
+			valueName = prefix + valueName
+			b.enumNames[protoreflect.FullName(strings.TrimPrefix(b.qualify(valueName), "."))] = v.Name
+		}
+		b.values[valueName] = true
+		ed.Value = append(ed.Value, &descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String(valueName),
+			Number: proto.Int32(int32(v.Number)),
+		})
+	}
+	b.enums[name] = ed
+	b.file.EnumType = append(b.file.EnumType, ed)
+	return b.qualify(name), nil
+}
+
+func isPlaceholder(t schema.Type) bool {
+	return t.Kind == schema.KindMessage && len(t.Fields) == 0
+}
+
+func (b *fileBuilder) addDependency(path string) {
+	if !slices.Contains(b.file.Dependency, path) {
+		b.file.Dependency = append(b.file.Dependency, path)
+	}
+}
+
+// Files imported for google.api options.
+const (
+	annotationsFile   = "google/api/annotations.proto"
+	fieldBehaviorFile = "google/api/field_behavior.proto"
+)
+
+// newHTTPRule returns the google.api.http rule for a method with an explicit
+// HTTP mapping, or nil. Methods other than GET and DELETE take the whole
+// request message as the body.
+func newHTTPRule(m schema.Method) *annotations.HttpRule {
+	if m.HTTPMethod == "" || m.HTTPPath == "" {
+		return nil
+	}
+	rule := &annotations.HttpRule{}
+	switch strings.ToUpper(m.HTTPMethod) {
+	case http.MethodGet:
+		rule.Pattern = &annotations.HttpRule_Get{Get: m.HTTPPath}
+	case http.MethodPut:
+		rule.Pattern = &annotations.HttpRule_Put{Put: m.HTTPPath}
+	case http.MethodPost:
+		rule.Pattern = &annotations.HttpRule_Post{Post: m.HTTPPath}
+	case http.MethodDelete:
+		rule.Pattern = &annotations.HttpRule_Delete{Delete: m.HTTPPath}
+	case http.MethodPatch:
+		rule.Pattern = &annotations.HttpRule_Patch{Patch: m.HTTPPath}
+	default:
+		rule.Pattern = &annotations.HttpRule_Custom{Custom: &annotations.CustomHttpPattern{
+			Kind: m.HTTPMethod,
+			Path: m.HTTPPath,
+		}}
+	}
+	if _, ok := rule.Pattern.(*annotations.HttpRule_Get); !ok {
+		if _, ok := rule.Pattern.(*annotations.HttpRule_Delete); !ok {
+			rule.Body = "*"
+		}
+	}
+	return rule
+}
+
+func (b *fileBuilder) qualify(name string) string {
+	if b.pkg == "" {
+		return "." + name
+	}
+	return "." + b.pkg + "." + name
+}
+
+// exportName upper-cases the first letter and drops underscores, turning
+// field names like "user_id" into "UserId" for generated type names.
+func exportName(s string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range s {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// enumPrefix converts a CamelCase enum name to UPPER_SNAKE_CASE.
+func enumPrefix(s string) string {
+	var sb strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) && i > 0 {
+			sb.WriteByte('_')
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
@@ -0,0 +1,241 @@
+package protofile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// Export renders the schema as proto3 source, one file per service package,
+// keyed by file path. The files declare exactly the services, messages and
+// enums served by the gRPC adapter, so stubs generated from them are
+// compatible with it.
+func Export(s *schema.Schema) (map[string][]byte, error) {
+	// Build through Files first so that only valid definitions are printed.
+	if _, err := Files(s); err != nil {
+		return nil, err
+	}
+	builders, err := build(s)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]byte, len(builders))
+	for _, b := range builders {
+		p := &printer{b: b}
+		p.file()
+		out[b.file.GetName()] = []byte(p.sb.String())
+	}
+	return out, nil
+}
+
+// WriteFiles exports the schema and writes the files under dir, creating
+// directories as needed.
+func WriteFiles(s *schema.Schema, dir string) error {
+	files, err := Export(s)
+	if err != nil {
+		return err
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, src, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printer writes a file descriptor as proto source.
+type printer struct {
+	b  *fileBuilder
+	sb strings.Builder
+}
+
+func (p *printer) line(indent int, format string, args ...any) {
+	p.sb.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(&p.sb, format, args...)
+	p.sb.WriteByte('\n')
+}
+
+func (p *printer) comment(indent int, text string) {
+	if text == "" {
+		return
+	}
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		p.line(indent, "// %s", strings.TrimSpace(l))
+	}
+}
+
+func (p *printer) file() {
+	f := p.b.file
+	p.line(0, "// Code generated by protokol. DO NOT EDIT.")
+	p.line(0, "")
+	p.line(0, "syntax = %q;", f.GetSyntax())
+	if f.GetPackage() != "" {
+		p.line(0, "")
+		p.line(0, "package %s;", f.GetPackage())
+	}
+	if len(f.Dependency) > 0 {
+		p.line(0, "")
+		deps := slices.Clone(f.Dependency)
+		slices.Sort(deps)
+		for _, dep := range deps {
+			p.line(0, "import %q;", dep)
+		}
+	}
+	for _, sd := range f.Service {
+		p.line(0, "")
+		p.service(sd)
+	}
+	for _, md := range f.MessageType {
+		p.line(0, "")
+		p.message(md)
+	}
+	for _, ed := range f.EnumType {
+		p.line(0, "")
+		p.enum(ed)
+	}
+}
+
+func (p *printer) service(sd *descriptorpb.ServiceDescriptorProto) {
+	name := strings.TrimPrefix(p.b.qualify(sd.GetName()), ".")
+	p.comment(0, p.b.comments[name])
+	p.line(0, "service %s {", sd.GetName())
+	for i, md := range sd.Method {
+		if i > 0 {
+			p.line(0, "")
+		}
+		p.comment(1, p.b.comments[name+"."+md.GetName()])
+		sig := fmt.Sprintf("rpc %s(%s%s) returns (%s%s)",
+			md.GetName(),
+			streamPrefix(md.GetClientStreaming()), p.typeName(md.GetInputType()),
+			streamPrefix(md.GetServerStreaming()), p.typeName(md.GetOutputType()))
+
+		rule, _ := proto.GetExtension(md.GetOptions(), annotations.E_Http).(*annotations.HttpRule)
+		if rule == nil {
+			p.line(1, "%s;", sig)
+			continue
+		}
+		p.line(1, "%s {", sig)
+		p.line(2, "option (google.api.http) = {")
+		kind, path := httpPattern(rule)
+		if _, ok := rule.Pattern.(*annotations.HttpRule_Custom); ok {
+			p.line(3, "custom: {")
+			p.line(4, "kind: %s", strconv.Quote(kind))
+			p.line(4, "path: %s", strconv.Quote(path))
+			p.line(3, "}")
+		} else {
+			p.line(3, "%s: %s", kind, strconv.Quote(path))
+		}
+		if rule.GetBody() != "" {
+			p.line(3, "body: %s", strconv.Quote(rule.GetBody()))
+		}
+		p.line(2, "};")
+		p.line(1, "}")
+	}
+	p.line(0, "}")
+}
+
+func (p *printer) message(md *descriptorpb.DescriptorProto) {
+	entries := make(map[string]*descriptorpb.DescriptorProto)
+	for _, nested := range md.NestedType {
+		if nested.GetOptions().GetMapEntry() {
+			entries[p.b.qualify(md.GetName()+"."+nested.GetName())] = nested
+		}
+	}
+
+	if len(md.Field) == 0 {
+		p.line(0, "message %s {}", md.GetName())
+		return
+	}
+	name := strings.TrimPrefix(p.b.qualify(md.GetName()), ".")
+	p.line(0, "message %s {", md.GetName())
+	for _, fd := range md.Field {
+		p.comment(1, p.b.comments[name+"."+fd.GetName()])
+		var typ string
+		if entry, ok := entries[fd.GetTypeName()]; ok {
+			typ = fmt.Sprintf("map<%s, %s>", p.fieldType(entry.Field[0]), p.fieldType(entry.Field[1]))
+		} else {
+			typ = p.fieldType(fd)
+			if fd.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
+				typ = "repeated " + typ
+			} else if fd.GetProto3Optional() {
+				typ = "optional " + typ
+			}
+		}
+		if requiredField(fd) {
+			p.line(1, "%s %s = %d [(google.api.field_behavior) = REQUIRED];", typ, fd.GetName(), fd.GetNumber())
+		} else {
+			p.line(1, "%s %s = %d;", typ, fd.GetName(), fd.GetNumber())
+		}
+	}
+	p.line(0, "}")
+}
+
+func (p *printer) enum(ed *descriptorpb.EnumDescriptorProto) {
+	p.line(0, "enum %s {", ed.GetName())
+	for _, v := range ed.Value {
+		p.line(1, "%s = %d;", v.GetName(), v.GetNumber())
+	}
+	p.line(0, "}")
+}
+
+func (p *printer) fieldType(fd *descriptorpb.FieldDescriptorProto) string {
+	switch fd.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, descriptorpb.FieldDescriptorProto_TYPE_ENUM:
+		return p.typeName(fd.GetTypeName())
+	default:
+		return strings.ToLower(strings.TrimPrefix(fd.GetType().String(), "TYPE_"))
+	}
+}
+
+// typeName returns a fully-qualified type name relative to the file's
+// package.
+func (p *printer) typeName(name string) string {
+	if p.b.pkg == "" {
+		return strings.TrimPrefix(name, ".")
+	}
+	return strings.TrimPrefix(name, "."+p.b.pkg+".")
+}
+
+func requiredField(fd *descriptorpb.FieldDescriptorProto) bool {
+	behaviors, _ := proto.GetExtension(fd.GetOptions(), annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	return slices.Contains(behaviors, annotations.FieldBehavior_REQUIRED)
+}
+
+func streamPrefix(streaming bool) string {
+	if streaming {
+		return "stream "
+	}
+	return ""
+}
+
+func httpPattern(rule *annotations.HttpRule) (kind, path string) {
+	switch pattern := rule.Pattern.(type) {
+	case *annotations.HttpRule_Get:
+		return "get", pattern.Get
+	case *annotations.HttpRule_Put:
+		return "put", pattern.Put
+	case *annotations.HttpRule_Post:
+		return "post", pattern.Post
+	case *annotations.HttpRule_Delete:
+		return "delete", pattern.Delete
+	case *annotations.HttpRule_Patch:
+		return "patch", pattern.Patch
+	case *annotations.HttpRule_Custom:
+		return pattern.Custom.GetKind(), pattern.Custom.GetPath()
+	default:
+		return "", ""
+	}
+}
@@ -0,0 +1,149 @@
+package protofile
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+func exportSchema() *schema.Schema {
+	status := schema.Enum("Status").Value("ACTIVE", 0).Value("DELETED", 1).Build()
+	state := schema.Enum("State").Value("ACTIVE", 0).Value("PAUSED", 1).Build()
+	user := schema.Message("User").
+		RequiredField("id", schema.Int64).
+		Field("name", schema.String).
+		Field("status", status).
+		Field("state", state).
+		Field("tags", schema.Repeated(schema.String)).
+		Field("labels", schema.Map(schema.String, schema.Int32)).
+		Build()
+	s := schema.NewSchema()
+	s.AddService(schema.NewService("Users").Package("acme.user.v1").Backend("users").Description("Users manages users.").
+		Method(schema.Unary("GetUser").Input(user).Output(user).HTTP("GET", "/users/{id}").Description("GetUser returns a user.").Build()).
+		Method(schema.ServerStream("WatchUsers").Input(user).Output(user).Build()).
+		MustBuild())
+	return s
+}
+
+const exportedProto = `// Code generated by protokol. DO NOT EDIT.
+
+syntax = "proto3";
+
+package acme.user.v1;
+
+import "google/api/annotations.proto";
+import "google/api/field_behavior.proto";
+
+// Users manages users.
+service Users {
+  // GetUser returns a user.
+  rpc GetUser(User) returns (User) {
+    option (google.api.http) = {
+      get: "/users/{id}"
+    };
+  }
+
+  rpc WatchUsers(User) returns (stream User);
+}
+
+message User {
+  optional int64 id = 1 [(google.api.field_behavior) = REQUIRED];
+  optional string name = 2;
+  optional Status status = 3;
+  optional State state = 4;
+  repeated string tags = 5;
+  map<string, int32> labels = 6;
+}
+
+enum Status {
+  ACTIVE = 0;
+  DELETED = 1;
+}
+
+enum State {
+  STATE_ACTIVE = 0;
+  STATE_PAUSED = 1;
+}
+`
+
+func TestExport(t *testing.T) {
+	files, err := Export(exportSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, ok := files["protokol/acme/user/v1.proto"]
+	if len(files) != 1 || !ok {
+		t.Fatalf("files = %v", len(files))
+	}
+	if string(src) != exportedProto {
+		t.Errorf("got:\n%s\nwant:\n%s", src, exportedProto)
+	}
+
+	names, err := EnumValueNames(exportSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(names); got != "map[acme.user.v1.STATE_ACTIVE:ACTIVE acme.user.v1.STATE_PAUSED:PAUSED]" {
+		t.Errorf("enum value names = %s", got)
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	s, err := load(t, map[string]string{"v1.proto": exportedProto})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, ok := s.ServiceByName("Users")
+	if !ok {
+		t.Fatal("service Users not imported")
+	}
+	m, _ := svc.MethodByName("GetUser")
+	if m.HTTPMethod != "GET" || m.HTTPPath != "/users/{id}" || m.Description != "GetUser returns a user." {
+		t.Errorf("GetUser = %s %s %q", m.HTTPMethod, m.HTTPPath, m.Description)
+	}
+	user, _ := s.Resolve(m.Input)
+	if len(user.Fields) != 6 || !user.Fields[0].Required {
+		t.Errorf("User fields = %+v, want six with a required id", user.Fields)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		svc  func(*schema.Schema)
+		want string
+	}{
+		{
+			name: "conflicting definitions",
+			svc: func(s *schema.Schema) {
+				a := schema.Message("User").Field("id", schema.Int64).Build()
+				b := schema.Message("User").Field("id", schema.String).Build()
+				s.AddService(schema.NewService("Users").Package("acme.v1").Backend("b").
+					Method(schema.Unary("GetUser").Input(a).Output(b).Build()).
+					MustBuild())
+			},
+			want: "conflicting definitions of type User",
+		},
+		{
+			name: "unregistered reference",
+			svc: func(s *schema.Schema) {
+				s.AddService(schema.NewService("Users").Package("acme.v1").Backend("b").
+					Method(schema.Unary("GetUser").Input(schema.Ref("User")).Output(schema.Ref("User")).Build()).
+					MustBuild())
+			},
+			want: "reference to unregistered type User",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := schema.NewSchema()
+			tt.svc(s)
+			_, err := Export(s)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
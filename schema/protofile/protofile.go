@@ -0,0 +1,395 @@
+// Package protofile imports Protocol Buffers definitions into a schema.
+// Files are parsed in-process, so protoc is not required.
+package protofile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// Config controls how .proto files and their imports are found.
+type Config struct {
+	// ImportPaths are searched for the files to load and their imports.
+	// Paths are relative to the working directory if empty.
+	ImportPaths []string
+	// Accessor opens source files instead of the file system, for example
+	// to read embedded files.
+	Accessor func(path string) (io.ReadCloser, error)
+	// Backend is assigned to every imported service.
+	Backend string
+}
+
+// Load parses the given proto3 files and adds their services and types to s.
+// Imports are resolved from cfg.ImportPaths; the well-known types and the
+// google.api annotations are always available.
+func Load(ctx context.Context, s *schema.Schema, cfg Config, files ...string) error {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(protocompile.CompositeResolver{
+			&protocompile.SourceResolver{
+				ImportPaths: cfg.ImportPaths,
+				Accessor:    cfg.Accessor,
+			},
+			protocompile.ResolverFunc(func(path string) (protocompile.SearchResult, error) {
+				fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+				if err != nil {
+					return protocompile.SearchResult{}, err
+				}
+				return protocompile.SearchResult{Desc: fd}, nil
+			}),
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+
+	compiled, err := compiler.Compile(ctx, files...)
+	if err != nil {
+		return fmt.Errorf("protofile: %w", err)
+	}
+	fds := make([]protoreflect.FileDescriptor, len(compiled))
+	for i, f := range compiled {
+		fds[i] = f
+	}
+	return Import(s, cfg.Backend, fds...)
+}
+
+// Import adds the services, messages and enums declared in files to s. Types
+// are registered under their fully-qualified proto names. Services are
+// assigned to backend. Adapters name types without their package, so types
+// of different packages that share a name, such as a.v1.User and b.v1.User,
+// are an error.
+func Import(s *schema.Schema, backend string, files ...protoreflect.FileDescriptor) error {
+	c := newConverter(s)
+	for _, fd := range files {
+		c.registerTypes(s, fd.Messages(), fd.Enums())
+		if c.err != nil {
+			return fmt.Errorf("protofile: %w", c.err)
+		}
+		services := fd.Services()
+		for i := range services.Len() {
+			svc, err := c.service(services.Get(i), backend)
+			if err == nil {
+				err = c.err
+			}
+			if err != nil {
+				return fmt.Errorf("protofile: %w", err)
+			}
+			s.AddService(svc)
+		}
+	}
+	return nil
+}
+
+// converter turns descriptors into schema types, caching each message so
+// shared types are converted once.
+type converter struct {
+	schema   *schema.Schema
+	messages map[protoreflect.FullName]schema.Type
+	// converting holds messages being converted, to detect recursion.
+	converting map[protoreflect.FullName]bool
+	// referenced holds recursive messages, which are registered so that
+	// references to them resolve.
+	referenced map[protoreflect.FullName]bool
+	// names maps the schema names of converted messages and enums to their
+	// full names, to detect types that would share a name.
+	names map[string]protoreflect.FullName
+	// err is the first name collision found.
+	err error
+}
+
+func newConverter(s *schema.Schema) *converter {
+	return &converter{
+		schema:     s,
+		messages:   make(map[protoreflect.FullName]schema.Type),
+		converting: make(map[protoreflect.FullName]bool),
+		referenced: make(map[protoreflect.FullName]bool),
+		names:      make(map[string]protoreflect.FullName),
+	}
+}
+
+// claim records name as the schema name of the type full, and records an
+// error if another type, imported now or registered before, has that name.
+func (c *converter) claim(name string, full protoreflect.FullName) {
+	other, ok := c.names[name]
+	if !ok {
+		for key, t := range c.schema.Types {
+			if t.Name == name && key != string(full) {
+				other, ok = protoreflect.FullName(key), true
+				break
+			}
+		}
+	}
+	if ok && other != full && c.err == nil {
+		c.err = fmt.Errorf("types %s and %s are both named %s", other, full, name)
+	}
+	c.names[name] = full
+}
+
+func (c *converter) registerTypes(s *schema.Schema, msgs protoreflect.MessageDescriptors, enums protoreflect.EnumDescriptors) {
+	for i := range enums.Len() {
+		ed := enums.Get(i)
+		s.RegisterType(string(ed.FullName()), c.enum(ed))
+	}
+	for i := range msgs.Len() {
+		md := msgs.Get(i)
+		if md.IsMapEntry() {
+			continue
+		}
+		s.RegisterType(string(md.FullName()), c.message(md))
+		c.registerTypes(s, md.Messages(), md.Enums())
+	}
+}
+
+func (c *converter) service(sd protoreflect.ServiceDescriptor, backend string) (schema.Service, error) {
+	svc := schema.Service{
+		Name:        string(sd.Name()),
+		Package:     string(sd.ParentFile().Package()),
+		Description: comments(sd),
+		Backend:     backend,
+	}
+	methods := sd.Methods()
+	for i := range methods.Len() {
+		md := methods.Get(i)
+		m := schema.Method{
+			Name:        string(md.Name()),
+			Input:       c.message(md.Input()),
+			Output:      c.message(md.Output()),
+			Type:        methodType(md),
+			Description: comments(md),
+		}
+		rule, err := httpRule(md)
+		if err != nil {
+			return schema.Service{}, fmt.Errorf("method %s: %w", md.FullName(), err)
+		}
+		if rule != nil {
+			m.HTTPMethod, m.HTTPPath, err = httpBinding(rule)
+			if err != nil {
+				return schema.Service{}, fmt.Errorf("method %s: %w", md.FullName(), err)
+			}
+		}
+		svc.Methods = append(svc.Methods, m)
+	}
+	return svc, nil
+}
+
+func (c *converter) message(md protoreflect.MessageDescriptor) schema.Type {
+	name := md.FullName()
+	if t, ok := c.messages[name]; ok {
+		return t
+	}
+	// Schema types are values, so a recursive field cannot embed its own
+	// type. It refers to the registered message instead.
+	if c.converting[name] {
+		c.referenced[name] = true
+		return schema.Ref(string(name))
+	}
+	c.converting[name] = true
+	defer delete(c.converting, name)
+
+	t := schema.Type{Kind: schema.KindMessage, Name: typeName(md)}
+	c.claim(t.Name, name)
+	fields := md.Fields()
+	for i := range fields.Len() {
+		fd := fields.Get(i)
+		f := schema.Field{
+			Name:        string(fd.Name()),
+			Type:        c.fieldType(fd),
+			Number:      int(fd.Number()),
+			Required:    isRequired(fd),
+			Description: comments(fd),
+		}
+		if fd.HasDefault() {
+			f.Default = defaultValue(fd)
+		}
+		t.Fields = append(t.Fields, f)
+	}
+	c.messages[name] = t
+	if c.referenced[name] {
+		c.schema.RegisterType(string(name), t)
+	}
+	return t
+}
+
+func (c *converter) fieldType(fd protoreflect.FieldDescriptor) schema.Type {
+	switch {
+	case fd.IsMap():
+		return schema.Map(c.singularType(fd.MapKey()), c.singularType(fd.MapValue()))
+	case fd.IsList():
+		return schema.Repeated(c.singularType(fd))
+	default:
+		return c.singularType(fd)
+	}
+}
+
+func (c *converter) singularType(fd protoreflect.FieldDescriptor) schema.Type {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return schema.Bool
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return schema.Int32
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// Unsigned 32-bit values do not fit in Int32.
+		return schema.Int64
+	case protoreflect.FloatKind:
+		return schema.Float32
+	case protoreflect.DoubleKind:
+		return schema.Float64
+	case protoreflect.StringKind:
+		return schema.String
+	case protoreflect.BytesKind:
+		return schema.Bytes
+	case protoreflect.EnumKind:
+		return c.enum(fd.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return c.message(fd.Message())
+	default:
+		return schema.Type{}
+	}
+}
+
+func (c *converter) enum(ed protoreflect.EnumDescriptor) schema.Type {
+	t := schema.Type{Kind: schema.KindEnum, Name: typeName(ed)}
+	c.claim(t.Name, ed.FullName())
+	values := ed.Values()
+	for i := range values.Len() {
+		v := values.Get(i)
+		t.Values = append(t.Values, schema.EnumValue{Name: string(v.Name()), Number: int(v.Number())})
+	}
+	return t
+}
+
+// typeName names nested types after their parents, e.g. Outer_Inner, so
+// every name is a valid identifier in each protocol.
+func typeName(d protoreflect.Descriptor) string {
+	name := string(d.Name())
+	for p := d.Parent(); p != nil; p = p.Parent() {
+		if _, ok := p.(protoreflect.MessageDescriptor); !ok {
+			break
+		}
+		name = string(p.Name()) + "_" + name
+	}
+	return name
+}
+
+func methodType(md protoreflect.MethodDescriptor) schema.MethodType {
+	switch {
+	case md.IsStreamingClient() && md.IsStreamingServer():
+		return schema.MethodBidirectional
+	case md.IsStreamingClient():
+		return schema.MethodClientStream
+	case md.IsStreamingServer():
+		return schema.MethodServerStream
+	default:
+		return schema.MethodUnary
+	}
+}
+
+// isRequired reports proto2 required fields and fields annotated with
+// (google.api.field_behavior) = REQUIRED.
+func isRequired(fd protoreflect.FieldDescriptor) bool {
+	if fd.Cardinality() == protoreflect.Required {
+		return true
+	}
+	opts := &descriptorpb.FieldOptions{}
+	if err := resolveOptions(fd.Options(), opts); err != nil {
+		return false
+	}
+	behaviors, _ := proto.GetExtension(opts, annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	for _, b := range behaviors {
+		if b == annotations.FieldBehavior_REQUIRED {
+			return true
+		}
+	}
+	return false
+}
+
+func defaultValue(fd protoreflect.FieldDescriptor) any {
+	if fd.Kind() == protoreflect.EnumKind {
+		return string(fd.DefaultEnumValue().Name())
+	}
+	return fd.Default().Interface()
+}
+
+func httpRule(md protoreflect.MethodDescriptor) (*annotations.HttpRule, error) {
+	opts := &descriptorpb.MethodOptions{}
+	if err := resolveOptions(md.Options(), opts); err != nil {
+		return nil, err
+	}
+	if !proto.HasExtension(opts, annotations.E_Http) {
+		return nil, nil
+	}
+	rule, _ := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+	return rule, nil
+}
+
+// resolveOptions re-decodes compiled options into dst so that extensions
+// are available as generated types regardless of how the compiler
+// represented them.
+func resolveOptions(src proto.Message, dst proto.Message) error {
+	if src == nil {
+		return nil
+	}
+	data, err := proto.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return proto.UnmarshalOptions{Resolver: protoregistry.GlobalTypes}.Unmarshal(data, dst)
+}
+
+// pathVariable matches path template variables, as in {name} or
+// {name=shelves/*}, with the pattern as the second submatch.
+var pathVariable = regexp.MustCompile(`\{([^}=]+)(?:=([^}]*))?\}`)
+
+// httpBinding returns the method and path of rule. The path variables of
+// the rule's template become {name} path parameters, which match a single
+// segment, so variables whose pattern is not "*" are rejected, as are rules
+// whose body is a single field, response bodies and additional bindings.
+func httpBinding(rule *annotations.HttpRule) (method, path string, err error) {
+	switch {
+	case rule.GetGet() != "":
+		method, path = http.MethodGet, rule.GetGet()
+	case rule.GetPut() != "":
+		method, path = http.MethodPut, rule.GetPut()
+	case rule.GetPost() != "":
+		method, path = http.MethodPost, rule.GetPost()
+	case rule.GetDelete() != "":
+		method, path = http.MethodDelete, rule.GetDelete()
+	case rule.GetPatch() != "":
+		method, path = http.MethodPatch, rule.GetPatch()
+	case rule.GetCustom() != nil:
+		method, path = strings.ToUpper(rule.GetCustom().GetKind()), rule.GetCustom().GetPath()
+	}
+	switch {
+	case rule.GetBody() != "" && rule.GetBody() != "*":
+		return "", "", fmt.Errorf("google.api.http body %q is not supported, only \"*\"", rule.GetBody())
+	case rule.GetResponseBody() != "":
+		return "", "", errors.New("google.api.http response_body is not supported")
+	case len(rule.GetAdditionalBindings()) > 0:
+		return "", "", errors.New("google.api.http additional_bindings are not supported")
+	}
+	for _, m := range pathVariable.FindAllStringSubmatch(path, -1) {
+		if m[2] != "" && m[2] != "*" {
+			return "", "", fmt.Errorf("path variable %s matches more than one segment, which is not supported", m[0])
+		}
+	}
+	return method, pathVariable.ReplaceAllString(path, "{$1}"), nil
+}
+
+func comments(d protoreflect.Descriptor) string {
+	loc := d.ParentFile().SourceLocations().ByDescriptor(d)
+	return strings.TrimSpace(loc.LeadingComments)
+}
@@ -0,0 +1,248 @@
+package protofile
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// compile parses a single source file.
+func compile(t *testing.T, src string) protoreflect.FileDescriptor {
+	t.Helper()
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: protocompile.SourceAccessorFromMap(map[string]string{"a.proto": src}),
+		}),
+	}
+	files, err := compiler.Compile(context.Background(), "a.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files[0]
+}
+
+// load imports the given sources, keyed by file name, into a new schema.
+func load(t *testing.T, files map[string]string) (*schema.Schema, error) {
+	t.Helper()
+	s := schema.NewSchema()
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	err := Load(context.Background(), s, Config{
+		Backend: "users",
+		Accessor: func(path string) (io.ReadCloser, error) {
+			src, ok := files[path]
+			if !ok {
+				return nil, os.ErrNotExist
+			}
+			return io.NopCloser(strings.NewReader(src)), nil
+		},
+	}, names...)
+	return s, err
+}
+
+const userProto = `syntax = "proto3";
+
+package acme.user.v1;
+
+import "google/api/annotations.proto";
+import "google/api/field_behavior.proto";
+
+// Users manages users.
+service Users {
+  // GetUser returns a user.
+  rpc GetUser(GetUserRequest) returns (User) {
+    option (google.api.http) = { get: "/v1/users/{id=*}" };
+  }
+  rpc CreateUser(User) returns (User) {
+    option (google.api.http) = { post: "/v1/users" body: "*" };
+  }
+  rpc Watch(GetUserRequest) returns (stream User);
+  rpc Upload(stream User) returns (User);
+  rpc Chat(stream User) returns (stream User);
+}
+
+message GetUserRequest {
+  string id = 1 [(google.api.field_behavior) = REQUIRED];
+}
+
+message User {
+  message Address {
+    string city = 1;
+  }
+  enum Role {
+    ROLE_UNSPECIFIED = 0;
+    ADMIN = 1;
+  }
+  string id = 1;
+  repeated string tags = 2;
+  map<string, int64> scores = 3;
+  Address address = 4;
+  Role role = 5;
+  User manager = 6;
+}
+`
+
+func TestLoad(t *testing.T) {
+	s, err := load(t, map[string]string{"user.proto": userProto})
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, ok := s.ServiceByName("Users")
+	if !ok {
+		t.Fatal("service Users not imported")
+	}
+	if svc.Package != "acme.user.v1" || svc.Backend != "users" || svc.Description != "Users manages users." {
+		t.Errorf("service = %q %q %q", svc.Package, svc.Backend, svc.Description)
+	}
+
+	tests := []struct {
+		method string
+		typ    schema.MethodType
+		http   string
+	}{
+		{"GetUser", schema.MethodUnary, "GET /v1/users/{id}"},
+		{"CreateUser", schema.MethodUnary, "POST /v1/users"},
+		{"Watch", schema.MethodServerStream, " "},
+		{"Upload", schema.MethodClientStream, " "},
+		{"Chat", schema.MethodBidirectional, " "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			m, ok := svc.MethodByName(tt.method)
+			if !ok {
+				t.Fatal("method not imported")
+			}
+			if m.Type != tt.typ {
+				t.Errorf("type = %v, want %v", m.Type, tt.typ)
+			}
+			if got := m.HTTPMethod + " " + m.HTTPPath; got != tt.http {
+				t.Errorf("http = %q, want %q", got, tt.http)
+			}
+		})
+	}
+
+	req, _ := s.Resolve(schema.Ref("acme.user.v1.GetUserRequest"))
+	if len(req.Fields) != 1 || !req.Fields[0].Required {
+		t.Errorf("GetUserRequest fields = %+v, want a required id", req.Fields)
+	}
+	user, ok := s.Resolve(schema.Ref("acme.user.v1.User"))
+	if !ok {
+		t.Fatal("User not registered")
+	}
+	type kind struct {
+		kind schema.Kind
+		name string
+	}
+	got := make(map[string]kind)
+	for _, f := range user.Fields {
+		got[f.Name] = kind{f.Type.Kind, f.Type.Name}
+	}
+	want := map[string]kind{
+		"id":      {schema.KindString, ""},
+		"tags":    {schema.KindRepeated, ""},
+		"scores":  {schema.KindMap, ""},
+		"address": {schema.KindMessage, "User_Address"},
+		"role":    {schema.KindEnum, "User_Role"},
+		"manager": {schema.KindRef, "acme.user.v1.User"},
+	}
+	for name, w := range want {
+		if got[name] != w {
+			t.Errorf("field %s = %+v, want %+v", name, got[name], w)
+		}
+	}
+	for _, name := range []string{"acme.user.v1.User.Address", "acme.user.v1.User.Role"} {
+		if _, ok := s.Types[name]; !ok {
+			t.Errorf("nested type %s not registered", name)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "types with the same name in different packages",
+			files: map[string]string{
+				"a.proto": "syntax = \"proto3\";\npackage a.v1;\nmessage User { string id = 1; }\n",
+				"b.proto": "syntax = \"proto3\";\npackage b.v1;\nmessage User { string name = 1; }\n",
+			},
+			want: "are both named User",
+		},
+		{
+			name: "nested type named like a top-level one",
+			files: map[string]string{
+				"a.proto": "syntax = \"proto3\";\npackage a.v1;\nmessage User { enum Role { R = 0; } }\nenum User_Role { X = 0; }\n",
+			},
+			want: "are both named User_Role",
+		},
+		{
+			name:  "multi-segment path variable",
+			files: map[string]string{"s.proto": httpProto(`get: "/v1/{name=shelves/*}"`)},
+			want:  "matches more than one segment",
+		},
+		{
+			name:  "double wildcard path variable",
+			files: map[string]string{"s.proto": httpProto(`get: "/v1/{name=**}"`)},
+			want:  "matches more than one segment",
+		},
+		{
+			name:  "field body",
+			files: map[string]string{"s.proto": httpProto(`post: "/v1/shelves" body: "name"`)},
+			want:  `body "name" is not supported`,
+		},
+		{
+			name:  "response body",
+			files: map[string]string{"s.proto": httpProto(`get: "/v1/shelves" response_body: "name"`)},
+			want:  "response_body is not supported",
+		},
+		{
+			name:  "additional bindings",
+			files: map[string]string{"s.proto": httpProto(`get: "/v1/shelves" additional_bindings { get: "/v2/shelves" }`)},
+			want:  "additional_bindings are not supported",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := load(t, tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportConflictsWithSchema(t *testing.T) {
+	s := schema.NewSchema()
+	s.RegisterType("User", schema.Message("User").Field("id", schema.String).Build())
+	err := Import(s, "", compile(t, "syntax = \"proto3\";\npackage a.v1;\nmessage User { string id = 1; }\n"))
+	if err == nil || !strings.Contains(err.Error(), "are both named User") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// httpProto returns a file with one method annotated with the given
+// google.api.http rule fields.
+func httpProto(rule string) string {
+	return `syntax = "proto3";
+package shelf.v1;
+import "google/api/annotations.proto";
+service Shelves {
+  rpc Get(Shelf) returns (Shelf) {
+    option (google.api.http) = { ` + rule + ` };
+  }
+}
+message Shelf { string name = 1; }
+`
+}
@@ -19,6 +19,15 @@ func (s Service) MethodByName(name string) (Method, bool) {
 	return Method{}, false
 }
 
+// BackendFor returns the name of the backend serving m: the method's own
+// Backend if set, and the service's otherwise.
+func (s Service) BackendFor(m Method) string {
+	if m.Backend != "" {
+		return m.Backend
+	}
+	return s.Backend
+}
+
 // Schema holds the complete API definition.
 type Schema struct {
 	Services []Service
@@ -57,3 +66,32 @@ func (s *Schema) LookupType(name string) (Type, bool) {
 	t, ok := s.Types[name]
 	return t, ok
 }
+
+// Resolve returns the registered type t refers to if t is a reference,
+// following references to references, and t itself otherwise. A registered
+// type without a name takes the name it was registered under. It returns
+// false for references to unregistered names or to themselves, and for any
+// reference if s is nil.
+func (s *Schema) Resolve(t Type) (Type, bool) {
+	if t.Kind != KindRef {
+		return t, true
+	}
+	if s == nil {
+		return t, false
+	}
+	for range len(s.Types) {
+		name := t.Name
+		resolved, ok := s.Types[name]
+		if !ok {
+			return t, false
+		}
+		if resolved.Kind != KindRef {
+			if resolved.Name == "" {
+				resolved.Name = name
+			}
+			return resolved, true
+		}
+		t = resolved
+	}
+	return t, false
+}
@@ -0,0 +1,47 @@
+package schema
+
+import "testing"
+
+func TestResolve(t *testing.T) {
+	s := NewSchema()
+	s.RegisterType("User", Message("").Field("id", String).Build())
+	s.RegisterType("Person", Ref("User"))
+	s.RegisterType("Loop", Ref("Loop"))
+	s.RegisterType("A", Ref("B"))
+	s.RegisterType("B", Ref("A"))
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		typ    Type
+		want   string
+		kind   Kind
+		ok     bool
+	}{
+		{"not a reference", s, String, "", KindString, true},
+		{"registered message takes its name", s, Ref("User"), "User", KindMessage, true},
+		{"reference to a reference", s, Ref("Person"), "User", KindMessage, true},
+		{"unregistered", s, Ref("Missing"), "Missing", KindRef, false},
+		{"self-reference", s, Ref("Loop"), "Loop", KindRef, false},
+		{"reference cycle", s, Ref("A"), "", KindRef, false},
+		{"nil schema", nil, Ref("User"), "User", KindRef, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.schema.Resolve(tt.typ)
+			if ok != tt.ok || got.Kind != tt.kind {
+				t.Fatalf("Resolve = %v %v, want kind %v ok %v", got.Kind, ok, tt.kind, tt.ok)
+			}
+			if tt.want != "" && got.Name != tt.want {
+				t.Errorf("name = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+
+	if got, _ := s.Resolve(Ref("User")); len(got.Fields) != 1 {
+		t.Errorf("fields = %+v", got.Fields)
+	}
+	if s.Types["User"].Name != "" {
+		t.Error("Resolve modified the registered type")
+	}
+}
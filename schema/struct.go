@@ -0,0 +1,292 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Enumerated is implemented, with a value receiver, by named integer and
+// string types that stand for enums. FromStruct turns fields of such types
+// into enum types named after the Go type.
+type Enumerated interface {
+	EnumValues() []EnumValue
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	enumeratedType = reflect.TypeFor[Enumerated]()
+)
+
+// FromStruct builds a message type from the Go struct T, named after the
+// struct. Field names come from json tags, or the Go field name without one;
+// fields tagged json:"-" and unexported fields are skipped, and embedded
+// structs are flattened, as encoding/json does.
+//
+// Integers map to Int32 or Int64 by size, floats to Float32 or Float64,
+// []byte to Bytes, other slices and arrays to Repeated, maps to Map and
+// nested structs to messages. time.Time is a String holding an RFC 3339
+// timestamp, and types implementing Enumerated are enums. Pointers are
+// treated as the type they point to. A struct that contains itself refers to
+// its own name with Ref at the recursive field, so it must be registered
+// under that name with Schema.RegisterType.
+//
+// The protokol tag sets field options as a comma-separated list:
+//
+//	type ListUsersRequest struct {
+//	    Limit  int32  `json:"limit" protokol:"default=10,number=1"`
+//	    Filter string `json:"filter" protokol:"required,description=Name prefix, case-insensitive"`
+//	}
+//
+// "required" marks the field required, "default=V" sets its default in the
+// form of a query parameter value, or as JSON for messages, lists and maps,
+// and "number=N" sets its field number, which otherwise follows the field
+// position. "description=D" sets its description; it takes the rest of the
+// tag, so it may contain commas and must come last.
+func FromStruct[T any]() (Type, error) {
+	rt := reflect.TypeFor[T]()
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt.Kind() != reflect.Struct || rt == timeType {
+		return Type{}, fmt.Errorf("FromStruct requires a struct type, got %s", rt)
+	}
+	c := &structConverter{converting: make(map[reflect.Type]bool)}
+	return c.typ(rt)
+}
+
+// MustFromStruct is like FromStruct but panics if the struct cannot be
+// converted.
+func MustFromStruct[T any]() Type {
+	t, err := FromStruct[T]()
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// UnaryFor creates a MethodBuilder for a unary method whose input and output
+// messages are derived from the structs In and Out with MustFromStruct. It
+// pairs with handlers registered with backend.RegisterTyped.
+func UnaryFor[In, Out any](name string) *MethodBuilder {
+	return Unary(name).Input(MustFromStruct[In]()).Output(MustFromStruct[Out]())
+}
+
+// structConverter converts Go types to schema types.
+type structConverter struct {
+	// converting holds the named structs being converted, to detect
+	// recursion.
+	converting map[reflect.Type]bool
+}
+
+func (c *structConverter) typ(rt reflect.Type) (Type, error) {
+	for rt.Kind() == reflect.Pointer {
+		rt = rt.Elem()
+	}
+	if rt == timeType {
+		return String, nil
+	}
+	if rt.Implements(enumeratedType) {
+		return enumType(rt)
+	}
+
+	switch rt.Kind() {
+	case reflect.Bool:
+		return Bool, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return Int32, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return Int64, nil
+	case reflect.Float32:
+		return Float32, nil
+	case reflect.Float64:
+		return Float64, nil
+	case reflect.String:
+		return String, nil
+	case reflect.Slice, reflect.Array:
+		if rt.Kind() == reflect.Slice && rt.Elem().Kind() == reflect.Uint8 {
+			return Bytes, nil
+		}
+		elem, err := c.typ(rt.Elem())
+		if err != nil {
+			return Type{}, err
+		}
+		return Repeated(elem), nil
+	case reflect.Map:
+		key, err := c.typ(rt.Key())
+		if err != nil {
+			return Type{}, err
+		}
+		switch key.Kind {
+		case KindString, KindBool, KindInt32, KindInt64:
+		default:
+			return Type{}, fmt.Errorf("unsupported map key type %s", rt.Key())
+		}
+		value, err := c.typ(rt.Elem())
+		if err != nil {
+			return Type{}, err
+		}
+		return Map(key, value), nil
+	case reflect.Struct:
+		return c.message(rt)
+	}
+	return Type{}, fmt.Errorf("unsupported type %s", rt)
+}
+
+func (c *structConverter) message(rt reflect.Type) (Type, error) {
+	if rt.Name() != "" {
+		if c.converting[rt] {
+			return Ref(rt.Name()), nil
+		}
+		c.converting[rt] = true
+		defer delete(c.converting, rt)
+	}
+	t := Type{Kind: KindMessage, Name: rt.Name()}
+	if err := c.fields(&t, rt); err != nil {
+		return Type{}, err
+	}
+	return t, nil
+}
+
+// fields appends the fields of struct rt to message t.
+func (c *structConverter) fields(t *Type, rt reflect.Type) error {
+	for i := range rt.NumField() {
+		sf := rt.Field(i)
+		jsonName, tagged := jsonFieldName(sf)
+		if jsonName == "-" {
+			continue
+		}
+		if sf.Anonymous && !tagged {
+			ft := sf.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				if err := c.fields(t, ft); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		ft, err := c.typ(sf.Type)
+		if err != nil {
+			return fieldError(rt, sf, err)
+		}
+		f := Field{Name: jsonName, Type: ft, Number: len(t.Fields) + 1}
+		if err := parseFieldTag(&f, sf.Tag.Get("protokol")); err != nil {
+			return fieldError(rt, sf, err)
+		}
+		t.Fields = append(t.Fields, f)
+	}
+	return nil
+}
+
+// jsonFieldName returns the field name encoding/json uses for sf, "-" for
+// skipped fields, and whether the json tag names the field.
+func jsonFieldName(sf reflect.StructField) (string, bool) {
+	tag, ok := sf.Tag.Lookup("json")
+	if !ok {
+		return sf.Name, false
+	}
+	if tag == "-" {
+		return "-", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	if name == "" {
+		return sf.Name, false
+	}
+	return name, true
+}
+
+// parseFieldTag applies the options of a protokol tag to f.
+func parseFieldTag(f *Field, tag string) error {
+	for tag != "" {
+		var opt string
+		if strings.HasPrefix(tag, "description=") {
+			opt, tag = tag, ""
+		} else {
+			opt, tag, _ = strings.Cut(tag, ",")
+		}
+		key, value, hasValue := strings.Cut(strings.TrimSpace(opt), "=")
+		switch {
+		case key == "required" && !hasValue:
+			f.Required = true
+		case key == "number":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid field number %q", value)
+			}
+			f.Number = n
+		case key == "default":
+			v, err := parseDefault(f.Type, value)
+			if err != nil {
+				return fmt.Errorf("invalid default %q: %w", value, err)
+			}
+			f.Default = v
+		case key == "description":
+			f.Description = value
+		case key == "":
+		default:
+			return fmt.Errorf("unknown protokol tag option %q", opt)
+		}
+	}
+	return nil
+}
+
+// parseDefault converts the text of a default value to a value of type t.
+func parseDefault(t Type, s string) (any, error) {
+	switch t.Kind {
+	case KindBool:
+		return strconv.ParseBool(s)
+	case KindInt32:
+		n, err := strconv.ParseInt(s, 10, 32)
+		return int(n), err
+	case KindInt64:
+		return strconv.ParseInt(s, 10, 64)
+	case KindFloat32:
+		return strconv.ParseFloat(s, 32)
+	case KindFloat64:
+		return strconv.ParseFloat(s, 64)
+	case KindString, KindBytes:
+		return s, nil
+	case KindEnum:
+		if !slices.ContainsFunc(t.Values, func(v EnumValue) bool { return v.Name == s }) {
+			return nil, fmt.Errorf("not a value of enum %s", t.Name)
+		}
+		return s, nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// enumType returns the enum type of a Go type implementing Enumerated.
+func enumType(rt reflect.Type) (Type, error) {
+	switch rt.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String:
+	default:
+		return Type{}, fmt.Errorf("enum type %s must be an integer or string type", rt)
+	}
+	values := reflect.Zero(rt).Interface().(Enumerated).EnumValues()
+	return Type{Kind: KindEnum, Name: rt.Name(), Values: values}, nil
+}
+
+func fieldError(rt reflect.Type, sf reflect.StructField, err error) error {
+	name := rt.Name()
+	if name == "" {
+		name = rt.String()
+	}
+	return fmt.Errorf("field %s of %s: %w", sf.Name, name, err)
+}
@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testRole int
+
+func (testRole) EnumValues() []EnumValue {
+	return []EnumValue{{Name: "USER", Number: 0}, {Name: "ADMIN", Number: 1}}
+}
+
+type testBase struct {
+	ID      int64     `json:"id" protokol:"required"`
+	Created time.Time `json:"created"`
+}
+
+type testUser struct {
+	testBase
+	Name    string            `json:"name" protokol:"description=Display name, may contain commas"`
+	Age     int8              `json:"age,omitempty"`
+	Score   float32           `json:"score"`
+	Avatar  []byte            `json:"avatar"`
+	Tags    []string          `json:"tags" protokol:"default=[\"a\"]"`
+	Labels  map[string]uint64 `json:"labels"`
+	Role    testRole          `json:"role" protokol:"default=ADMIN,number=20"`
+	Limit   *int32            `protokol:"default=10"`
+	Manager *testUser         `json:"manager"`
+	Secret  string            `json:"-"`
+	private string
+}
+
+func TestFromStruct(t *testing.T) {
+	got, err := FromStruct[*testUser]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != KindMessage || got.Name != "testUser" {
+		t.Fatalf("type = %v %q", got.Kind, got.Name)
+	}
+
+	tests := []struct {
+		name     string
+		kind     Kind
+		number   int
+		required bool
+		def      any
+		desc     string
+	}{
+		{name: "id", kind: KindInt64, number: 1, required: true},
+		{name: "created", kind: KindString, number: 2},
+		{name: "name", kind: KindString, number: 3, desc: "Display name, may contain commas"},
+		{name: "age", kind: KindInt32, number: 4},
+		{name: "score", kind: KindFloat32, number: 5},
+		{name: "avatar", kind: KindBytes, number: 6},
+		{name: "tags", kind: KindRepeated, number: 7, def: "[a]"},
+		{name: "labels", kind: KindMap, number: 8},
+		{name: "role", kind: KindEnum, number: 20, def: "ADMIN"},
+		{name: "Limit", kind: KindInt32, number: 10, def: "10"},
+		{name: "manager", kind: KindRef, number: 11},
+	}
+	if len(got.Fields) != len(tests) {
+		t.Fatalf("fields = %+v", got.Fields)
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := got.Fields[i]
+			if f.Name != tt.name || f.Type.Kind != tt.kind || f.Number != tt.number || f.Required != tt.required || f.Description != tt.desc {
+				t.Errorf("field = %+v, want %+v", f, tt)
+			}
+			if tt.def != nil && fmt.Sprint(f.Default) != tt.def {
+				t.Errorf("default = %v, want %v", f.Default, tt.def)
+			}
+		})
+	}
+	if m := got.Fields[10].Type; m.Name != "testUser" {
+		t.Errorf("recursive field refers to %q", m.Name)
+	}
+	if role := got.Fields[8].Type; role.Name != "testRole" || len(role.Values) != 2 {
+		t.Errorf("enum = %+v", role)
+	}
+}
+
+func TestFromStructErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		conv func() error
+		want string
+	}{
+		{
+			name: "not a struct",
+			conv: func() error { _, err := FromStruct[string](); return err },
+			want: "FromStruct requires a struct type, got string",
+		},
+		{
+			name: "unsupported field type",
+			conv: func() error {
+				_, err := FromStruct[struct{ C chan int }]()
+				return err
+			},
+			want: "field C of struct { C chan int }: unsupported type chan int",
+		},
+		{
+			name: "unsupported map key",
+			conv: func() error {
+				_, err := FromStruct[struct{ M map[float64]string }]()
+				return err
+			},
+			want: "unsupported map key type float64",
+		},
+		{
+			name: "invalid default",
+			conv: func() error {
+				_, err := FromStruct[struct {
+					N int32 `protokol:"default=x"`
+				}]()
+				return err
+			},
+			want: `invalid default "x"`,
+		},
+		{
+			name: "default outside the enum",
+			conv: func() error {
+				_, err := FromStruct[struct {
+					R testRole `protokol:"default=OWNER"`
+				}]()
+				return err
+			},
+			want: "not a value of enum testRole",
+		},
+		{
+			name: "invalid number",
+			conv: func() error {
+				_, err := FromStruct[struct {
+					N int32 `protokol:"number=0"`
+				}]()
+				return err
+			},
+			want: `invalid field number "0"`,
+		},
+		{
+			name: "unknown option",
+			conv: func() error {
+				_, err := FromStruct[struct {
+					N int32 `protokol:"optional"`
+				}]()
+				return err
+			},
+			want: `unknown protokol tag option "optional"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conv()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
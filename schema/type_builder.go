@@ -21,6 +21,13 @@ func Map(key, value Type) Type {
 	return Type{Kind: KindMap, Key: &key, Elem: &value}
 }
 
+// Ref creates a reference to the type registered under name. References are
+// resolved with Schema.Resolve when the schema is used, so a message may
+// contain itself, as in a tree node whose children are nodes.
+func Ref(name string) Type {
+	return Type{Kind: KindRef, Name: name}
+}
+
 // TypeBuilder provides a fluent API for building message types.
 type TypeBuilder struct {
 	t Type
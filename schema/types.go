@@ -18,6 +18,7 @@ const (
 	KindEnum                 // KindEnum represents an enumeration type.
 	KindMap                  // KindMap represents a key-value map type.
 	KindRepeated             // KindRepeated represents a repeated/array type.
+	KindRef                  // KindRef refers by Name to a type registered with Schema.RegisterType.
 )
 
 // Type represents a field's type information.
@@ -32,11 +33,12 @@ type Type struct {
 
 // Field represents a single field in a message.
 type Field struct {
-	Name     string
-	Type     Type
-	Number   int
-	Required bool
-	Default  any
+	Name        string
+	Type        Type
+	Number      int
+	Required    bool
+	Default     any
+	Description string
 }
 
 // EnumValue represents a single enum option.
@@ -0,0 +1,306 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every problem found by Schema.Validate.
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "invalid schema: " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual problems.
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+// pathParamPattern matches {name} and {name:regexp} path parameters.
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Validate checks the schema for consistency: unique, non-empty service and
+// method names, a backend for every service, message inputs and outputs,
+// unique field names and numbers, non-empty enums with unique values, map
+// keys of string, bool or integer kind, references to registered types, HTTP
+// methods that are valid tokens, path parameters that name input fields, and
+// a single definition per type name. Registered types are identified by the
+// name they are registered under, so types sharing a short name in different
+// packages do not conflict. It returns a *ValidationError listing every
+// problem, or nil.
+func (s *Schema) Validate() error {
+	v := &schemaValidator{
+		schema:     s,
+		defs:       make(map[string]Type),
+		registered: make(map[string][]string),
+	}
+
+	names := make([]string, 0, len(s.Types))
+	for name := range s.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if t := registeredType(s, name); t.Name != "" {
+			v.registered[t.Name] = append(v.registered[t.Name], name)
+		}
+	}
+	for _, name := range names {
+		v.typ(registeredType(s, name), "type "+name)
+	}
+
+	services := make(map[string]bool)
+	for i, svc := range s.Services {
+		path := "service " + svc.Name
+		if svc.Name == "" {
+			path = fmt.Sprintf("service #%d", i+1)
+			v.add(path, "name is empty")
+		} else if services[svc.Name] {
+			v.add(path, "duplicate service name")
+		}
+		services[svc.Name] = true
+		// Methods may name their own backends; the service's is needed for
+		// the rest.
+		if svc.Backend == "" {
+			needed := len(svc.Methods) == 0
+			for _, m := range svc.Methods {
+				needed = needed || m.Backend == ""
+			}
+			if needed {
+				v.add(path, "backend is empty")
+			}
+		}
+
+		methods := make(map[string]bool)
+		for j, m := range svc.Methods {
+			mpath := path + ": method " + m.Name
+			if m.Name == "" {
+				mpath = fmt.Sprintf("%s: method #%d", path, j+1)
+				v.add(mpath, "name is empty")
+			} else if methods[m.Name] {
+				v.add(mpath, "duplicate method name")
+			}
+			methods[m.Name] = true
+			v.method(m, mpath)
+		}
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+type schemaValidator struct {
+	schema *Schema
+	errs   []error
+	// defs holds the first definition of each named message and enum, by
+	// the key returned by defKey.
+	defs map[string]Type
+	// registered maps type names to the names their types are registered
+	// under.
+	registered map[string][]string
+}
+
+// registeredType returns the type registered under name. Messages and enums
+// without a name take the registered one, as in Schema.Resolve.
+func registeredType(s *Schema, name string) Type {
+	t := s.Types[name]
+	if t.Name == "" && (t.Kind == KindMessage || t.Kind == KindEnum) {
+		t.Name = name
+	}
+	return t
+}
+
+// defKey identifies the definition of named type t: the name a type equal to
+// t is registered under, or the type name for unregistered types.
+func (v *schemaValidator) defKey(t Type) string {
+	for _, name := range v.registered[t.Name] {
+		if reflect.DeepEqual(registeredType(v.schema, name), t) {
+			return name
+		}
+	}
+	return t.Name
+}
+
+func (v *schemaValidator) add(path, format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+}
+
+func (v *schemaValidator) method(m Method, path string) {
+	for _, io := range []struct {
+		name string
+		t    Type
+	}{{"input", m.Input}, {"output", m.Output}} {
+		t, ok := v.schema.Resolve(io.t)
+		if !ok {
+			v.add(path, "%s refers to unregistered type %s", io.name, io.t.Name)
+			continue
+		}
+		switch t.Kind {
+		case KindInvalid:
+			v.add(path, "%s type is not set", io.name)
+		case KindMessage:
+			v.typ(t, path+": "+io.name)
+		default:
+			v.add(path, "%s must be a message type", io.name)
+		}
+	}
+
+	if m.HTTPMethod != "" && !IsToken(m.HTTPMethod) {
+		v.add(path, "invalid HTTP method %q", m.HTTPMethod)
+	}
+	if m.HTTPPath != "" {
+		if !strings.HasPrefix(m.HTTPPath, "/") {
+			v.add(path, "HTTP path %q must start with /", m.HTTPPath)
+		}
+		for _, match := range pathParamPattern.FindAllStringSubmatch(m.HTTPPath, -1) {
+			if !v.hasFieldPath(m.Input, match[1]) {
+				v.add(path, "path parameter {%s} is not a field of the input message", match[1])
+			}
+		}
+	}
+}
+
+// typ checks t and the types nested in it.
+func (v *schemaValidator) typ(t Type, path string) {
+	if (t.Kind == KindMessage || t.Kind == KindEnum) && t.Name != "" {
+		// Empty messages stand in for recursive references, so they never
+		// conflict with the full definition.
+		key := v.defKey(t)
+		def, seen := v.defs[key]
+		switch {
+		case seen && isPlaceholder(t):
+			return
+		case seen && !isPlaceholder(def):
+			if !reflect.DeepEqual(def, t) {
+				v.add(path, "conflicting definitions of type %s", key)
+			}
+			// Already checked when first seen.
+			return
+		}
+		v.defs[key] = t
+	}
+
+	switch t.Kind {
+	case KindMessage:
+		names := make(map[string]bool)
+		numbers := make(map[int]string)
+		for i, f := range t.Fields {
+			fpath := path + ": field " + f.Name
+			if f.Name == "" {
+				fpath = fmt.Sprintf("%s: field #%d", path, i+1)
+				v.add(fpath, "name is empty")
+			} else if names[f.Name] {
+				v.add(fpath, "duplicate field name")
+			}
+			names[f.Name] = true
+
+			// Zero numbers are assigned from the field position.
+			number := f.Number
+			if number == 0 {
+				number = i + 1
+			}
+			if number < 0 {
+				v.add(fpath, "negative field number %d", number)
+			} else if other, ok := numbers[number]; ok {
+				v.add(fpath, "field number %d is already used by %s", number, other)
+			}
+			numbers[number] = f.Name
+
+			if f.Type.Kind == KindInvalid {
+				v.add(fpath, "type is not set")
+				continue
+			}
+			v.typ(f.Type, fpath)
+		}
+
+	case KindEnum:
+		if len(t.Values) == 0 {
+			v.add(path, "enum %s has no values", t.Name)
+		}
+		names := make(map[string]bool)
+		numbers := make(map[int]string)
+		for _, ev := range t.Values {
+			if names[ev.Name] {
+				v.add(path, "duplicate enum value %s", ev.Name)
+			}
+			names[ev.Name] = true
+			if other, ok := numbers[ev.Number]; ok {
+				v.add(path, "enum value %s reuses number %d of %s", ev.Name, ev.Number, other)
+			}
+			numbers[ev.Number] = ev.Name
+		}
+
+	case KindRepeated:
+		if t.Elem == nil {
+			v.add(path, "repeated type without element type")
+			return
+		}
+		v.typ(*t.Elem, path+"[]")
+
+	case KindRef:
+		// The registered type itself is checked on its own.
+		if _, ok := v.schema.Resolve(t); !ok {
+			v.add(path, "reference to unregistered type %s", t.Name)
+		}
+
+	case KindMap:
+		if t.Key == nil || t.Elem == nil {
+			v.add(path, "map type without key or value type")
+			return
+		}
+		switch t.Key.Kind {
+		case KindString, KindBool, KindInt32, KindInt64:
+		default:
+			v.add(path, "map key must be a string, bool or integer type")
+		}
+		v.typ(*t.Elem, path+"[value]")
+	}
+}
+
+// hasFieldPath reports whether the dotted path names a field of message t,
+// descending into nested messages.
+func (v *schemaValidator) hasFieldPath(t Type, path string) bool {
+	t, _ = v.schema.Resolve(t)
+	name, rest, nested := strings.Cut(path, ".")
+	for _, f := range t.Fields {
+		if f.Name != name {
+			continue
+		}
+		if !nested {
+			return true
+		}
+		ft, _ := v.schema.Resolve(f.Type)
+		return ft.Kind == KindMessage && v.hasFieldPath(ft, rest)
+	}
+	return false
+}
+
+func isPlaceholder(t Type) bool {
+	return t.Kind == KindMessage && len(t.Fields) == 0
+}
+
+// IsToken reports whether s is an HTTP token (RFC 7230, section 3.2.6), the
+// syntax of request methods, including custom ones such as "PURGE".
+func IsToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	user := Message("User").Field("id", String).Build()
+	method := func(m Method) func(*Schema) {
+		return func(s *Schema) {
+			s.AddService(Service{Name: "Users", Backend: "b", Methods: []Method{m}})
+		}
+	}
+
+	tests := []struct {
+		name  string
+		setup func(*Schema)
+		want  []string
+	}{
+		{
+			name:  "valid",
+			setup: method(Unary("GetUser").Input(user).Output(user).HTTP("GET", "/users/{id}").Build()),
+		},
+		{
+			name: "services",
+			setup: func(s *Schema) {
+				m := Unary("GetUser").Input(user).Output(user).Build()
+				s.AddService(Service{Name: "Users", Methods: []Method{m}})
+				s.AddService(Service{Name: "Users", Backend: "b"})
+				s.AddService(Service{Backend: "b"})
+			},
+			want: []string{
+				"service Users: backend is empty",
+				"service Users: duplicate service name",
+				"service #3: name is empty",
+			},
+		},
+		{
+			name: "methods",
+			setup: func(s *Schema) {
+				m := Unary("GetUser").Input(user).Output(user).Build()
+				s.AddService(Service{Name: "Users", Backend: "b", Methods: []Method{m, m, {Input: user, Output: user}}})
+			},
+			want: []string{
+				"service Users: method GetUser: duplicate method name",
+				"service Users: method #3: name is empty",
+			},
+		},
+		{
+			name:  "method types",
+			setup: method(Method{Name: "GetUser", Input: String, Output: Ref("Missing")}),
+			want: []string{
+				"service Users: method GetUser: input must be a message type",
+				"service Users: method GetUser: output refers to unregistered type Missing",
+			},
+		},
+		{
+			name:  "http mapping",
+			setup: method(Unary("GetUser").Input(user).Output(user).HTTP("G ET", "users/{name}").Build()),
+			want: []string{
+				`service Users: method GetUser: invalid HTTP method "G ET"`,
+				`service Users: method GetUser: HTTP path "users/{name}" must start with /`,
+				"service Users: method GetUser: path parameter {name} is not a field of the input message",
+			},
+		},
+		{
+			name: "nested path parameter",
+			setup: method(Unary("GetUser").
+				Input(Message("GetUserRequest").Field("user", user).Build()).
+				Output(user).
+				HTTP("GET", "/users/{user.id}/{user.name}").
+				Build()),
+			want: []string{
+				"service Users: method GetUser: path parameter {user.name} is not a field of the input message",
+			},
+		},
+		{
+			name: "fields",
+			setup: func(s *Schema) {
+				s.RegisterType("Bad", Type{Kind: KindMessage, Fields: []Field{
+					{Name: "a", Type: String},
+					{Name: "a", Type: String, Number: 1},
+					{Type: String, Number: -1},
+					{Name: "d"},
+					{Name: "e", Type: Map(Repeated(String), String)},
+					{Name: "f", Type: Type{Kind: KindRepeated}},
+				}})
+			},
+			want: []string{
+				"type Bad: field a: duplicate field name",
+				"type Bad: field a: field number 1 is already used by a",
+				"type Bad: field #3: name is empty",
+				"type Bad: field #3: negative field number -1",
+				"type Bad: field d: type is not set",
+				"type Bad: field e: map key must be a string, bool or integer type",
+				"type Bad: field f: repeated type without element type",
+			},
+		},
+		{
+			name: "enums",
+			setup: func(s *Schema) {
+				s.RegisterType("Empty", Type{Kind: KindEnum})
+				s.RegisterType("Role", Type{Kind: KindEnum, Values: []EnumValue{{"USER", 0}, {"USER", 1}, {"ADMIN", 1}}})
+			},
+			want: []string{
+				"type Empty: enum Empty has no values",
+				"type Role: duplicate enum value USER",
+				"type Role: enum value ADMIN reuses number 1 of USER",
+			},
+		},
+		{
+			name: "conflicting definitions",
+			setup: method(Unary("GetUser").
+				Input(user).
+				Output(Message("User").Field("id", Int64).Build()).
+				Build()),
+			want: []string{
+				"service Users: method GetUser: output: conflicting definitions of type User",
+			},
+		},
+		{
+			name: "same short name registered under different names",
+			setup: func(s *Schema) {
+				s.RegisterType("a.User", user)
+				s.RegisterType("b.User", Message("User").Field("id", Int64).Build())
+			},
+		},
+		{
+			name: "recursive placeholder",
+			setup: func(s *Schema) {
+				s.RegisterType("Node", Message("Node").Field("next", Message("Node").Build()).Build())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSchema()
+			tt.setup(s)
+			err := s.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("err = %v, want *ValidationError", err)
+			}
+			var got []string
+			for _, e := range verr.Errors {
+				got = append(got, e.Error())
+			}
+			if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+				t.Errorf("errors:\n%q\nwant:\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsToken(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"GET", true},
+		{"PURGE", true},
+		{"M-SEARCH", true},
+		{"", false},
+		{"G ET", false},
+		{"G(ET", false},
+		{"GÉT", false},
+	}
+	for _, tt := range tests {
+		if got := IsToken(tt.s); got != tt.want {
+			t.Errorf("IsToken(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
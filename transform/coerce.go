@@ -0,0 +1,208 @@
+package transform
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+// Coerce converts string parameters, such as URL path and query values, to
+// the kinds of the fields of message type t they name and stores them in
+// input.
+//
+// Dotted keys address nested messages ("filter.status") and map entries
+// ("labels.env"). Repeated fields accept repeated parameters as well as
+// comma-separated values. Enum fields accept a value name or number and are
+// stored as the name. Keys that do not name a field are stored unchanged: a
+// string for a single value, a []string otherwise.
+//
+// Type references are resolved against s. It returns a *ValidationError
+// listing every value that could not be parsed.
+func Coerce(s *schema.Schema, t schema.Type, input map[string]any, params map[string][]string) error {
+	keys := make([]string, 0, len(params))
+	for key, values := range params {
+		if len(values) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	v := &validator{schema: s}
+	for _, key := range keys {
+		v.coerceParam(t, input, key, params[key])
+	}
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+func (v *validator) coerceParam(t schema.Type, input map[string]any, key string, values []string) {
+	dst := input
+	parts := strings.Split(key, ".")
+	unknown := key
+	for i, name := range parts {
+		f, ok := fieldByName(v.resolve(t), name)
+		if !ok {
+			unknown = strings.Join(parts[i:], ".")
+			break
+		}
+		f.Type = v.resolve(f.Type)
+		path := strings.Join(parts[:i+1], ".")
+		rest := strings.Join(parts[i+1:], ".")
+
+		switch {
+		case rest == "":
+			if val, ok := v.coerceField(f.Type, values, path); ok {
+				dst[name] = val
+			}
+			return
+
+		case f.Type.Kind == schema.KindMessage:
+			nested, ok := dst[name].(map[string]any)
+			if !ok {
+				nested = make(map[string]any)
+				dst[name] = nested
+			}
+			dst, t = nested, f.Type
+
+		case f.Type.Kind == schema.KindMap && f.Type.Elem != nil:
+			m, ok := dst[name].(map[string]any)
+			if !ok {
+				m = make(map[string]any)
+				dst[name] = m
+			}
+			if val, ok := v.scalar(*f.Type.Elem, values[0], key); ok {
+				m[rest] = val
+			}
+			return
+
+		default:
+			v.add(path, "%s has no nested fields", kindName(f.Type.Kind))
+			return
+		}
+	}
+
+	if len(values) == 1 {
+		dst[unknown] = values[0]
+	} else {
+		dst[unknown] = values
+	}
+}
+
+func (v *validator) coerceField(t schema.Type, values []string, path string) (any, bool) {
+	t = v.resolve(t)
+	switch t.Kind {
+	case schema.KindRepeated:
+		var out []any
+		ok := true
+		for _, value := range values {
+			for _, s := range strings.Split(value, ",") {
+				if t.Elem == nil {
+					out = append(out, s)
+					continue
+				}
+				elemPath := path + "[" + strconv.Itoa(len(out)) + "]"
+				val, valid := v.scalar(*t.Elem, s, elemPath)
+				ok = ok && valid
+				out = append(out, val)
+			}
+		}
+		return out, ok
+
+	case schema.KindMessage, schema.KindMap:
+		v.add(path, "set %s fields with dotted keys such as %s.name", kindName(t.Kind), path)
+		return nil, false
+
+	default:
+		return v.scalar(t, values[0], path)
+	}
+}
+
+func (v *validator) scalar(t schema.Type, s, path string) (any, bool) {
+	t = v.resolve(t)
+	switch t.Kind {
+	case schema.KindBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			v.add(path, "invalid bool %q", s)
+			return nil, false
+		}
+		return b, true
+
+	case schema.KindInt32:
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			v.add(path, "invalid int32 %q", s)
+			return nil, false
+		}
+		return int32(n), true
+
+	case schema.KindInt64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			v.add(path, "invalid int64 %q", s)
+			return nil, false
+		}
+		return n, true
+
+	case schema.KindFloat32:
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			v.add(path, "invalid float32 %q", s)
+			return nil, false
+		}
+		return float32(f), true
+
+	case schema.KindFloat64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			v.add(path, "invalid float64 %q", s)
+			return nil, false
+		}
+		return f, true
+
+	case schema.KindEnum:
+		for _, ev := range t.Values {
+			if ev.Name == s || strconv.Itoa(ev.Number) == s {
+				return ev.Name, true
+			}
+		}
+		v.add(path, "invalid enum value %q", s)
+		return nil, false
+
+	case schema.KindMessage, schema.KindMap, schema.KindRepeated:
+		v.add(path, "%s is not supported here", kindName(t.Kind))
+		return nil, false
+
+	default:
+		return s, true
+	}
+}
+
+func fieldByName(t schema.Type, name string) (schema.Field, bool) {
+	if t.Kind != schema.KindMessage {
+		return schema.Field{}, false
+	}
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return schema.Field{}, false
+}
+
+func kindName(k schema.Kind) string {
+	switch k {
+	case schema.KindMessage:
+		return "message"
+	case schema.KindMap:
+		return "map"
+	case schema.KindRepeated:
+		return "repeated field"
+	default:
+		return "scalar field"
+	}
+}
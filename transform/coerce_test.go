@@ -0,0 +1,95 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+func TestCoerce(t *testing.T) {
+	s := schema.NewSchema()
+	s.RegisterType("Filter", schema.Message("Filter").
+		Field("status", schema.Enum("Status").Value("ACTIVE", 0).Value("DELETED", 1).Build()).
+		Field("since", schema.Int64).
+		Build())
+	list := schema.Message("ListRequest").
+		Field("limit", schema.Int32).
+		Field("ratio", schema.Float64).
+		Field("deleted", schema.Bool).
+		Field("name", schema.String).
+		Field("ids", schema.Repeated(schema.Int64)).
+		Field("filter", schema.Ref("Filter")).
+		Field("labels", schema.Map(schema.String, schema.Int32)).
+		Build()
+
+	tests := []struct {
+		name   string
+		params map[string][]string
+		want   string
+		err    string
+	}{
+		{
+			name:   "scalars",
+			params: map[string][]string{"limit": {"10"}, "ratio": {"0.5"}, "deleted": {"true"}, "name": {"ada"}},
+			want:   "map[deleted:true limit:10 name:ada ratio:0.5]",
+		},
+		{
+			name:   "repeated and comma-separated values",
+			params: map[string][]string{"ids": {"1,2", "3"}},
+			want:   "map[ids:[1 2 3]]",
+		},
+		{
+			name:   "nested message and enum by number",
+			params: map[string][]string{"filter.status": {"1"}, "filter.since": {"5"}},
+			want:   "map[filter:map[since:5 status:DELETED]]",
+		},
+		{
+			name:   "map entries",
+			params: map[string][]string{"labels.env": {"2"}},
+			want:   "map[labels:map[env:2]]",
+		},
+		{
+			name:   "unknown keys are kept",
+			params: map[string][]string{"page": {"x"}, "sort": {"a", "b"}},
+			want:   "map[page:x sort:[a b]]",
+		},
+		{
+			name:   "invalid values",
+			params: map[string][]string{"limit": {"ten"}, "ids": {"1,x"}, "filter.status": {"GONE"}},
+			want:   "map[filter:map[]]",
+			err:    "[{filter.status invalid enum value \"GONE\"} {ids[1] invalid int64 \"x\"} {limit invalid int32 \"ten\"}]",
+		},
+		{
+			name:   "message set without a dotted key",
+			params: map[string][]string{"filter": {"x"}},
+			want:   "map[]",
+			err:    "[{filter set message fields with dotted keys such as filter.name}]",
+		},
+		{
+			name:   "dotted key on a scalar",
+			params: map[string][]string{"limit.max": {"1"}},
+			want:   "map[]",
+			err:    "[{limit scalar field has no nested fields}]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := make(map[string]any)
+			err := Coerce(s, list, input, tt.params)
+			if got := fmt.Sprint(input); got != tt.want {
+				t.Errorf("input = %s, want %s", got, tt.want)
+			}
+			var verr *ValidationError
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err != "" && !errors.As(err, &verr):
+				t.Errorf("err = %v, want *ValidationError", err)
+			case tt.err != "" && fmt.Sprint(verr.Violations) != tt.err:
+				t.Errorf("violations = %v, want %s", verr.Violations, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package transform
+
+import (
+	"github.com/jekabolt/protokol/schema"
+)
+
+// ApplyDefaults sets every field of message type t that is missing or null
+// in m to the field's Default, if it has one. It descends into nested
+// messages present in m, including elements of repeated fields and values of
+// maps. Type references are resolved against s. Default values that are maps
+// or slices are copied, so backends may modify them freely.
+func ApplyDefaults(s *schema.Schema, t schema.Type, m map[string]any) {
+	t, _ = s.Resolve(t)
+	if t.Kind != schema.KindMessage || m == nil {
+		return
+	}
+	for _, f := range t.Fields {
+		val, ok := m[f.Name]
+		if !ok || val == nil {
+			if f.Default != nil {
+				m[f.Name] = clone(f.Default)
+			}
+			continue
+		}
+		applyNested(s, f.Type, val)
+	}
+}
+
+func applyNested(s *schema.Schema, t schema.Type, val any) {
+	t, _ = s.Resolve(t)
+	switch t.Kind {
+	case schema.KindMessage:
+		if m, ok := val.(map[string]any); ok {
+			ApplyDefaults(s, t, m)
+		}
+	case schema.KindRepeated, schema.KindMap:
+		if t.Elem == nil {
+			return
+		}
+		elemType, _ := s.Resolve(*t.Elem)
+		if elemType.Kind != schema.KindMessage {
+			return
+		}
+		switch v := val.(type) {
+		case []any:
+			for _, elem := range v {
+				applyNested(s, elemType, elem)
+			}
+		case []map[string]any:
+			for _, elem := range v {
+				ApplyDefaults(s, elemType, elem)
+			}
+		case map[string]any:
+			for _, elem := range v {
+				applyNested(s, elemType, elem)
+			}
+		}
+	}
+}
+
+func clone(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = clone(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = clone(e)
+		}
+		return out
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return v
+	}
+}
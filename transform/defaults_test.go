@@ -0,0 +1,83 @@
+package transform
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jekabolt/protokol/schema"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	s := schema.NewSchema()
+	s.RegisterType("Page", schema.Message("Page").
+		FieldWithDefault("size", schema.Int32, 20).
+		Field("token", schema.String).
+		Build())
+	list := schema.Message("ListRequest").
+		FieldWithDefault("limit", schema.Int32, 10).
+		FieldWithDefault("tags", schema.Repeated(schema.String), []any{"a"}).
+		Field("page", schema.Ref("Page")).
+		Field("pages", schema.Repeated(schema.Ref("Page"))).
+		Field("byName", schema.Map(schema.String, schema.Ref("Page"))).
+		Build()
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		want  string
+	}{
+		{
+			name:  "missing fields",
+			input: map[string]any{},
+			want:  "map[limit:10 tags:[a]]",
+		},
+		{
+			name:  "null fields",
+			input: map[string]any{"limit": nil},
+			want:  "map[limit:10 tags:[a]]",
+		},
+		{
+			name:  "zero values are kept",
+			input: map[string]any{"limit": 0, "tags": []any{}},
+			want:  "map[limit:0 tags:[]]",
+		},
+		{
+			name:  "nested message",
+			input: map[string]any{"page": map[string]any{"token": "x"}},
+			want:  "map[limit:10 page:map[size:20 token:x] tags:[a]]",
+		},
+		{
+			name: "repeated and map elements",
+			input: map[string]any{
+				"pages":  []any{map[string]any{}, map[string]any{"size": 5}},
+				"byName": map[string]any{"k": map[string]any{}},
+			},
+			want: "map[byName:map[k:map[size:20]] limit:10 pages:[map[size:20] map[size:5]] tags:[a]]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ApplyDefaults(s, list, tt.input)
+			if got := fmt.Sprint(tt.input); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyDefaultsCopiesValues(t *testing.T) {
+	s := schema.NewSchema()
+	list := schema.Message("ListRequest").
+		FieldWithDefault("tags", schema.Repeated(schema.String), []any{"a"}).
+		Build()
+
+	first := map[string]any{}
+	ApplyDefaults(s, list, first)
+	first["tags"].([]any)[0] = "changed"
+
+	second := map[string]any{}
+	ApplyDefaults(s, list, second)
+	if got := second["tags"].([]any)[0]; got != "a" {
+		t.Errorf("default changed by an earlier request: %v", got)
+	}
+}
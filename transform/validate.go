@@ -0,0 +1,324 @@
+// Package transform converts and checks request data against schema types.
+package transform
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/schema"
+)
+
+// Violation describes a single invalid field.
+type Violation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field found in an input.
+// It matches protokol.ErrInvalidArgument with errors.Is.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + ": " + v.Message
+	}
+	return "invalid argument: " + strings.Join(parts, "; ")
+}
+
+// Is reports whether target is protokol.ErrInvalidArgument.
+func (e *ValidationError) Is(target error) bool {
+	return target == protokol.ErrInvalidArgument
+}
+
+// Validate checks input against the fields of message type t: required
+// fields must be present, and every value must match its field's kind,
+// including enum membership, nested messages, and repeated and map elements.
+// Type references are resolved against s. Fields not declared in t are
+// ignored. It returns a *ValidationError listing every violation, or nil.
+func Validate(s *schema.Schema, t schema.Type, input map[string]any) error {
+	t, _ = s.Resolve(t)
+	if t.Kind != schema.KindMessage {
+		return nil
+	}
+	v := &validator{schema: s}
+	v.message(t, input, "")
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
+
+type validator struct {
+	schema     *schema.Schema
+	violations []Violation
+}
+
+// resolve returns the type t refers to. Unresolved references are returned
+// unchanged and accept any value.
+func (v *validator) resolve(t schema.Type) schema.Type {
+	t, _ = v.schema.Resolve(t)
+	return t
+}
+
+func (v *validator) add(path, format string, args ...any) {
+	v.violations = append(v.violations, Violation{Field: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) message(t schema.Type, input map[string]any, prefix string) {
+	for _, f := range t.Fields {
+		path := joinPath(prefix, f.Name)
+		val, ok := input[f.Name]
+		if !ok || val == nil {
+			if f.Required {
+				v.add(path, "required")
+			}
+			continue
+		}
+		v.value(f.Type, val, path)
+	}
+}
+
+func (v *validator) value(t schema.Type, val any, path string) {
+	t = v.resolve(t)
+	switch t.Kind {
+	case schema.KindBool:
+		if _, ok := val.(bool); !ok {
+			v.add(path, "expected bool, got %s", describe(val))
+		}
+
+	case schema.KindInt32:
+		n, err := toInt(val)
+		if err != nil {
+			v.add(path, "expected int32, got %s", describe(val))
+		} else if n < math.MinInt32 || n > math.MaxInt32 {
+			v.add(path, "value %d out of int32 range", n)
+		}
+
+	case schema.KindInt64:
+		if _, err := toInt(val); err != nil {
+			v.add(path, "expected int64, got %s", describe(val))
+		}
+
+	case schema.KindFloat32, schema.KindFloat64:
+		if _, err := toFloat(val); err != nil {
+			v.add(path, "expected number, got %s", describe(val))
+		}
+
+	case schema.KindString:
+		if _, ok := val.(string); !ok {
+			v.add(path, "expected string, got %s", describe(val))
+		}
+
+	case schema.KindBytes:
+		switch b := val.(type) {
+		case []byte:
+		case string:
+			if _, err := base64.StdEncoding.DecodeString(b); err != nil {
+				if _, err := base64.URLEncoding.DecodeString(b); err != nil {
+					v.add(path, "expected base64 string")
+				}
+			}
+		default:
+			v.add(path, "expected bytes, got %s", describe(val))
+		}
+
+	case schema.KindEnum:
+		if !enumContains(t, val) {
+			v.add(path, "invalid enum value %v", val)
+		}
+
+	case schema.KindMessage:
+		m, ok := asMap(val)
+		if !ok {
+			v.add(path, "expected object, got %s", describe(val))
+			return
+		}
+		v.message(t, m, path)
+
+	case schema.KindRepeated:
+		rv := reflect.ValueOf(val)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			v.add(path, "expected array, got %s", describe(val))
+			return
+		}
+		if t.Elem == nil {
+			return
+		}
+		for i := range rv.Len() {
+			elem := rv.Index(i).Interface()
+			elemPath := fmt.Sprintf("%s[%d]", path, i)
+			if elem == nil {
+				v.add(elemPath, "null element")
+				continue
+			}
+			v.value(*t.Elem, elem, elemPath)
+		}
+
+	case schema.KindMap:
+		m, ok := asMap(val)
+		if !ok {
+			v.add(path, "expected object, got %s", describe(val))
+			return
+		}
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			elemPath := fmt.Sprintf("%s[%s]", path, k)
+			if t.Key != nil && !validKey(v.resolve(*t.Key), k) {
+				v.add(elemPath, "invalid map key %q", k)
+			}
+			if t.Elem != nil && m[k] != nil {
+				v.value(*t.Elem, m[k], elemPath)
+			}
+		}
+	}
+}
+
+func validKey(t schema.Type, k string) bool {
+	switch t.Kind {
+	case schema.KindInt32:
+		_, err := strconv.ParseInt(k, 10, 32)
+		return err == nil
+	case schema.KindInt64:
+		_, err := strconv.ParseInt(k, 10, 64)
+		return err == nil
+	case schema.KindBool:
+		_, err := strconv.ParseBool(k)
+		return err == nil
+	default:
+		return true
+	}
+}
+
+func enumContains(t schema.Type, val any) bool {
+	if s, ok := val.(string); ok {
+		for _, ev := range t.Values {
+			if ev.Name == s {
+				return true
+			}
+		}
+		return false
+	}
+	n, err := toInt(val)
+	if err != nil {
+		return false
+	}
+	for _, ev := range t.Values {
+		if int64(ev.Number) == n {
+			return true
+		}
+	}
+	return false
+}
+
+// toInt accepts Go integer types, integral floats (as produced by JSON
+// decoding) and decimal strings, which the proto3 JSON mapping allows for
+// all integer types.
+func toInt(val any) (int64, error) {
+	switch n := val.(type) {
+	case json.Number:
+		return n.Int64()
+	case string:
+		return strconv.ParseInt(n, 10, 64)
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d overflows int64", u)
+		}
+		return int64(u), nil
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) || f < math.MinInt64 || f > math.MaxInt64 {
+			return 0, fmt.Errorf("value %v is not an integer", f)
+		}
+		return int64(f), nil
+	}
+	return 0, fmt.Errorf("expected integer, got %T", val)
+}
+
+func toFloat(val any) (float64, error) {
+	switch n := val.(type) {
+	case json.Number:
+		return n.Float64()
+	case string:
+		return strconv.ParseFloat(n, 64)
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	}
+	return 0, fmt.Errorf("expected number, got %T", val)
+}
+
+// asMap accepts map[string]any as well as other string-keyed maps such as
+// map[string]string.
+func asMap(val any) (map[string]any, bool) {
+	if m, ok := val.(map[string]any); ok {
+		return m, true
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	out := make(map[string]any, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		out[iter.Key().String()] = iter.Value().Interface()
+	}
+	return out, true
+}
+
+// describe names the JSON type of a value for error messages.
+func describe(val any) string {
+	switch val.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	switch reflect.ValueOf(val).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "object"
+	}
+	return fmt.Sprintf("%T", val)
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
@@ -0,0 +1,118 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jekabolt/protokol"
+	"github.com/jekabolt/protokol/schema"
+)
+
+func TestValidate(t *testing.T) {
+	s := schema.NewSchema()
+	s.RegisterType("Address", schema.Message("Address").
+		RequiredField("city", schema.String).
+		Build())
+	role := schema.Enum("Role").Value("USER", 0).Value("ADMIN", 1).Build()
+	user := schema.Message("User").
+		RequiredField("id", schema.Int64).
+		Field("name", schema.String).
+		Field("age", schema.Int32).
+		Field("score", schema.Float64).
+		Field("active", schema.Bool).
+		Field("avatar", schema.Bytes).
+		Field("role", role).
+		Field("address", schema.Ref("Address")).
+		Field("tags", schema.Repeated(schema.String)).
+		Field("counts", schema.Map(schema.Int32, schema.Int64)).
+		Build()
+
+	tests := []struct {
+		name  string
+		input map[string]any
+		want  string
+	}{
+		{
+			name:  "valid",
+			input: map[string]any{"id": float64(1), "name": "ada", "role": "ADMIN", "tags": []any{"a"}, "counts": map[string]any{"1": 2}},
+		},
+		{
+			name:  "integers as decimal strings and enums by number",
+			input: map[string]any{"id": "12", "age": "3", "role": 1},
+		},
+		{
+			name:  "missing required field",
+			input: map[string]any{"name": "ada"},
+			want:  "[{id required}]",
+		},
+		{
+			name:  "null required field",
+			input: map[string]any{"id": nil},
+			want:  "[{id required}]",
+		},
+		{
+			name:  "wrong kinds",
+			input: map[string]any{"id": "x", "name": 1, "active": "yes", "score": true},
+			want:  "[{id expected int64, got string} {name expected string, got number} {score expected number, got bool} {active expected bool, got string}]",
+		},
+		{
+			name:  "int32 out of range",
+			input: map[string]any{"id": 1, "age": float64(1 << 40)},
+			want:  "[{age value 1099511627776 out of int32 range}]",
+		},
+		{
+			name:  "invalid enum value",
+			input: map[string]any{"id": 1, "role": "OWNER"},
+			want:  "[{role invalid enum value OWNER}]",
+		},
+		{
+			name:  "invalid base64",
+			input: map[string]any{"id": 1, "avatar": "!!"},
+			want:  "[{avatar expected base64 string}]",
+		},
+		{
+			name:  "nested message through a reference",
+			input: map[string]any{"id": 1, "address": map[string]any{}},
+			want:  "[{address.city required}]",
+		},
+		{
+			name:  "repeated elements",
+			input: map[string]any{"id": 1, "tags": []any{"a", nil, 3}},
+			want:  "[{tags[1] null element} {tags[2] expected string, got number}]",
+		},
+		{
+			name:  "map keys and values",
+			input: map[string]any{"id": 1, "counts": map[string]any{"x": 1, "2": "y"}},
+			want:  "[{counts[2] expected int64, got string} {counts[x] invalid map key \"x\"}]",
+		},
+		{
+			name:  "undeclared fields are ignored",
+			input: map[string]any{"id": 1, "extra": []int{1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(s, user, tt.input)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("err = %v, want *ValidationError", err)
+			}
+			if got := fmt.Sprint(verr.Violations); got != tt.want {
+				t.Errorf("violations = %s, want %s", got, tt.want)
+			}
+			if !errors.Is(err, protokol.ErrInvalidArgument) {
+				t.Error("error does not match ErrInvalidArgument")
+			}
+			if protokol.ErrorCode(err) != protokol.CodeInvalidArgument {
+				t.Errorf("code = %v, want CodeInvalidArgument", protokol.ErrorCode(err))
+			}
+		})
+	}
+}